@@ -3,7 +3,16 @@ package main
 import (
 	"ambassador/src/config"
 	"ambassador/src/database"
+	"ambassador/src/mailer"
+	"ambassador/src/middlewares"
+	"ambassador/src/passwordpolicy"
+	"ambassador/src/ratelimit"
 	"ambassador/src/routes"
+	"ambassador/src/scheduler"
+	"ambassador/src/search"
+	"ambassador/src/storage"
+	"ambassador/src/utils"
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -27,11 +36,56 @@ func main () {
         log.Fatalf("Database connection failed: %v", err)
     }
 
+	// Login brute-force protection (Redis with in-memory fallback)
+    ratelimit.Setup(cfg)
+
+	// Password hashing parameters for new and upgraded hashes
+    if err := utils.SetPasswordHashing(utils.PasswordHashing{
+        Algorithm:     cfg.PasswordHashAlgorithm,
+        BcryptCost:    cfg.BcryptCost,
+        Argon2Memory:  uint32(cfg.Argon2MemoryKiB),
+        Argon2Time:    uint32(cfg.Argon2Iterations),
+        Argon2Threads: uint8(cfg.Argon2Threads),
+    }); err != nil {
+        log.Fatalf("Password hashing setup failed: %v", err)
+    }
+
+	// Password policy (loads the breached password list)
+    if err := passwordpolicy.Setup(cfg); err != nil {
+        log.Fatalf("Password policy setup failed: %v", err)
+    }
+
+	// Mailer (SMTP or log/file for local dev)
+    if err := mailer.Setup(cfg); err != nil {
+        log.Fatalf("Mailer setup failed: %v", err)
+    }
+
+	// File storage for uploads (local directory or S3-compatible bucket)
+    if err := storage.Setup(cfg); err != nil {
+        log.Fatalf("Storage setup failed: %v", err)
+    }
+
 	// Run migrations
     if err := database.AutoMigrate(); err != nil {
         log.Fatalf("Database migration failed: %v", err)
     }
 
+	// In-process product search index (SEARCH_BACKEND=memory, single instance only)
+    if cfg.SearchBackend == "memory" {
+        log.Printf("SEARCH_BACKEND=memory: search only sees product changes made through this instance; don't run more than one")
+        if err := search.RebuildProducts(database.DB); err != nil {
+            log.Fatalf("Search index build failed: %v", err)
+        }
+    }
+
+	// Typeahead suggestions (served empty until built)
+    if err := search.Suggestions.Refresh(database.DB); err != nil {
+        log.Printf("Warning: suggestion index build failed: %v", err)
+    }
+
+	// Background jobs (sale start/end cache invalidation, suggestion refresh)
+    scheduler.Start(context.Background())
+
 	// Initialize Fiber app
     app := fiber.New(fiber.Config{
         AppName:               "Ambassador API",
@@ -43,6 +97,11 @@ func main () {
         ServerHeader:          "Ambassador",
         StrictRouting:         true,
         CaseSensitive:         true,
+        // Bodies over the default are streamed, not buffered, so the
+        // per-route BodyLimit middleware can refuse them unread
+        BodyLimit:                    middlewares.DefaultBodyLimit,
+        StreamRequestBody:            true,
+        DisablePreParseMultipartForm: true,
     })
 
 	 // Setup routes
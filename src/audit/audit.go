@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+    pendingKey  = "audit_entries"
+    recordedKey = "audit_recorded"
+)
+
+// Entry describes one mutation, recorded by handlers through Record
+type Entry struct {
+    Action     string      // e.g. product.update
+    TargetType string      // e.g. product
+    TargetID   interface{} // uint, int or string
+    Before     interface{} // nil on create
+    After      interface{} // nil on delete
+}
+
+// Record queues an entry for the current request. Middleware writes it once the
+// handler has finished, and only if the request succeeded.
+func Record(c *fiber.Ctx, entry Entry) {
+    pending, _ := c.Locals(pendingKey).([]Entry)
+    c.Locals(pendingKey, append(pending, entry))
+}
+
+// Write builds the log row from entry and the request context and stores it
+func Write(c *fiber.Ctx, entry Entry) error {
+    row, err := newLog(c, entry)
+    if err != nil {
+        return err
+    }
+    return Save(database.DB.WithContext(c.Context()), row)
+}
+
+// Save appends a log row to the hash chain; every audit write goes through here
+func Save(db *gorm.DB, row *models.AuditLog) error {
+    return appendToChain(db, row)
+}
+
+// newLog fills actor, request and diff details for an entry
+func newLog(c *fiber.Ctx, entry Entry) (*models.AuditLog, error) {
+    changes, err := Diff(entry.Before, entry.After)
+    if err != nil {
+        return nil, fmt.Errorf("audit diff: %w", err)
+    }
+    rawChanges, err := json.Marshal(changes)
+    if err != nil {
+        return nil, fmt.Errorf("audit diff: %w", err)
+    }
+
+    row := &models.AuditLog{
+        Action:     entry.Action,
+        TargetType: entry.TargetType,
+        TargetID:   targetID(entry.TargetID),
+        Changes:    rawChanges,
+        IP:         c.IP(),
+        Method:     c.Method(),
+        Path:       c.Path(),
+        Status:     c.Response().StatusCode(),
+    }
+
+    if user, ok := c.Locals("user").(*models.User); ok {
+        id := user.ID
+        row.ActorID = &id
+        row.ActorEmail = user.Email
+    }
+    if impersonatorID, ok := c.Locals("impersonator_id").(uint); ok && impersonatorID != 0 {
+        row.ImpersonatorID = &impersonatorID
+    }
+    if requestID, ok := c.Locals("requestid").(string); ok {
+        // Clients may send their own X-Request-ID
+        if len(requestID) > 64 {
+            requestID = requestID[:64]
+        }
+        row.RequestID = requestID
+    }
+
+    return row, nil
+}
+
+func targetID(id interface{}) string {
+    switch v := id.(type) {
+    case nil:
+        return ""
+    case string:
+        return v
+    case uint:
+        return strconv.FormatUint(uint64(v), 10)
+    case int:
+        return strconv.Itoa(v)
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// flush writes the entries recorded by the handler
+func flush(c *fiber.Ctx) {
+    pending, _ := c.Locals(pendingKey).([]Entry)
+    for _, entry := range pending {
+        if err := Write(c, entry); err != nil {
+            log.Printf("Audit write failed for %s: %v", entry.Action, err)
+        }
+    }
+    c.Locals(pendingKey, nil)
+    if len(pending) > 0 {
+        c.Locals(recordedKey, true)
+    }
+}
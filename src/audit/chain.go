@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"ambassador/src/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Serializes appends within this process; the row lock on the chain head
+// does the same across instances
+var chainMu sync.Mutex
+
+// BrokenLink describes the first row where the chain doesn't verify
+type BrokenLink struct {
+    ID     uint
+    Reason string
+}
+
+func (b *BrokenLink) Error() string {
+    return fmt.Sprintf("audit chain broken at entry %d: %s", b.ID, b.Reason)
+}
+
+// hashedContent is the canonical form of a row that Hash covers.
+// Field order is fixed by the struct, so encoding is deterministic.
+type hashedContent struct {
+    PrevHash       string          `json:"prev_hash"`
+    CreatedAt      string          `json:"created_at"`
+    ActorID        *uint           `json:"actor_id"`
+    ActorEmail     string          `json:"actor_email"`
+    ImpersonatorID *uint           `json:"impersonator_id"`
+    Action         string          `json:"action"`
+    TargetType     string          `json:"target_type"`
+    TargetID       string          `json:"target_id"`
+    Changes        json.RawMessage `json:"changes"`
+    IP             string          `json:"ip"`
+    RequestID      string          `json:"request_id"`
+    Method         string          `json:"method"`
+    Path           string          `json:"path"`
+    Status         int             `json:"status"`
+}
+
+// ComputeHash returns the SHA-256 of the row's content chained to its PrevHash
+func ComputeHash(row *models.AuditLog) (string, error) {
+    changes := row.Changes
+    if len(changes) == 0 {
+        changes = json.RawMessage("null")
+    }
+
+    payload, err := json.Marshal(hashedContent{
+        PrevHash:       row.PrevHash,
+        CreatedAt:      row.CreatedAt.UTC().Format(time.RFC3339Nano),
+        ActorID:        row.ActorID,
+        ActorEmail:     row.ActorEmail,
+        ImpersonatorID: row.ImpersonatorID,
+        Action:         row.Action,
+        TargetType:     row.TargetType,
+        TargetID:       row.TargetID,
+        Changes:        changes,
+        IP:             row.IP,
+        RequestID:      row.RequestID,
+        Method:         row.Method,
+        Path:           row.Path,
+        Status:         row.Status,
+    })
+    if err != nil {
+        return "", err
+    }
+
+    sum := sha256.Sum256(payload)
+    return hex.EncodeToString(sum[:]), nil
+}
+
+// appendToChain links row to the current chain head and inserts it
+func appendToChain(db *gorm.DB, row *models.AuditLog) error {
+    chainMu.Lock()
+    defer chainMu.Unlock()
+
+    return db.Transaction(func(tx *gorm.DB) error {
+        var head models.AuditLog
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Select("id", "hash").
+            Order("id DESC").
+            Limit(1).
+            Find(&head).Error
+        if err != nil {
+            return err
+        }
+
+        row.PrevHash = head.Hash
+        // Stored with millisecond precision; hash what will be read back
+        row.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+        row.Hash, err = ComputeHash(row)
+        if err != nil {
+            return err
+        }
+
+        return tx.Create(row).Error
+    })
+}
+
+// VerifyResult summarizes a chain walk
+type VerifyResult struct {
+    Checked  int    // chained entries verified
+    Legacy   int    // entries written before chaining existed (not covered)
+    LastHash string // head of the chain; keep a copy elsewhere to detect truncation
+}
+
+// Verify walks the chain in id order and returns a *BrokenLink error for the
+// first entry that doesn't verify. Unhashed entries are only accepted at the
+// start of the log, where rows written before chaining was introduced live.
+func Verify(db *gorm.DB, batchSize int) (VerifyResult, error) {
+    var (
+        result VerifyResult
+        lastID uint
+    )
+
+    for {
+        var rows []models.AuditLog
+        if err := db.Where("id > ?", lastID).Order("id ASC").Limit(batchSize).Find(&rows).Error; err != nil {
+            return result, err
+        }
+        if len(rows) == 0 {
+            return result, nil
+        }
+
+        for i := range rows {
+            lastID = rows[i].ID
+            if err := result.check(&rows[i]); err != nil {
+                return result, err
+            }
+        }
+    }
+}
+
+// check verifies the next row of the chain and advances the result
+func (result *VerifyResult) check(row *models.AuditLog) error {
+    if row.Hash == "" {
+        if result.Checked == 0 && row.PrevHash == "" {
+            result.Legacy++
+            return nil
+        }
+        return &BrokenLink{ID: row.ID, Reason: "entry has no hash"}
+    }
+
+    if row.PrevHash != result.LastHash {
+        return &BrokenLink{ID: row.ID, Reason: "previous hash does not match the preceding entry (entry removed, reordered or inserted)"}
+    }
+
+    hash, err := ComputeHash(row)
+    if err != nil {
+        return err
+    }
+    if hash != row.Hash {
+        return &BrokenLink{ID: row.ID, Reason: "content does not match its hash (entry modified)"}
+    }
+
+    result.LastHash = row.Hash
+    result.Checked++
+    return nil
+}
@@ -0,0 +1,207 @@
+package audit
+
+import (
+	"ambassador/src/models"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testEntry(id uint, action string) models.AuditLog {
+    actor := uint(7)
+    return models.AuditLog{
+        ID:         id,
+        CreatedAt:  time.Date(2026, 10, 18, 12, 0, int(id), 123000000, time.UTC),
+        ActorID:    &actor,
+        ActorEmail: "admin@example.com",
+        Action:     action,
+        TargetType: "product",
+        TargetID:   "42",
+        Changes:    json.RawMessage(`{"price":{"from":10,"to":12}}`),
+        IP:         "10.0.0.1",
+        RequestID:  "req-1",
+        Method:     "PUT",
+        Path:       "/api/admin/products/42",
+        Status:     200,
+    }
+}
+
+// chain links rows the way appendToChain does
+func chain(t *testing.T, rows []models.AuditLog) []models.AuditLog {
+    t.Helper()
+    prev := ""
+    for i := range rows {
+        rows[i].PrevHash = prev
+        hash, err := ComputeHash(&rows[i])
+        if err != nil {
+            t.Fatalf("ComputeHash: %v", err)
+        }
+        rows[i].Hash = hash
+        prev = hash
+    }
+    return rows
+}
+
+func TestComputeHash(t *testing.T) {
+    base := testEntry(1, "product.update")
+    want, err := ComputeHash(&base)
+    if err != nil {
+        t.Fatalf("ComputeHash: %v", err)
+    }
+    if len(want) != 64 {
+        t.Fatalf("hash %q is not hex SHA-256", want)
+    }
+
+    other := uint(8)
+    tests := []struct {
+        name   string
+        modify func(row *models.AuditLog)
+        same   bool
+    }{
+        {"unchanged", func(row *models.AuditLog) {}, true},
+        {"same instant in another zone", func(row *models.AuditLog) { row.CreatedAt = row.CreatedAt.In(time.FixedZone("CEST", 2*3600)) }, true},
+        {"id and stored hash are not covered", func(row *models.AuditLog) { row.ID = 99; row.Hash = "x" }, true},
+        {"prev hash", func(row *models.AuditLog) { row.PrevHash = "abc" }, false},
+        {"created at", func(row *models.AuditLog) { row.CreatedAt = row.CreatedAt.Add(time.Millisecond) }, false},
+        {"actor", func(row *models.AuditLog) { row.ActorID = &other }, false},
+        {"no actor", func(row *models.AuditLog) { row.ActorID = nil }, false},
+        {"impersonator", func(row *models.AuditLog) { row.ImpersonatorID = &other }, false},
+        {"action", func(row *models.AuditLog) { row.Action = "product.delete" }, false},
+        {"target", func(row *models.AuditLog) { row.TargetID = "43" }, false},
+        {"changes", func(row *models.AuditLog) { row.Changes = json.RawMessage(`{"price":{"from":10,"to":13}}`) }, false},
+        {"ip", func(row *models.AuditLog) { row.IP = "10.0.0.2" }, false},
+        {"status", func(row *models.AuditLog) { row.Status = 500 }, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            row := testEntry(1, "product.update")
+            tt.modify(&row)
+            got, err := ComputeHash(&row)
+            if err != nil {
+                t.Fatalf("ComputeHash: %v", err)
+            }
+            if (got == want) != tt.same {
+                t.Errorf("hash equal to the original = %v, want %v", got == want, tt.same)
+            }
+        })
+    }
+}
+
+func TestComputeHashEmptyChanges(t *testing.T) {
+    withNil := testEntry(1, "user.login")
+    withNil.Changes = nil
+    withNull := withNil
+    withNull.Changes = json.RawMessage("null")
+
+    a, err := ComputeHash(&withNil)
+    if err != nil {
+        t.Fatalf("ComputeHash: %v", err)
+    }
+    b, err := ComputeHash(&withNull)
+    if err != nil {
+        t.Fatalf("ComputeHash: %v", err)
+    }
+    if a != b {
+        t.Errorf("nil and null changes hash differently")
+    }
+}
+
+func TestVerifyChain(t *testing.T) {
+    legacy := func(id uint) models.AuditLog { return testEntry(id, "legacy") }
+
+    tests := []struct {
+        name    string
+        rows    func(t *testing.T) []models.AuditLog
+        checked int
+        legacy  int
+        broken  uint // 0 = chain verifies
+    }{
+        {
+            name: "intact chain",
+            rows: func(t *testing.T) []models.AuditLog {
+                return chain(t, []models.AuditLog{testEntry(1, "a"), testEntry(2, "b"), testEntry(3, "c")})
+            },
+            checked: 3,
+        },
+        {
+            name: "legacy entries before the chain",
+            rows: func(t *testing.T) []models.AuditLog {
+                return append([]models.AuditLog{legacy(1), legacy(2)}, chain(t, []models.AuditLog{testEntry(3, "a"), testEntry(4, "b")})...)
+            },
+            checked: 2,
+            legacy:  2,
+        },
+        {
+            name: "modified entry",
+            rows: func(t *testing.T) []models.AuditLog {
+                rows := chain(t, []models.AuditLog{testEntry(1, "a"), testEntry(2, "b"), testEntry(3, "c")})
+                rows[1].Status = 201
+                return rows
+            },
+            broken: 2,
+        },
+        {
+            name: "removed entry",
+            rows: func(t *testing.T) []models.AuditLog {
+                rows := chain(t, []models.AuditLog{testEntry(1, "a"), testEntry(2, "b"), testEntry(3, "c")})
+                return []models.AuditLog{rows[0], rows[2]}
+            },
+            broken: 3,
+        },
+        {
+            name: "reordered entries",
+            rows: func(t *testing.T) []models.AuditLog {
+                rows := chain(t, []models.AuditLog{testEntry(1, "a"), testEntry(2, "b"), testEntry(3, "c")})
+                return []models.AuditLog{rows[0], rows[2], rows[1]}
+            },
+            broken: 3,
+        },
+        {
+            name: "unhashed entry after the chain started",
+            rows: func(t *testing.T) []models.AuditLog {
+                rows := chain(t, []models.AuditLog{testEntry(1, "a"), testEntry(2, "b")})
+                return append(rows, legacy(3))
+            },
+            broken: 3,
+        },
+        {
+            name: "rehashed entry without relinking the next",
+            rows: func(t *testing.T) []models.AuditLog {
+                rows := chain(t, []models.AuditLog{testEntry(1, "a"), testEntry(2, "b"), testEntry(3, "c")})
+                rows[1].Action = "forged"
+                rows[1].Hash, _ = ComputeHash(&rows[1])
+                return rows
+            },
+            broken: 3,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var result VerifyResult
+            var err error
+            for _, row := range tt.rows(t) {
+                if err = result.check(&row); err != nil {
+                    break
+                }
+            }
+
+            if tt.broken != 0 {
+                var broken *BrokenLink
+                if !errors.As(err, &broken) {
+                    t.Fatalf("err = %v, want a BrokenLink", err)
+                }
+                if broken.ID != tt.broken {
+                    t.Errorf("broken at entry %d, want %d", broken.ID, tt.broken)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("chain did not verify: %v", err)
+            }
+            if result.Checked != tt.checked || result.Legacy != tt.legacy {
+                t.Errorf("checked %d, legacy %d; want %d, %d", result.Checked, result.Legacy, tt.checked, tt.legacy)
+            }
+        })
+    }
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Change is the before/after value of one field
+type Change struct {
+    From interface{} `json:"from"`
+    To   interface{} `json:"to"`
+}
+
+// Diff compares the JSON representations of before and after field by field.
+// A nil before means creation, a nil after means deletion; unchanged fields are omitted.
+func Diff(before, after interface{}) (map[string]Change, error) {
+    from, err := toFields(before)
+    if err != nil {
+        return nil, err
+    }
+    to, err := toFields(after)
+    if err != nil {
+        return nil, err
+    }
+
+    changes := make(map[string]Change)
+    for key, value := range from {
+        if next, ok := to[key]; !ok || !reflect.DeepEqual(value, next) {
+            changes[key] = Change{From: value, To: to[key]}
+        }
+    }
+    for key, value := range to {
+        if _, ok := from[key]; !ok {
+            changes[key] = Change{From: nil, To: value}
+        }
+    }
+
+    return changes, nil
+}
+
+// toFields flattens a value into its top-level JSON fields
+func toFields(v interface{}) (map[string]interface{}, error) {
+    fields := make(map[string]interface{})
+    if v == nil {
+        return fields, nil
+    }
+
+    if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+        return fields, nil
+    }
+
+    raw, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+
+    // Non-object values are recorded under a single key
+    if err := json.Unmarshal(raw, &fields); err != nil {
+        var value interface{}
+        if err := json.Unmarshal(raw, &value); err != nil {
+            return nil, err
+        }
+        return map[string]interface{}{"value": value}, nil
+    }
+
+    return fields, nil
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware audits every successful write request in the group. Handlers that
+// call Record get their detailed entries; any other write gets a generic entry
+// named after the route, so no admin mutation goes unrecorded.
+func Middleware() fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        switch c.Method() {
+        case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+        default:
+            return c.Next()
+        }
+
+        err := c.Next()
+
+        // Failed requests changed nothing
+        status := c.Response().StatusCode()
+        if err != nil || status >= fiber.StatusBadRequest {
+            return err
+        }
+
+        flush(c)
+
+        if recorded, _ := c.Locals(recordedKey).(bool); !recorded {
+            targetType, action := routeAction(c)
+            if werr := Write(c, Entry{
+                Action:     action,
+                TargetType: targetType,
+                TargetID:   c.Params("id"),
+            }); werr != nil {
+                log.Printf("Audit write failed for %s: %v", action, werr)
+            }
+        }
+
+        return nil
+    }
+}
+
+// routeAction derives a target type and action from the matched route,
+// e.g. POST /api/admin/users/:id/unlock -> ("users", "users.unlock")
+func routeAction(c *fiber.Ctx) (string, string) {
+    path := strings.TrimPrefix(c.Route().Path, "/api/admin/")
+
+    var parts []string
+    for _, part := range strings.Split(path, "/") {
+        if part != "" && !strings.HasPrefix(part, ":") {
+            parts = append(parts, part)
+        }
+    }
+    if len(parts) == 0 {
+        return "", strings.ToLower(c.Method())
+    }
+
+    action := strings.Join(parts, ".")
+    if len(parts) == 1 {
+        action += "." + strings.ToLower(c.Method())
+    }
+
+    return parts[0], action
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Bootstraps the first admin: admin registration requires an invite,
+// so the very first one has to be created from the command line.
+// go run ./src/commands/invites/invite_creator.go -email admin@example.com
+func main() {
+    email := flag.String("email", "", "email address of the admin to invite")
+    flag.Parse()
+
+    if strings.TrimSpace(*email) == "" {
+        log.Fatal("-email is required")
+    }
+
+    cfg, err := config.Load()
+    if err != nil {
+        log.Fatalf("Failed to load config: %v", err)
+    }
+
+    if err := database.Connect(cfg); err != nil {
+        log.Fatalf("Database connection failed: %v", err)
+    }
+    defer database.Close()
+
+    if err := database.AutoMigrate(); err != nil {
+        log.Fatalf("Database migration failed: %v", err)
+    }
+
+    ttl := time.Duration(cfg.InviteExpireHours) * time.Hour
+    invite, token, err := models.NewAdminInvite(strings.ToLower(strings.TrimSpace(*email)), nil, ttl)
+    if err != nil {
+        log.Fatalf("Failed to generate invite: %v", err)
+    }
+
+    if err := database.DB.Create(invite).Error; err != nil {
+        log.Fatalf("Failed to create invite: %v", err)
+    }
+
+    fmt.Printf("Invite %d created for %s (expires %s)\n", invite.ID, invite.Email, invite.ExpiresAt.Format(time.RFC3339))
+    fmt.Printf("Invite token: %s\n", token)
+}
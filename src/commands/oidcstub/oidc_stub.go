@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Minimal OpenID Connect provider for local development of the ambassador
+// OIDC login. It auto-approves every authorization request for one user.
+//
+// go run ./src/commands/oidcstub/oidc_stub.go -email jane@example.com
+//
+// then run the API with:
+//   OIDC_ISSUER_URL=http://localhost:9000 OIDC_CLIENT_ID=ambassador OIDC_CLIENT_SECRET=secret
+// and open http://localhost:8000/api/ambassador/oauth/login
+
+type pendingCode struct {
+    clientID      string
+    redirectURI   string
+    nonce         string
+    codeChallenge string
+    expiresAt     time.Time
+}
+
+type stub struct {
+    issuer       string
+    clientID     string
+    clientSecret string
+    email        string
+    verified     bool
+    key          *rsa.PrivateKey
+    kid          string
+
+    mu    sync.Mutex
+    codes map[string]pendingCode
+}
+
+func main() {
+    addr := flag.String("addr", ":9000", "listen address")
+    issuer := flag.String("issuer", "http://localhost:9000", "issuer URL (must match OIDC_ISSUER_URL)")
+    clientID := flag.String("client-id", "ambassador", "accepted client_id")
+    clientSecret := flag.String("client-secret", "secret", "accepted client_secret")
+    email := flag.String("email", "ambassador@example.com", "email of the signed-in user")
+    verified := flag.Bool("email-verified", true, "value of the email_verified claim")
+    flag.Parse()
+
+    key, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        log.Fatalf("Failed to generate signing key: %v", err)
+    }
+
+    s := &stub{
+        issuer:       strings.TrimSuffix(*issuer, "/"),
+        clientID:     *clientID,
+        clientSecret: *clientSecret,
+        email:        *email,
+        verified:     *verified,
+        key:          key,
+        kid:          fmt.Sprintf("stub-%d", time.Now().Unix()),
+        codes:        make(map[string]pendingCode),
+    }
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+    mux.HandleFunc("/authorize", s.authorize)
+    mux.HandleFunc("/token", s.token)
+    mux.HandleFunc("/jwks", s.jwks)
+
+    log.Printf("Stub OIDC provider %s listening on %s (user %s)", s.issuer, *addr, s.email)
+    log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *stub) discovery(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "issuer":                                s.issuer,
+        "authorization_endpoint":                s.issuer + "/authorize",
+        "token_endpoint":                        s.issuer + "/token",
+        "jwks_uri":                              s.issuer + "/jwks",
+        "response_types_supported":              []string{"code"},
+        "subject_types_supported":               []string{"public"},
+        "id_token_signing_alg_values_supported": []string{"RS256"},
+        "code_challenge_methods_supported":      []string{"S256"},
+        "token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+        "scopes_supported":                      []string{"openid", "email", "profile"},
+    })
+}
+
+// authorize auto-approves and redirects back with a code
+func (s *stub) authorize(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+
+    if q.Get("response_type") != "code" || q.Get("client_id") != s.clientID {
+        http.Error(w, "invalid response_type or client_id", http.StatusBadRequest)
+        return
+    }
+    if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+        http.Error(w, "PKCE S256 is required", http.StatusBadRequest)
+        return
+    }
+
+    redirectURI, err := url.Parse(q.Get("redirect_uri"))
+    if err != nil || redirectURI.Scheme == "" {
+        http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+        return
+    }
+
+    code := randomString(24)
+    s.mu.Lock()
+    s.codes[code] = pendingCode{
+        clientID:      q.Get("client_id"),
+        redirectURI:   q.Get("redirect_uri"),
+        nonce:         q.Get("nonce"),
+        codeChallenge: q.Get("code_challenge"),
+        expiresAt:     time.Now().Add(time.Minute),
+    }
+    s.mu.Unlock()
+
+    params := redirectURI.Query()
+    params.Set("code", code)
+    params.Set("state", q.Get("state"))
+    redirectURI.RawQuery = params.Encode()
+
+    http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *stub) token(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if err := r.ParseForm(); err != nil {
+        tokenError(w, "invalid_request")
+        return
+    }
+
+    clientID, clientSecret, ok := r.BasicAuth()
+    if ok {
+        clientID, _ = url.QueryUnescape(clientID)
+        clientSecret, _ = url.QueryUnescape(clientSecret)
+    } else {
+        clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+    }
+    if clientID != s.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) != 1 {
+        tokenError(w, "invalid_client")
+        return
+    }
+
+    if r.PostForm.Get("grant_type") != "authorization_code" {
+        tokenError(w, "unsupported_grant_type")
+        return
+    }
+
+    code := r.PostForm.Get("code")
+    s.mu.Lock()
+    pending, found := s.codes[code]
+    delete(s.codes, code) // codes are single-use
+    s.mu.Unlock()
+
+    if !found || time.Now().After(pending.expiresAt) || pending.redirectURI != r.PostForm.Get("redirect_uri") {
+        tokenError(w, "invalid_grant")
+        return
+    }
+
+    sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+    if base64.RawURLEncoding.EncodeToString(sum[:]) != pending.codeChallenge {
+        tokenError(w, "invalid_grant")
+        return
+    }
+
+    now := time.Now()
+    idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+        "iss":            s.issuer,
+        "sub":            "stub|" + s.email,
+        "aud":            s.clientID,
+        "exp":            now.Add(5 * time.Minute).Unix(),
+        "iat":            now.Unix(),
+        "nonce":          pending.nonce,
+        "email":          s.email,
+        "email_verified": s.verified,
+        "given_name":     "Stub",
+        "family_name":    "User",
+        "name":           "Stub User",
+    })
+    idToken.Header["kid"] = s.kid
+
+    signed, err := idToken.SignedString(s.key)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "access_token": randomString(32),
+        "token_type":   "Bearer",
+        "expires_in":   300,
+        "id_token":     signed,
+    })
+}
+
+func (s *stub) jwks(w http.ResponseWriter, r *http.Request) {
+    pub := s.key.PublicKey
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "keys": []map[string]string{{
+            "kty": "RSA",
+            "use": "sig",
+            "alg": "RS256",
+            "kid": s.kid,
+            "n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+            "e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+        }},
+    })
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+    writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(body)
+}
+
+func randomString(n int) string {
+    b := make([]byte, n)
+    rand.Read(b)
+    return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -40,6 +40,16 @@ func main() {
 
     fmt.Printf("Using %d ambassadors + %d products\n", len(users), len(products))
 
+    // Order items point at the product version they were bought at
+    productIDs := make([]uint, len(products))
+    for i, product := range products {
+        productIDs[i] = product.ID
+    }
+    versions, err := models.LatestProductVersions(database.DB, productIDs)
+    if err != nil {
+        log.Fatalf("Failed to load product versions: %v", err)
+    }
+
     rand.Seed(time.Now().UnixNano())
     streetNames := []string{"Main St", "Quezon Ave", "EDSA", "Makati Ave", "Ayala Ave"}
     cities := []string{"Makati", "Manila", "Quezon City", "Cebu", "Davao"}
@@ -80,18 +90,22 @@ func main() {
         totalRevenue := 0.0
         for j := 0; j < numItems; j++ {
             product := products[rand.Intn(len(products))]
+            price := product.EffectivePrice(time.Now()) // sale price while a sale runs
             qty := uint(rand.Intn(3) + 1)
-            itemRevenue := product.Price * float64(qty) * 0.3
+            itemRevenue := price * float64(qty) * models.AmbassadorShare
             totalRevenue += itemRevenue
             
             order.OrderItems[j] = models.OrderItem{
                 ProductID:         product.ID,
                 ProductTitle:      product.Title,
-                Price:             product.Price,
+                Price:             price,
                 Quantity:          qty,
-                AdminRevenue:      product.Price * float64(qty) * 0.7,
+                AdminRevenue:      price * float64(qty) * (1 - models.AmbassadorShare),
                 AmbassadorRevenue: itemRevenue, // Ambassador gets 30%
             }
+            if version, ok := versions[product.ID]; ok {
+                order.OrderItems[j].ProductVersionID = &version.ID
+            }
         }
 
         if err := database.DB.Create(&order).Error; err != nil {
@@ -105,6 +119,11 @@ func main() {
         }
     }
 
+    // Popularity and revenue sorting read the denormalized summary
+    if err := models.RefreshProductStats(database.DB); err != nil {
+        fmt.Printf("❌ Failed to refresh product stats: %v\n", err)
+    }
+
     fmt.Printf("\nSUCCESS! Created %d REAL revenue orders!\n", createdCount)
     fmt.Println("Test now:")
     fmt.Println("curl -H \"Authorization: Bearer YOUR_TOKEN\" http://localhost:8000/api/ambassador/rankings")
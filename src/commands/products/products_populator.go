@@ -9,6 +9,7 @@ import (
 	"math/rand"
 
 	"github.com/bxcodec/faker/v3"
+	"gorm.io/gorm"
 )
 
 
@@ -34,6 +35,15 @@ func main() {
         "GoPro Hero 12", "Kindle Paperwhite", "Dyson V15", "Samsung 4K TV", "LG OLED C4",
     }
     
+    // Items sold in sizes or configurations get one variant per option
+    variantOptions := map[string][]models.VariantAttributes{
+        "Nike Air Max":       shoeSizes(),
+        "Adidas Ultraboost":  shoeSizes(),
+        "iPhone 15 Pro":      phoneOptions(),
+        "Samsung Galaxy S25": phoneOptions(),
+        "Google Pixel 9":     phoneOptions(),
+    }
+
     for i := 0; i < 50; i++ {
         // Random product details
         product := models.Product{
@@ -43,7 +53,33 @@ func main() {
             Price:       9.99 + rand.Float64()*990.01, // $9.99 - $1000
         }
 
-        if err := database.DB.Create(&product).Error; err != nil {
+        err := database.DB.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Create(&product).Error; err != nil {
+                return err
+            }
+            if _, err := models.RecordProductVersion(tx, &product, models.ProductChangeCreate, nil, nil); err != nil {
+                return err
+            }
+            for j, attributes := range variantOptions[product.Title] {
+                stock := rand.Intn(20)
+                variant := models.ProductVariant{
+                    ProductID:  product.ID,
+                    SKU:        fmt.Sprintf("P%05d-%02d", product.ID, j+1),
+                    Attributes: attributes,
+                    Stock:      &stock,
+                }
+                // Bigger storage costs more
+                if attributes["storage"] == "512GB" {
+                    price := product.Price * 1.2
+                    variant.Price = &price
+                }
+                if err := tx.Create(&variant).Error; err != nil {
+                    return err
+                }
+            }
+            return nil
+        })
+        if err != nil {
             fmt.Printf("Failed to create product %d: %v\n", i, err)
             continue
         }
@@ -56,3 +92,21 @@ func main() {
     
     fmt.Printf("\n Successfully created %d fake products!\n", createdCount)
 }
+
+func shoeSizes() []models.VariantAttributes {
+    var options []models.VariantAttributes
+    for size := 38; size <= 45; size++ {
+        options = append(options, models.VariantAttributes{"size": fmt.Sprint(size)})
+    }
+    return options
+}
+
+func phoneOptions() []models.VariantAttributes {
+    var options []models.VariantAttributes
+    for _, color := range []string{"black", "white", "blue"} {
+        for _, storage := range []string{"256GB", "512GB"} {
+            options = append(options, models.VariantAttributes{"color": color, "storage": storage})
+        }
+    }
+    return options
+}
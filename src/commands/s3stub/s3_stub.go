@@ -0,0 +1,144 @@
+package main
+
+import (
+	"ambassador/src/storage"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal S3-compatible object store for local development and for checking
+// the S3 storage adapter without AWS. It keeps objects in memory, verifies
+// SigV4 signatures and payload hashes on writes, and serves reads publicly
+// (like a public-read bucket behind a CDN).
+//
+// go run ./src/commands/s3stub/s3_stub.go
+//
+// then run the API with:
+//   STORAGE_DRIVER=s3 S3_ENDPOINT=http://localhost:9100 S3_BUCKET=ambassador
+//   S3_ACCESS_KEY=stub S3_SECRET_KEY=stub-secret
+
+type object struct {
+    data        []byte
+    contentType string
+    modified    time.Time
+}
+
+type stub struct {
+    bucket string
+    creds  storage.Credentials
+
+    mu      sync.RWMutex
+    objects map[string]object
+}
+
+func main() {
+    addr := flag.String("addr", ":9100", "listen address")
+    bucket := flag.String("bucket", "ambassador", "bucket name (must match S3_BUCKET)")
+    accessKey := flag.String("access-key", "stub", "accepted access key")
+    secretKey := flag.String("secret-key", "stub-secret", "accepted secret key")
+    region := flag.String("region", "us-east-1", "region in the signing scope (must match S3_REGION)")
+    flag.Parse()
+
+    s := &stub{
+        bucket:  *bucket,
+        creds:   storage.Credentials{AccessKey: *accessKey, SecretKey: *secretKey, Region: *region},
+        objects: make(map[string]object),
+    }
+
+    log.Printf("Stub S3 bucket %q listening on %s", s.bucket, *addr)
+    log.Fatal(http.ListenAndServe(*addr, s))
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    // Path-style only: /bucket/key
+    bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+    if bucket != s.bucket || key == "" {
+        s3Error(w, http.StatusNotFound, "NoSuchBucket", "unknown bucket or empty key")
+        return
+    }
+
+    switch r.Method {
+    case http.MethodGet, http.MethodHead:
+        s.get(w, r, key)
+    case http.MethodPut:
+        s.put(w, r, key)
+    case http.MethodDelete:
+        s.delete(w, r, key)
+    default:
+        s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+    }
+}
+
+func (s *stub) get(w http.ResponseWriter, r *http.Request, key string) {
+    s.mu.RLock()
+    obj, found := s.objects[key]
+    s.mu.RUnlock()
+
+    if !found {
+        s3Error(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+        return
+    }
+
+    w.Header().Set("Content-Type", obj.contentType)
+    w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+    if r.Method == http.MethodHead {
+        return
+    }
+    w.Write(obj.data)
+}
+
+func (s *stub) put(w http.ResponseWriter, r *http.Request, key string) {
+    body, err := io.ReadAll(r.Body)
+    if err != nil {
+        s3Error(w, http.StatusBadRequest, "IncompleteBody", "could not read body")
+        return
+    }
+    if !s.authorized(w, r, body) {
+        return
+    }
+
+    s.mu.Lock()
+    s.objects[key] = object{data: body, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+    s.mu.Unlock()
+
+    log.Printf("PUT %s (%d bytes)", key, len(body))
+    w.WriteHeader(http.StatusOK)
+}
+
+func (s *stub) delete(w http.ResponseWriter, r *http.Request, key string) {
+    if !s.authorized(w, r, nil) {
+        return
+    }
+
+    s.mu.Lock()
+    delete(s.objects, key)
+    s.mu.Unlock()
+
+    log.Printf("DELETE %s", key)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized checks the signature and that the signed payload hash matches the body
+func (s *stub) authorized(w http.ResponseWriter, r *http.Request, body []byte) bool {
+    if err := storage.VerifyV4(r, s.creds, 15*time.Minute, time.Now()); err != nil {
+        log.Printf("Rejected %s %s: %v", r.Method, r.URL.Path, err)
+        s3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+        return false
+    }
+    if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != "UNSIGNED-PAYLOAD" && hash != storage.PayloadHash(body) {
+        s3Error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash does not match the body")
+        return false
+    }
+    return true
+}
+
+func s3Error(w http.ResponseWriter, status int, code, message string) {
+    w.Header().Set("Content-Type", "application/xml")
+    w.WriteHeader(status)
+    io.WriteString(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Error><Code>"+code+"</Code><Message>"+message+"</Message></Error>")
+}
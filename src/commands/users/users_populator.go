@@ -6,6 +6,7 @@ import (
 	"ambassador/src/models"
 	"ambassador/src/utils"
 	"log"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 )
@@ -26,11 +27,13 @@ func main() {
     
     var createdCount int
     for i := 0; i < 30; i++ {
+        verifiedAt := time.Now()
         ambassador := models.User{
             FirstName:    faker.FirstName(),
             LastName:     faker.LastName(),
             Email:        faker.Email(),
             IsAmbassador: true,
+            EmailVerifiedAt: &verifiedAt, // Seeded users skip email verification
         }
 
         hashPassword, err := utils.HashPassword("12345678")
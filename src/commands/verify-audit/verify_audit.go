@@ -0,0 +1,57 @@
+package main
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Walks the audit log hash chain and reports the first broken link.
+// Exits 1 if the chain is broken, 2 on other errors.
+// go run ./src/commands/verify-audit
+func main() {
+    batch := flag.Int("batch", 1000, "entries loaded per query")
+    flag.Parse()
+
+    if *batch <= 0 {
+        log.Fatal("-batch must be positive")
+    }
+
+    cfg, err := config.Load()
+    if err != nil {
+        log.Fatalf("Failed to load config: %v", err)
+    }
+
+    if err := database.Connect(cfg); err != nil {
+        log.Fatalf("Database connection failed: %v", err)
+    }
+    defer database.Close()
+
+    result, err := audit.Verify(database.DB, *batch)
+
+    var broken *audit.BrokenLink
+    if errors.As(err, &broken) {
+        fmt.Printf("BROKEN: entry %d: %s\n", broken.ID, broken.Reason)
+        fmt.Printf("%d entries verified before the break\n", result.Checked)
+        database.Close()
+        os.Exit(1)
+    }
+    if err != nil {
+        log.Printf("Verification failed: %v", err)
+        database.Close()
+        os.Exit(2)
+    }
+
+    fmt.Printf("OK: %d entries verified\n", result.Checked)
+    if result.Legacy > 0 {
+        fmt.Printf("%d earlier entries predate hash chaining and are not covered\n", result.Legacy)
+    }
+    if result.LastHash != "" {
+        fmt.Printf("Chain head: %s\n", result.LastHash)
+    }
+}
@@ -6,7 +6,9 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,8 @@ type Config struct {
     // Application
     Environment string
     AppPort     string
+    AppURL      string // Public base URL used in emailed links
+    FrontendURL string // Base URL of the web app (password reset page)
     
     // Database
     DBHost     string
@@ -31,6 +35,85 @@ type Config struct {
     // JWT
     JWTSecret      string
     JWTExpireHours int
+
+    // Password policy
+    PasswordMinLength     int
+    PasswordMinScore      int    // 0..4 strength score
+    PasswordHistorySize   int    // recent passwords that can't be reused
+    BreachedPasswordsPath string // SHA-1 list file or HIBP range directory; empty = disabled
+
+    // Password hashing (existing hashes are upgraded on login)
+    PasswordHashAlgorithm string // "bcrypt" or "argon2id"
+    BcryptCost            int
+    Argon2MemoryKiB       int
+    Argon2Iterations      int
+    Argon2Threads         int
+
+    // Login brute-force protection
+    LoginIPMaxAttempts      int
+    LoginIPWindowMinutes    int
+    LoginEmailMaxFailures   int
+    LoginEmailWindowMinutes int
+    LoginLockoutBaseSeconds int
+    LoginLockoutMaxMinutes  int
+
+    // Two-factor authentication
+    MFARequireAdmin     bool   // every admin must enroll TOTP before using the API
+    MFAIssuer           string // shown in authenticator apps
+    MFAChallengeMinutes int
+
+    // Support impersonation of ambassadors by admins
+    ImpersonationMinutes int
+
+    // OpenID Connect login for ambassadors (disabled when OIDCIssuerURL is empty)
+    OIDCIssuerURL       string
+    OIDCClientID        string
+    OIDCClientSecret    string
+    OIDCRedirectURL     string
+    OIDCScopes          string // space separated
+    OIDCSuccessRedirect string // where the browser lands after login; empty = JSON response
+
+    // Product search: "mysql" (FULLTEXT) or "memory" (in-process inverted index).
+    // "memory" is for single-instance deployments: each process only sees
+    // product changes made through itself.
+    SearchBackend string
+
+    // Checkout: how long a pending order holds its stock before it is released
+    CheckoutReservationMinutes int
+    CheckoutIPMaxOrders        int // checkouts per IP per CheckoutIPWindowMinutes
+    CheckoutIPWindowMinutes    int
+    CheckoutMaxPendingOrders   int // unpaid orders holding stock per buyer email or IP
+    // Shared secret of the payment provider's confirmation webhook (HMAC-SHA256);
+    // empty disables confirmation
+    CheckoutWebhookSecret string
+
+    // Uploaded files (product images): "local" (served from /uploads) or "s3"
+    StorageDriver    string
+    StorageLocalPath string
+    StoragePublicURL string // base URL of stored files; local default APP_URL/uploads
+    S3Endpoint       string
+    S3Region         string
+    S3Bucket         string
+    S3AccessKey      string
+    S3SecretKey      string
+
+    // Product image uploads
+    ImageMaxUploadMB int
+    ImageMaxPixels   int // width*height limit, guards against decompression bombs
+
+    // Admin invites
+    InviteExpireHours int
+
+    // Mail
+    MailDriver   string // "log" (writes to MailLogPath or stdout) or "smtp"
+    MailFrom     string
+    MailLogPath  string
+    SMTPHost     string
+    SMTPPort     string
+    SMTPUsername string
+    SMTPPassword string
+    EmailVerificationExpireHours int
+    PasswordResetExpireMinutes   int
     
     // CORS
     CORSOrigins string
@@ -58,6 +141,8 @@ func Load() (*Config, error) {
         config := &Config{
             Environment:    getEnv("ENVIRONMENT", "development"),
             AppPort:        getEnv("APP_PORT", "8000"),
+            AppURL:         getEnv("APP_URL", "http://localhost:8000"),
+            FrontendURL:    getEnv("FRONTEND_URL", "http://localhost:3000"),
             DBHost:         getEnv("DB_HOST", "localhost"),
             DBPort:         getEnv("DB_PORT", "3306"),
             DBUser:         getEnv("DB_USER", "root"),
@@ -68,9 +153,69 @@ func Load() (*Config, error) {
             RedisPassword: getEnv("REDIS_PASSWORD", ""),
             JWTSecret:      getEnv("JWT_SECRET", ""),
             JWTExpireHours: getEnvInt("JWT_EXPIRE_HOURS", 24),
+            PasswordMinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+            PasswordMinScore:      getEnvInt("PASSWORD_MIN_SCORE", 2),
+            PasswordHistorySize:   getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+            BreachedPasswordsPath: getEnv("BREACHED_PASSWORDS_PATH", ""),
+
+            PasswordHashAlgorithm: strings.ToLower(getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt")),
+            BcryptCost:            getEnvInt("BCRYPT_COST", 12),
+            Argon2MemoryKiB:       getEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+            Argon2Iterations:      getEnvInt("ARGON2_ITERATIONS", 3),
+            Argon2Threads:         getEnvInt("ARGON2_THREADS", 2),
+            LoginIPMaxAttempts:      getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+            LoginIPWindowMinutes:    getEnvInt("LOGIN_IP_WINDOW_MINUTES", 15),
+            LoginEmailMaxFailures:   getEnvInt("LOGIN_EMAIL_MAX_FAILURES", 5),
+            LoginEmailWindowMinutes: getEnvInt("LOGIN_EMAIL_WINDOW_MINUTES", 15),
+            LoginLockoutBaseSeconds: getEnvInt("LOGIN_LOCKOUT_BASE_SECONDS", 60),
+            LoginLockoutMaxMinutes:  getEnvInt("LOGIN_LOCKOUT_MAX_MINUTES", 60),
+            MFARequireAdmin:     getEnvBool("MFA_REQUIRE_ADMIN", false),
+            MFAIssuer:           getEnv("MFA_ISSUER", "Ambassador"),
+            MFAChallengeMinutes: getEnvInt("MFA_CHALLENGE_MINUTES", 5),
+            ImpersonationMinutes: getEnvInt("IMPERSONATION_MINUTES", 15),
+            OIDCIssuerURL:       getEnv("OIDC_ISSUER_URL", ""),
+            OIDCClientID:        getEnv("OIDC_CLIENT_ID", ""),
+            OIDCClientSecret:    getEnv("OIDC_CLIENT_SECRET", ""),
+            OIDCScopes:          getEnv("OIDC_SCOPES", "openid email profile"),
+            OIDCSuccessRedirect: getEnv("OIDC_SUCCESS_REDIRECT", ""),
+            SearchBackend: strings.ToLower(getEnv("SEARCH_BACKEND", "mysql")),
+            CheckoutReservationMinutes: getEnvInt("CHECKOUT_RESERVATION_MINUTES", 15),
+            CheckoutIPMaxOrders:        getEnvInt("CHECKOUT_IP_MAX_ORDERS", 10),
+            CheckoutIPWindowMinutes:    getEnvInt("CHECKOUT_IP_WINDOW_MINUTES", 15),
+            CheckoutMaxPendingOrders:   getEnvInt("CHECKOUT_MAX_PENDING_ORDERS", 3),
+            CheckoutWebhookSecret:      getEnv("CHECKOUT_WEBHOOK_SECRET", ""),
+            StorageDriver:    strings.ToLower(getEnv("STORAGE_DRIVER", "local")),
+            StorageLocalPath: getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+            S3Endpoint:       getEnv("S3_ENDPOINT", ""),
+            S3Region:         getEnv("S3_REGION", "us-east-1"),
+            S3Bucket:         getEnv("S3_BUCKET", ""),
+            S3AccessKey:      getEnv("S3_ACCESS_KEY", ""),
+            S3SecretKey:      getEnv("S3_SECRET_KEY", ""),
+            ImageMaxUploadMB: getEnvInt("IMAGE_MAX_UPLOAD_MB", 10),
+            ImageMaxPixels:   getEnvInt("IMAGE_MAX_PIXELS", 40_000_000),
+            InviteExpireHours: getEnvInt("INVITE_EXPIRE_HOURS", 72),
+            MailDriver:     getEnv("MAIL_DRIVER", "log"),
+            MailFrom:       getEnv("MAIL_FROM", "no-reply@ambassador.local"),
+            MailLogPath:    getEnv("MAIL_LOG_PATH", ""),
+            SMTPHost:       getEnv("SMTP_HOST", ""),
+            SMTPPort:       getEnv("SMTP_PORT", "587"),
+            SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+            SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+            EmailVerificationExpireHours: getEnvInt("EMAIL_VERIFICATION_EXPIRE_HOURS", 48),
+            PasswordResetExpireMinutes:   getEnvInt("PASSWORD_RESET_EXPIRE_MINUTES", 30),
             CORSOrigins:    getEnv("CORS_ORIGINS", "http://localhost:3000"),
         }
 
+        // Local uploads are served by this API
+        if config.StorageDriver == "local" {
+            config.StoragePublicURL = getEnv("STORAGE_PUBLIC_URL", config.AppURL+"/uploads")
+        } else {
+            config.StoragePublicURL = getEnv("STORAGE_PUBLIC_URL", "")
+        }
+
+        // Callback defaults to this API's own endpoint
+        config.OIDCRedirectURL = getEnv("OIDC_REDIRECT_URL", config.AppURL+"/api/ambassador/oauth/callback")
+
         // Validate configuration
         if err := config.Validate(); err != nil {
             loadErr = fmt.Errorf("config validation failed: %w", err)
@@ -140,6 +285,134 @@ func (c *Config) Validate() error {
         return errors.New("JWT_EXPIRE_HOURS must be between 1 and 168")
     }
 
+    if c.PasswordMinLength < 8 || c.PasswordMinLength > 72 {
+        return errors.New("PASSWORD_MIN_LENGTH must be between 8 and 72")
+    }
+
+    if c.PasswordMinScore < 0 || c.PasswordMinScore > 4 {
+        return errors.New("PASSWORD_MIN_SCORE must be between 0 and 4")
+    }
+
+    if c.PasswordHistorySize < 0 || c.PasswordHistorySize > 24 {
+        return errors.New("PASSWORD_HISTORY_SIZE must be between 0 and 24")
+    }
+
+    if c.SearchBackend != "mysql" && c.SearchBackend != "memory" {
+        return errors.New("SEARCH_BACKEND must be mysql or memory")
+    }
+
+    if c.CheckoutReservationMinutes < 1 || c.CheckoutReservationMinutes > 1440 {
+        return errors.New("CHECKOUT_RESERVATION_MINUTES must be between 1 and 1440")
+    }
+
+    if c.CheckoutIPMaxOrders <= 0 || c.CheckoutMaxPendingOrders <= 0 {
+        return errors.New("CHECKOUT_IP_MAX_ORDERS and CHECKOUT_MAX_PENDING_ORDERS must be positive")
+    }
+
+    if c.CheckoutIPWindowMinutes < 1 || c.CheckoutIPWindowMinutes > 1440 {
+        return errors.New("CHECKOUT_IP_WINDOW_MINUTES must be between 1 and 1440")
+    }
+
+    if c.CheckoutWebhookSecret != "" && len(c.CheckoutWebhookSecret) < 32 {
+        return errors.New("CHECKOUT_WEBHOOK_SECRET must be at least 32 characters")
+    }
+
+    switch c.StorageDriver {
+    case "local":
+        if c.StorageLocalPath == "" {
+            return errors.New("STORAGE_LOCAL_PATH is required when STORAGE_DRIVER=local")
+        }
+    case "s3":
+        if c.S3Endpoint == "" || c.S3Bucket == "" || c.S3AccessKey == "" || c.S3SecretKey == "" {
+            return errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required when STORAGE_DRIVER=s3")
+        }
+    default:
+        return errors.New("STORAGE_DRIVER must be local or s3")
+    }
+
+    if c.ImageMaxUploadMB < 1 || c.ImageMaxUploadMB > 100 {
+        return errors.New("IMAGE_MAX_UPLOAD_MB must be between 1 and 100")
+    }
+
+    if c.ImageMaxPixels < 1_000_000 {
+        return errors.New("IMAGE_MAX_PIXELS must be at least 1000000")
+    }
+
+    switch c.PasswordHashAlgorithm {
+    case "bcrypt":
+        if c.BcryptCost < 10 || c.BcryptCost > 31 {
+            return errors.New("BCRYPT_COST must be between 10 and 31")
+        }
+    case "argon2id":
+        if c.Argon2MemoryKiB < 8*1024 || c.Argon2MemoryKiB > 4*1024*1024 {
+            return errors.New("ARGON2_MEMORY_KIB must be between 8192 and 4194304")
+        }
+        if c.Argon2Iterations < 1 || c.Argon2Iterations > 20 {
+            return errors.New("ARGON2_ITERATIONS must be between 1 and 20")
+        }
+        if c.Argon2Threads < 1 || c.Argon2Threads > 64 {
+            return errors.New("ARGON2_THREADS must be between 1 and 64")
+        }
+    default:
+        return fmt.Errorf("PASSWORD_HASH_ALGORITHM must be bcrypt or argon2id, got %q", c.PasswordHashAlgorithm)
+    }
+
+    if c.LoginIPMaxAttempts <= 0 || c.LoginEmailMaxFailures <= 0 {
+        return errors.New("LOGIN_IP_MAX_ATTEMPTS and LOGIN_EMAIL_MAX_FAILURES must be positive")
+    }
+
+    if c.LoginIPWindowMinutes <= 0 || c.LoginEmailWindowMinutes <= 0 ||
+       c.LoginIPWindowMinutes > 1440 || c.LoginEmailWindowMinutes > 1440 { // Max 1 day
+        return errors.New("LOGIN_*_WINDOW_MINUTES must be between 1 and 1440")
+    }
+
+    if c.LoginLockoutBaseSeconds <= 0 || c.LoginLockoutMaxMinutes <= 0 ||
+       time.Duration(c.LoginLockoutBaseSeconds)*time.Second > time.Duration(c.LoginLockoutMaxMinutes)*time.Minute {
+        return errors.New("LOGIN_LOCKOUT_BASE_SECONDS must be positive and not exceed LOGIN_LOCKOUT_MAX_MINUTES")
+    }
+
+    if c.MFAChallengeMinutes <= 0 || c.MFAChallengeMinutes > 30 {
+        return errors.New("MFA_CHALLENGE_MINUTES must be between 1 and 30")
+    }
+
+    if c.ImpersonationMinutes < 1 || c.ImpersonationMinutes > 60 {
+        return errors.New("IMPERSONATION_MINUTES must be between 1 and 60")
+    }
+
+    if c.OIDCIssuerURL != "" {
+        if c.OIDCClientID == "" || c.OIDCRedirectURL == "" {
+            return errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+        }
+        if !strings.Contains(" "+c.OIDCScopes+" ", " openid ") {
+            return errors.New("OIDC_SCOPES must include openid")
+        }
+        if c.IsProduction() && !strings.HasPrefix(c.OIDCIssuerURL, "https://") {
+            return errors.New("OIDC_ISSUER_URL must use https in production")
+        }
+    }
+
+    if c.InviteExpireHours <= 0 || c.InviteExpireHours > 720 { // Max 30 days
+        return errors.New("INVITE_EXPIRE_HOURS must be between 1 and 720")
+    }
+
+    switch c.MailDriver {
+    case "log":
+    case "smtp":
+        if c.SMTPHost == "" {
+            return errors.New("SMTP_HOST is required when MAIL_DRIVER=smtp")
+        }
+    default:
+        return fmt.Errorf("MAIL_DRIVER must be log or smtp, got %q", c.MailDriver)
+    }
+
+    if c.EmailVerificationExpireHours <= 0 {
+        return errors.New("EMAIL_VERIFICATION_EXPIRE_HOURS must be positive")
+    }
+
+    if c.PasswordResetExpireMinutes <= 0 || c.PasswordResetExpireMinutes > 1440 { // Max 1 day
+        return errors.New("PASSWORD_RESET_EXPIRE_MINUTES must be between 1 and 1440")
+    }
+
     return nil
 }
 
@@ -169,6 +442,7 @@ func (c *Config) MaskSensitive() map[string]string {
     return map[string]string{
         "ENVIRONMENT":      c.Environment,
         "APP_PORT":         c.AppPort,
+        "APP_URL":          c.AppURL,
         "DB_HOST":          c.DBHost,
         "DB_PORT":          c.DBPort,
         "DB_USER":          c.DBUser,
@@ -176,7 +450,14 @@ func (c *Config) MaskSensitive() map[string]string {
         "DB_NAME":          c.DBName,
         "JWT_SECRET":       "****",
         "JWT_EXPIRE_HOURS": strconv.Itoa(c.JWTExpireHours),
+        "INVITE_EXPIRE_HOURS": strconv.Itoa(c.InviteExpireHours),
         "CORS_ORIGINS":     c.CORSOrigins,
+        "MFA_REQUIRE_ADMIN": strconv.FormatBool(c.MFARequireAdmin),
+        "MAIL_DRIVER":      c.MailDriver,
+        "SMTP_HOST":        c.SMTPHost,
+        "SMTP_PASSWORD":    "****",
+        "OIDC_ISSUER_URL":  c.OIDCIssuerURL,
+        "OIDC_CLIENT_SECRET": "****",
     }
 }
 
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/utils"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+    maxAPIKeysPerUser      = 10
+    defaultAPIKeyExpiryDays = 90
+    maxAPIKeyExpiryDays    = 365
+)
+
+type CreateAPIKeyRequest struct {
+    Name          string   `json:"name" validate:"required,min=1,max=100"`
+    Scopes        []string `json:"scopes"`
+    ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type APIKeyResponse struct {
+    ID         uint       `json:"id"`
+    Name       string     `json:"name"`
+    Prefix     string     `json:"prefix"`
+    Scopes     []string   `json:"scopes"`
+    ExpiresAt  *time.Time `json:"expires_at"`
+    LastUsedAt *time.Time `json:"last_used_at"`
+    LastUsedIP string     `json:"last_used_ip"`
+    RevokedAt  *time.Time `json:"revoked_at"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeys lists the authenticated ambassador's API keys
+// GET /api/ambassador/api-keys
+func APIKeys(c *fiber.Ctx) error {
+    userID, err := middlewares.GetUserID(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var keys []models.APIKey
+    if err := database.DB.
+        Where("user_id = ?", userID).
+        Order("id DESC").
+        Find(&keys).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch API keys",
+        })
+    }
+
+    data := make([]APIKeyResponse, len(keys))
+    for i := range keys {
+        data[i] = toAPIKeyResponse(&keys[i])
+    }
+
+    return c.JSON(fiber.Map{
+        "data":       data,
+        "count":      len(data),
+        "scopes":     models.APIKeyScopes,
+        "is_success": true,
+    })
+}
+
+// CreateAPIKey issues a new personal API key; the plain key is returned only once
+// POST /api/ambassador/api-keys
+func CreateAPIKey(c *fiber.Ctx) error {
+    userID, err := middlewares.GetUserID(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var data CreateAPIKeyRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Name = strings.TrimSpace(data.Name)
+    if data.Name == "" || len(data.Name) > 100 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "name is required (max 100 characters)",
+        })
+    }
+
+    // Least privilege by default
+    if len(data.Scopes) == 0 {
+        data.Scopes = []string{models.APIKeyScopeStatsRead}
+    }
+    scopes := make([]string, 0, len(data.Scopes))
+    seen := map[string]bool{}
+    for _, scope := range data.Scopes {
+        scope = strings.TrimSpace(scope)
+        if !models.IsValidAPIKeyScope(scope) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error":  fmt.Sprintf("unknown scope %q", scope),
+                "scopes": models.APIKeyScopes,
+            })
+        }
+        if !seen[scope] {
+            seen[scope] = true
+            scopes = append(scopes, scope)
+        }
+    }
+
+    if data.ExpiresInDays == 0 {
+        data.ExpiresInDays = defaultAPIKeyExpiryDays
+    }
+    if data.ExpiresInDays < 0 || data.ExpiresInDays > maxAPIKeyExpiryDays {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": fmt.Sprintf("expires_in_days must be between 1 and %d", maxAPIKeyExpiryDays),
+        })
+    }
+
+    var active int64
+    database.DB.Model(&models.APIKey{}).
+        Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+        Count(&active)
+    if active >= maxAPIKeysPerUser {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": fmt.Sprintf("maximum of %d active API keys reached", maxAPIKeysPerUser),
+        })
+    }
+
+    rawKey, prefix, err := generateAPIKey()
+    if err != nil {
+        log.Printf("API key generation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create API key",
+        })
+    }
+
+    expiresAt := time.Now().AddDate(0, 0, data.ExpiresInDays)
+    key := models.APIKey{
+        UserID:    userID,
+        Name:      data.Name,
+        Prefix:    prefix,
+        KeyHash:   utils.HashToken(rawKey),
+        Scopes:    strings.Join(scopes, ","),
+        ExpiresAt: &expiresAt,
+    }
+
+    if err := database.DB.Create(&key).Error; err != nil {
+        log.Printf("API key creation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create API key",
+        })
+    }
+
+    log.Printf("User %d created API key %d (%s)", userID, key.ID, key.Prefix)
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "API key created, store it now: it won't be shown again",
+        "key":     rawKey,
+        "data":    toAPIKeyResponse(&key),
+    })
+}
+
+// RevokeAPIKey revokes one of the authenticated ambassador's keys
+// DELETE /api/ambassador/api-keys/:id
+func RevokeAPIKey(c *fiber.Ctx) error {
+    userID, err := middlewares.GetUserID(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid API key ID",
+        })
+    }
+
+    result := database.DB.Model(&models.APIKey{}).
+        Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+        Update("revoked_at", time.Now())
+    if result.Error != nil {
+        log.Printf("Failed to revoke API key %d: %v", id, result.Error)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to revoke API key",
+        })
+    }
+    if result.RowsAffected == 0 {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+            "error": "API key not found",
+        })
+    }
+
+    log.Printf("User %d revoked API key %d", userID, id)
+
+    return c.JSON(fiber.Map{
+        "message":    "API key revoked successfully",
+        "revoked_id": id,
+    })
+}
+
+// generateAPIKey returns the full key and its displayable prefix
+func generateAPIKey() (string, string, error) {
+    id, err := utils.GenerateToken(6) // 8 characters
+    if err != nil {
+        return "", "", err
+    }
+    secret, err := utils.GenerateToken(32)
+    if err != nil {
+        return "", "", err
+    }
+
+    prefix := middlewares.APIKeyPrefix + id
+    return prefix + "_" + secret, prefix, nil
+}
+
+func toAPIKeyResponse(key *models.APIKey) APIKeyResponse {
+    return APIKeyResponse{
+        ID:         key.ID,
+        Name:       key.Name,
+        Prefix:     key.Prefix,
+        Scopes:     key.ScopeList(),
+        ExpiresAt:  key.ExpiresAt,
+        LastUsedAt: key.LastUsedAt,
+        LastUsedIP: key.LastUsedIP,
+        RevokedAt:  key.RevokedAt,
+        CreatedAt:  key.CreatedAt,
+    }
+}
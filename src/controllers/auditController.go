@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditLogListResponse struct {
+    TotalCount int64             `json:"total_count"`
+    Data       []models.AuditLog `json:"data"`
+    Page       int               `json:"page"`
+    PerPage    int               `json:"per_page"`
+    LastPage   int               `json:"last_page"`
+}
+
+// AuditLogs lists audit entries, newest first
+// GET /api/admin/audit?actor_id=&action=&target_type=&target_id=&request_id=&from=&to=&page=&per_page=
+// from/to accept RFC 3339 timestamps or YYYY-MM-DD dates (to is inclusive for dates)
+func AuditLogs(c *fiber.Ctx) error {
+    page := max(1, atoi(c.Query("page", "1")))
+    perPage := clamp(atoi(c.Query("per_page", "50")), 1, 200)
+
+    db := database.DB.WithContext(c.Context()).Model(&models.AuditLog{})
+
+    if actorID := atoi(c.Query("actor_id")); actorID > 0 {
+        db = db.Where("actor_id = ?", actorID)
+    }
+    if action := c.Query("action"); action != "" {
+        // "product" matches product.create, product.update, ...
+        db = db.Where("action = ? OR action LIKE ?", action, likeEscaper.Replace(action)+".%")
+    }
+    if targetType := c.Query("target_type"); targetType != "" {
+        db = db.Where("target_type = ?", targetType)
+    }
+    if targetID := c.Query("target_id"); targetID != "" {
+        db = db.Where("target_id = ?", targetID)
+    }
+    if requestID := c.Query("request_id"); requestID != "" {
+        db = db.Where("request_id = ?", requestID)
+    }
+
+    if from := c.Query("from"); from != "" {
+        t, _, err := parseAuditTime(from)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "from must be an RFC 3339 timestamp or YYYY-MM-DD",
+            })
+        }
+        db = db.Where("created_at >= ?", t)
+    }
+    if to := c.Query("to"); to != "" {
+        t, dateOnly, err := parseAuditTime(to)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "to must be an RFC 3339 timestamp or YYYY-MM-DD",
+            })
+        }
+        if dateOnly {
+            db = db.Where("created_at < ?", t.AddDate(0, 0, 1))
+        } else {
+            db = db.Where("created_at <= ?", t)
+        }
+    }
+
+    var total int64
+    if err := db.Count(&total).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to count audit entries",
+        })
+    }
+
+    logs := []models.AuditLog{}
+    if err := db.
+        Order("id DESC").
+        Offset((page - 1) * perPage).
+        Limit(perPage).
+        Find(&logs).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch audit entries",
+        })
+    }
+
+    return c.JSON(AuditLogListResponse{
+        TotalCount: total,
+        Data:       logs,
+        Page:       page,
+        PerPage:    perPage,
+        LastPage:   int(math.Ceil(float64(total) / float64(perPage))),
+    })
+}
+
+// parseAuditTime parses a timestamp or a date, reporting which one it was
+func parseAuditTime(value string) (time.Time, bool, error) {
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t.UTC(), false, nil
+    }
+    t, err := time.Parse("2006-01-02", value)
+    return t, true, err
+}
@@ -1,16 +1,21 @@
 package controllers
 
 import (
+	"ambassador/src/config"
 	"ambassador/src/database"
 	"ambassador/src/middlewares"
 	"ambassador/src/models"
+	"ambassador/src/passwordpolicy"
+	"ambassador/src/ratelimit"
 	"ambassador/src/utils"
+	"errors"
 	"log"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
@@ -21,6 +26,7 @@ type RegisterRequest struct {
     Email           string `json:"email" validate:"required,email,max=255"`
     Password        string `json:"password" validate:"required,min=8,max=72"`
     PasswordConfirm string `json:"password_confirm" validate:"required"`
+    InviteToken     string `json:"invite_token"` // required for /api/admin/register
 }
 
 type UserResponse struct {
@@ -29,6 +35,7 @@ type UserResponse struct {
     LastName    string `json:"last_name"`
     Email       string `json:"email"`
     IsAmbassador bool  `json:"is_ambassador"`
+    EmailVerifiedAt *time.Time `json:"email_verified_at"`
     Revenue     *float64 `json:"revenue,omitempty" gorm:"-"`
 }
 
@@ -57,9 +64,7 @@ func Register(c *fiber.Ctx) error {
 
    // Validate input
    if err := validateRegistration(&data); err != nil {
-	   return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-		"error":   err.Error(),
-	})
+	   return passwordError(c, err)
    }
 
 	// Hash password
@@ -74,6 +79,17 @@ func Register(c *fiber.Ctx) error {
     // Determine if ambassador and exact path prefix match for /api/ambassador/register
     isAmbassador := strings.HasPrefix(c.Path(), "/api/ambassador")
 
+    // Admin sign-up requires a valid invite bound to this email
+    var invite *models.AdminInvite
+    if !isAmbassador {
+        invite, err = findUsableInvite(data.InviteToken, data.Email)
+        if err != nil {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+                "error": "invalid or expired invite",
+            })
+        }
+    }
+
 	// Create user
     user := models.User{
         FirstName:    data.FirstName,
@@ -83,8 +99,28 @@ func Register(c *fiber.Ctx) error {
         IsAmbassador: isAmbassador, 
     }
 
+    // The invite was bound to this email, which proves ownership
+    if invite != nil {
+        now := time.Now()
+        user.EmailVerifiedAt = &now
+    }
+
 	// INSERT FIRST — DB ENFORCES UNIQUENESS
-    if err := database.DB.Create(&user).Error; err != nil {
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&user).Error; err != nil {
+            return err
+        }
+        if invite != nil {
+            return redeemInvite(tx, invite, user.ID)
+        }
+        return nil
+    })
+    if err != nil {
+        if errors.Is(err, errInviteUsed) {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+                "error": "invalid or expired invite",
+            })
+        }
         if strings.Contains(err.Error(), "Duplicate entry") || 
            strings.Contains(err.Error(), "UNIQUE constraint") {
             // Generic message to prevent user enumeration
@@ -98,12 +134,19 @@ func Register(c *fiber.Ctx) error {
         })
     }
 
+    if invite != nil {
+        log.Printf("Admin %d registered with invite %d (invited by %v)", user.ID, invite.ID, invite.InvitedByID)
+    } else {
+        sendVerificationEmail(&user)
+    }
+
 	return c.Status(fiber.StatusCreated).JSON(UserResponse{
         ID:           user.ID,
         FirstName:    user.FirstName,
         LastName:     user.LastName,
         Email:        user.Email,
         IsAmbassador: user.IsAmbassador,
+        EmailVerifiedAt: user.EmailVerifiedAt,
     })
 }
 
@@ -120,21 +163,45 @@ func validateRegistration(data *RegisterRequest) error {
     }
 
 	// Password rules
-    if len(data.Password) < 8 {
-        return fiber.NewError(fiber.StatusBadRequest, "Password must be at least 8 characters")
-    }
-    if len(data.Password) > 72 {
-        return fiber.NewError(fiber.StatusBadRequest, "Password too long")
+    return validatePassword(data.Password, data.PasswordConfirm, &models.User{
+        FirstName: data.FirstName,
+        LastName:  data.LastName,
+        Email:     data.Email,
+    })
+}
+
+// validatePassword applies the password policy shared by registration, update and reset
+func validatePassword(password, confirm string, user *models.User) error {
+    if err := passwordpolicy.Validate(password, passwordpolicy.UserInfo{
+        Email:     user.Email,
+        FirstName: user.FirstName,
+        LastName:  user.LastName,
+    }); err != nil {
+        return err
     }
 
     // Password match
-    if data.Password != data.PasswordConfirm {
+    if password != confirm {
         return fiber.NewError(fiber.StatusBadRequest, "Passwords do not match")
     }
 
     return nil
 }
 
+// passwordError responds 400 with policy suggestions when there are any
+func passwordError(c *fiber.Ctx, err error) error {
+    response := fiber.Map{
+        "error": err.Error(),
+    }
+
+    var policyErr *passwordpolicy.ValidationError
+    if errors.As(err, &policyErr) && len(policyErr.Suggestions) > 0 {
+        response["suggestions"] = policyErr.Suggestions
+    }
+
+    return c.Status(fiber.StatusBadRequest).JSON(response)
+}
+
 type LoginRequest struct {
     Email    string `json:"email" validate:"required,email"`
     Password string `json:"password" validate:"required"`
@@ -160,23 +227,90 @@ func Login(c *fiber.Ctx) error {
         })
     }
 
+    ctx := c.Context()
+
+    // Locked accounts are rejected before touching the password
+    if wait := ratelimit.AccountLockedFor(ctx, data.Email); wait > 0 {
+        return middlewares.TooManyRequests(c, wait)
+    }
+
 	// Find user by email
     var user models.User
     if err := database.DB.Where("email = ?", data.Email).First(&user).Error; err != nil {
-        // FIXED: Single check, no duplicate logic
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "error": "Invalid credentials",
-        })
+        // Unknown emails count too, so lockouts don't reveal which accounts exist
+        return loginFailed(c, data.Email)
     }
 
     // Check password (timing-safe)
     if err := utils.CheckPassword(user.Password, data.Password); err != nil {
         log.Printf("Failed login attempt for: %s", data.Email)
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "error": "Invalid credentials",
+        return loginFailed(c, data.Email)
+    }
+
+    if user.IsSuspended() {
+        return accountSuspended(c)
+    }
+
+    // Upgrade hashes made with an older algorithm or weaker parameters
+    if utils.NeedsRehash(user.Password) {
+        rehashPassword(&user, data.Password)
+    }
+
+    // Second factor: hand out a short-lived challenge instead of a session
+    // Failure history is kept until the code checks out too, so logging in
+    // again doesn't reset the count of wrong codes
+    if user.MFAEnabled() {
+        mfaToken, err := issueMFAChallenge(user.ID)
+        if err != nil {
+            log.Printf("MFA challenge generation failed: %v", err)
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "Failed to generate token",
+            })
+        }
+
+        return c.JSON(fiber.Map{
+            "message":      "MFA code required",
+            "mfa_required": true,
+            "mfa_token":    mfaToken,
+            "expires_in":   config.Get().MFAChallengeMinutes * 60,
         })
     }
 
+    ratelimit.RecordSuccess(ctx, data.Email)
+
+    return completeLogin(c, &user)
+}
+
+// rehashPassword stores a fresh hash with the current parameters.
+// Failures are logged only; the old hash keeps working.
+func rehashPassword(user *models.User, password string) {
+    hashedPassword, err := utils.HashPassword(password)
+    if err != nil {
+        log.Printf("Password rehash failed for user %d: %v", user.ID, err)
+        return
+    }
+
+    // Guard on the old hash so a concurrent password change isn't overwritten
+    result := database.DB.Model(&models.User{}).
+        Where("id = ? AND password = ?", user.ID, user.Password).
+        Update("password", hashedPassword)
+    if result.Error != nil {
+        log.Printf("Password rehash failed for user %d: %v", user.ID, result.Error)
+        return
+    }
+    if result.RowsAffected == 1 {
+        user.Password = hashedPassword
+        log.Printf("Password hash upgraded for user %d", user.ID)
+    }
+}
+
+// completeLogin issues the session cookie once every required factor has passed
+func completeLogin(c *fiber.Ctx, user *models.User) error {
+    // Covers the MFA path too
+    if user.IsSuspended() {
+        return accountSuspended(c)
+    }
+
     // FIXED: Use user.IsAmbassador for scope (not path)
     scope := "admin"
     if user.IsAmbassador {
@@ -198,12 +332,38 @@ func Login(c *fiber.Ctx) error {
         LastName:     user.LastName,
         Email:        user.Email,
         IsAmbassador: user.IsAmbassador,
+        EmailVerifiedAt: user.EmailVerifiedAt,
     }
 
-	 return c.JSON(fiber.Map{
+    result := fiber.Map{
         "message": "Login successful",
         "user":    response,
         // Don't return token if using cookie-based auth
+    }
+
+    // Admin sessions are restricted to MFA enrollment until TOTP is set up
+    if !user.IsAmbassador && !user.MFAEnabled() && config.Get().MFARequireAdmin {
+        result["mfa_enrollment_required"] = true
+    }
+
+	 return c.JSON(result)
+}
+
+// accountSuspended refuses to start a session for a suspended account
+func accountSuspended(c *fiber.Ctx) error {
+    return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+        "error": "account is suspended",
+    })
+}
+
+// loginFailed records a failed attempt and responds 401, or 429 once the account gets locked
+func loginFailed(c *fiber.Ctx, email string) error {
+    if wait := ratelimit.RecordFailure(c.Context(), email); wait > 0 {
+        return middlewares.TooManyRequests(c, wait)
+    }
+
+    return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+        "error": "Invalid credentials",
     })
 }
 
@@ -225,6 +385,7 @@ func User(c *fiber.Ctx) error {
         LastName:     user.LastName,
         Email:        user.Email,
         IsAmbassador: user.IsAmbassador,
+        EmailVerifiedAt: user.EmailVerifiedAt,
         Revenue:      &user.Revenue, 
     })
 }
@@ -287,6 +448,7 @@ func UpdateInfo(c *fiber.Ctx) error {
     if data.LastName != "" {
         user.LastName = data.LastName
     }
+    emailChanged := false
     if data.Email != "" {
         // Validate email format
         if !emailRegex.MatchString(data.Email) {
@@ -294,6 +456,11 @@ func UpdateInfo(c *fiber.Ctx) error {
                 "error": "invalid email format",
             })
         }
+        // A new address has to be verified again
+        if data.Email != user.Email {
+            emailChanged = true
+            user.EmailVerifiedAt = nil
+        }
         user.Email = data.Email
     }
 
@@ -311,12 +478,17 @@ func UpdateInfo(c *fiber.Ctx) error {
         })
     }
 
+    if emailChanged {
+        sendVerificationEmail(user)
+    }
+
     return c.JSON(UserResponse{
         ID:           user.ID,
         FirstName:    user.FirstName,
         LastName:     user.LastName,
         Email:        user.Email,
         IsAmbassador: user.IsAmbassador,
+        EmailVerifiedAt: user.EmailVerifiedAt,
     })
 }
 
@@ -343,25 +515,23 @@ func UpdatePassword(c *fiber.Ctx) error {
         })
     }
 
-    // Validate passwords match
-    if data.NewPassword != data.NewPasswordConfirm {
+    // Verify current password first: the policy, breach-list and reuse checks
+    // below shouldn't answer someone who doesn't know it (a stolen session
+    // could otherwise probe them, e.g. for the account's old passwords)
+    if err := utils.CheckPassword(user.Password, data.CurrentPassword); err != nil {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "new passwords do not match",
+            "error": "current password is incorrect",
         })
     }
 
-    // Validate new password length
-    if len(data.NewPassword) < 8 {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "new password must be at least 8 characters",
-        })
+    // Validate new password (policy + match)
+    if err := validatePassword(data.NewPassword, data.NewPasswordConfirm, user); err != nil {
+        return passwordError(c, err)
     }
 
-    // Verify current password
-    if err := utils.CheckPassword(user.Password, data.CurrentPassword); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "current password is incorrect",
-        })
+    // Recent passwords can't be reused
+    if err := passwordpolicy.CheckReuse(database.DB, user, data.NewPassword); err != nil {
+        return passwordError(c, err)
     }
 
      // Hash new password
@@ -373,9 +543,16 @@ func UpdatePassword(c *fiber.Ctx) error {
         })
     }
 
-    // Update password in database
+    // Update password in database (remembering the old hash)
+    oldHash := user.Password
     user.Password = []byte(newHashedPassword)
-    if err := database.DB.Save(&user).Error; err != nil {
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := passwordpolicy.Remember(tx, user.ID, oldHash); err != nil {
+            return err
+        }
+        return tx.Save(&user).Error
+    })
+    if err != nil {
         log.Printf("Password update failed: %v", err)
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "error": "failed to update password",
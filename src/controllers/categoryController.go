@@ -0,0 +1,493 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errCategoryCycle = errors.New("category cannot be its own ancestor")
+
+type CategoryRequest struct {
+    Name     string `json:"name" validate:"required,min=1,max=100"`
+    Slug     string `json:"slug" validate:"omitempty,max=120"` // derived from name when empty
+    ParentID *uint  `json:"parent_id"`
+}
+
+type TagRequest struct {
+    Name string `json:"name" validate:"required,min=1,max=100"`
+    Slug string `json:"slug" validate:"omitempty,max=120"`
+}
+
+type ProductTaxonomyRequest struct {
+    CategoryIDs []uint `json:"category_ids"`
+    TagIDs      []uint `json:"tag_ids"`
+}
+
+// Categories lists all categories, parents before children
+// GET /api/admin/categories, GET /api/ambassador/categories
+func Categories(c *fiber.Ctx) error {
+    var categories []models.Category
+    if err := database.DB.WithContext(c.Context()).
+        Order("parent_id IS NOT NULL, parent_id, name").
+        Find(&categories).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch categories",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "data": categories,
+    })
+}
+
+// CreateCategory adds a category, optionally under a parent
+// POST /api/admin/categories
+func CreateCategory(c *fiber.Ctx) error {
+    var data CategoryRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    category := models.Category{ParentID: data.ParentID}
+    if msg := applyTaxonomyName(&category.Name, &category.Slug, data.Name, data.Slug); msg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": msg,
+        })
+    }
+    if data.ParentID != nil && !categoryExists(*data.ParentID) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "parent category not found",
+        })
+    }
+    if slugTaken(&models.Category{}, category.Slug, 0) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "slug already in use",
+        })
+    }
+
+    if err := database.DB.Create(&category).Error; err != nil {
+        log.Printf("Failed to create category: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create category",
+        })
+    }
+
+    audit.Record(c, audit.Entry{
+        Action:     "category.create",
+        TargetType: "category",
+        TargetID:   category.ID,
+        After:      category,
+    })
+
+    return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+// UpdateCategory renames or moves a category; moving under its own subtree is rejected
+// PUT /api/admin/categories/:id
+func UpdateCategory(c *fiber.Ctx) error {
+    var category models.Category
+    if ferr := findTaxonomy(c, &category, "category"); ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var data CategoryRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    before := category
+    if msg := applyTaxonomyName(&category.Name, &category.Slug, data.Name, data.Slug); msg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": msg,
+        })
+    }
+    if slugTaken(&models.Category{}, category.Slug, category.ID) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "slug already in use",
+        })
+    }
+
+    if data.ParentID != nil {
+        if !categoryExists(*data.ParentID) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "parent category not found",
+            })
+        }
+        subtree, err := models.CategoryDescendantIDs(database.DB, category.ID)
+        if err != nil {
+            log.Printf("Failed to load category tree: %v", err)
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "failed to update category",
+            })
+        }
+        for _, id := range subtree {
+            if id == *data.ParentID {
+                return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                    "error": errCategoryCycle.Error(),
+                })
+            }
+        }
+    }
+    // parent_id: null moves the category to the top level
+    category.ParentID = data.ParentID
+
+    if err := database.DB.Model(&category).Select("name", "slug", "parent_id").Updates(&category).Error; err != nil {
+        log.Printf("Failed to update category %d: %v", category.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to update category",
+        })
+    }
+
+    // Moving a category changes which products its ancestors list
+    database.ClearProductCaches(c.Context())
+
+    audit.Record(c, audit.Entry{
+        Action:     "category.update",
+        TargetType: "category",
+        TargetID:   category.ID,
+        Before:     before,
+        After:      category,
+    })
+
+    return c.JSON(category)
+}
+
+// DeleteCategory removes a leaf category and its product assignments
+// DELETE /api/admin/categories/:id
+func DeleteCategory(c *fiber.Ctx) error {
+    var category models.Category
+    if ferr := findTaxonomy(c, &category, "category"); ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var children int64
+    database.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children)
+    if children > 0 {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "category has subcategories; move or delete them first",
+        })
+    }
+
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&category).Association("Products").Clear(); err != nil {
+            return err
+        }
+        return tx.Delete(&category).Error
+    })
+    if err != nil {
+        log.Printf("Failed to delete category %d: %v", category.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to delete category",
+        })
+    }
+
+    database.ClearProductCaches(c.Context())
+
+    audit.Record(c, audit.Entry{
+        Action:     "category.delete",
+        TargetType: "category",
+        TargetID:   category.ID,
+        Before:     category,
+    })
+
+    return c.JSON(fiber.Map{
+        "message":    "category deleted successfully",
+        "deleted_id": category.ID,
+    })
+}
+
+// Tags lists all tags by name
+// GET /api/admin/tags, GET /api/ambassador/tags
+func Tags(c *fiber.Ctx) error {
+    var tags []models.Tag
+    if err := database.DB.WithContext(c.Context()).Order("name").Find(&tags).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch tags",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "data": tags,
+    })
+}
+
+// CreateTag adds a tag
+// POST /api/admin/tags
+func CreateTag(c *fiber.Ctx) error {
+    var data TagRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    var tag models.Tag
+    if msg := applyTaxonomyName(&tag.Name, &tag.Slug, data.Name, data.Slug); msg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": msg,
+        })
+    }
+    if slugTaken(&models.Tag{}, tag.Slug, 0) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "slug already in use",
+        })
+    }
+
+    if err := database.DB.Create(&tag).Error; err != nil {
+        log.Printf("Failed to create tag: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create tag",
+        })
+    }
+
+    audit.Record(c, audit.Entry{
+        Action:     "tag.create",
+        TargetType: "tag",
+        TargetID:   tag.ID,
+        After:      tag,
+    })
+
+    return c.Status(fiber.StatusCreated).JSON(tag)
+}
+
+// UpdateTag renames a tag
+// PUT /api/admin/tags/:id
+func UpdateTag(c *fiber.Ctx) error {
+    var tag models.Tag
+    if ferr := findTaxonomy(c, &tag, "tag"); ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var data TagRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    before := tag
+    if msg := applyTaxonomyName(&tag.Name, &tag.Slug, data.Name, data.Slug); msg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": msg,
+        })
+    }
+    if slugTaken(&models.Tag{}, tag.Slug, tag.ID) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "slug already in use",
+        })
+    }
+
+    if err := database.DB.Model(&tag).Select("name", "slug").Updates(&tag).Error; err != nil {
+        log.Printf("Failed to update tag %d: %v", tag.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to update tag",
+        })
+    }
+
+    database.ClearProductCaches(c.Context())
+
+    audit.Record(c, audit.Entry{
+        Action:     "tag.update",
+        TargetType: "tag",
+        TargetID:   tag.ID,
+        Before:     before,
+        After:      tag,
+    })
+
+    return c.JSON(tag)
+}
+
+// DeleteTag removes a tag from all products and deletes it
+// DELETE /api/admin/tags/:id
+func DeleteTag(c *fiber.Ctx) error {
+    var tag models.Tag
+    if ferr := findTaxonomy(c, &tag, "tag"); ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&tag).Association("Products").Clear(); err != nil {
+            return err
+        }
+        return tx.Delete(&tag).Error
+    })
+    if err != nil {
+        log.Printf("Failed to delete tag %d: %v", tag.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to delete tag",
+        })
+    }
+
+    database.ClearProductCaches(c.Context())
+
+    audit.Record(c, audit.Entry{
+        Action:     "tag.delete",
+        TargetType: "tag",
+        TargetID:   tag.ID,
+        Before:     tag,
+    })
+
+    return c.JSON(fiber.Map{
+        "message":    "tag deleted successfully",
+        "deleted_id": tag.ID,
+    })
+}
+
+// SetProductTaxonomy replaces a product's categories and tags.
+// A field left out of the body keeps its current assignments.
+// PUT /api/admin/products/:id/taxonomy
+func SetProductTaxonomy(c *fiber.Ctx) error {
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var data ProductTaxonomyRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    var categories []models.Category
+    var tags []models.Tag
+    if data.CategoryIDs != nil {
+        if err := database.DB.Where("id IN ?", data.CategoryIDs).Find(&categories).Error; err != nil || len(categories) != len(uniqueIDs(data.CategoryIDs)) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "unknown category in category_ids",
+            })
+        }
+    }
+    if data.TagIDs != nil {
+        if err := database.DB.Where("id IN ?", data.TagIDs).Find(&tags).Error; err != nil || len(tags) != len(uniqueIDs(data.TagIDs)) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "unknown tag in tag_ids",
+            })
+        }
+    }
+
+    var before models.Product
+    database.DB.Preload("Categories").Preload("Tags").First(&before, product.ID)
+
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if data.CategoryIDs != nil {
+            if err := tx.Model(product).Association("Categories").Replace(categories); err != nil {
+                return err
+            }
+        }
+        if data.TagIDs != nil {
+            if err := tx.Model(product).Association("Tags").Replace(tags); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        log.Printf("Failed to update taxonomy of product %d: %v", product.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to update product taxonomy",
+        })
+    }
+
+    database.ClearProductCaches(c.Context(), product.ID)
+
+    var after models.Product
+    database.DB.Preload("Categories").Preload("Tags").First(&after, product.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.taxonomy",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     fiber.Map{"categories": before.Categories, "tags": before.Tags},
+        After:      fiber.Map{"categories": after.Categories, "tags": after.Tags},
+    })
+
+    return c.JSON(fiber.Map{
+        "message":       "product taxonomy updated successfully",
+        "categories":    after.Categories,
+        "tags":          after.Tags,
+        "cache_cleared": true,
+    })
+}
+
+// applyTaxonomyName validates and sets a name and slug, returning an error message
+func applyTaxonomyName(name, slug *string, newName, newSlug string) string {
+    newName = strings.TrimSpace(newName)
+    if newName == "" || len(newName) > 100 {
+        return "name must be between 1 and 100 characters"
+    }
+
+    if strings.TrimSpace(newSlug) == "" {
+        newSlug = newName
+    }
+    newSlug = models.Slugify(newSlug)
+    if newSlug == "" || len(newSlug) > 120 {
+        return "slug must contain letters or digits (max 120 characters)"
+    }
+
+    *name = newName
+    *slug = newSlug
+    return ""
+}
+
+func findTaxonomy(c *fiber.Ctx, dest interface{}, kind string) *fiber.Error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return fiber.NewError(fiber.StatusBadRequest, "invalid "+kind+" ID")
+    }
+
+    if err := database.DB.First(dest, id).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return fiber.NewError(fiber.StatusNotFound, kind+" not found")
+        }
+        log.Printf("Failed to fetch %s %d: %v", kind, id, err)
+        return fiber.NewError(fiber.StatusInternalServerError, "failed to fetch "+kind)
+    }
+    return nil
+}
+
+func categoryExists(id uint) bool {
+    var count int64
+    database.DB.Model(&models.Category{}).Where("id = ?", id).Count(&count)
+    return count > 0
+}
+
+func slugTaken(model interface{}, slug string, exceptID uint) bool {
+    var count int64
+    database.DB.Model(model).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count)
+    return count > 0
+}
+
+func uniqueIDs(ids []uint) []uint {
+    seen := make(map[uint]bool, len(ids))
+    unique := ids[:0:0]
+    for _, id := range ids {
+        if !seen[id] {
+            seen[id] = true
+            unique = append(unique, id)
+        }
+    }
+    return unique
+}
@@ -0,0 +1,457 @@
+package controllers
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"ambassador/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxCheckoutQuantity caps the units of one product (or variant) per order
+const maxCheckoutQuantity = 100
+
+type CheckoutLineRequest struct {
+    ProductID uint `json:"product_id"`
+    VariantID uint `json:"variant_id"` // required for products with variants, unless the link preselects one
+    Quantity  int  `json:"quantity"`
+}
+
+// checkoutLineKey identifies an order line; VariantID 0 = the product itself
+type checkoutLineKey struct {
+    ProductID uint
+    VariantID uint
+}
+
+type CheckoutRequest struct {
+    Code      string                `json:"code" validate:"required"`
+    FirstName string                `json:"first_name" validate:"required,min=2,max=50"`
+    LastName  string                `json:"last_name" validate:"required,min=2,max=50"`
+    Email     string                `json:"email" validate:"required,email"`
+    Address   string                `json:"address" validate:"required,min=5"`
+    City      string                `json:"city" validate:"required,min=2"`
+    Country   string                `json:"country" validate:"required,min=2"`
+    Zip       string                `json:"zip"`
+    Products  []CheckoutLineRequest `json:"products" validate:"required,min=1"`
+}
+
+type CheckoutTransactionRequest struct {
+    TransactionID string `json:"transaction_id" validate:"required"`
+}
+
+// CheckoutConfirmRequest is the payment provider's confirmation webhook body
+type CheckoutConfirmRequest struct {
+    TransactionID string   `json:"transaction_id" validate:"required"`
+    Amount        *float64 `json:"amount" validate:"required"` // amount paid; must match the order total
+}
+
+// CreateCheckoutOrder places a pending order through an ambassador link and
+// reserves its stock until the order is paid, cancelled or expires.
+// Product rows are locked while stock is checked, so concurrent checkouts
+// can't sell the same units twice. Only products on the link can be ordered,
+// and a buyer (email or IP) may hold a limited number of unpaid orders.
+// POST /api/checkout/orders
+func CreateCheckoutOrder(c *fiber.Ctx) error {
+    var data CheckoutRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Code = strings.TrimSpace(data.Code)
+    data.FirstName = strings.TrimSpace(data.FirstName)
+    data.LastName = strings.TrimSpace(data.LastName)
+    data.Email = strings.ToLower(strings.TrimSpace(data.Email))
+    if data.Code == "" || len(data.FirstName) < 2 || len(data.LastName) < 2 ||
+        !strings.Contains(data.Email, "@") || len(strings.TrimSpace(data.Address)) < 5 ||
+        len(strings.TrimSpace(data.City)) < 2 || len(strings.TrimSpace(data.Country)) < 2 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "code, first_name, last_name, email, address, city and country are required",
+        })
+    }
+    if len(data.Products) == 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "at least one product is required",
+        })
+    }
+
+    for _, line := range data.Products {
+        if line.ProductID == 0 || line.Quantity < 1 {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "each product needs a product_id and a quantity of at least 1",
+            })
+        }
+    }
+
+    var link models.Link
+    if err := database.DB.Preload("User").Preload("Products").Preload("Variants").Where("code = ?", data.Code).First(&link).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+                "error": "link not found",
+            })
+        }
+        log.Printf("Failed to fetch link %q: %v", data.Code, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create order",
+        })
+    }
+
+    onLink := make(map[uint]bool, len(link.Products))
+    for _, product := range link.Products {
+        onLink[product.ID] = true
+    }
+    for _, line := range data.Products {
+        if !onLink[line.ProductID] {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": fmt.Sprintf("product %d is not part of this link", line.ProductID),
+            })
+        }
+    }
+
+    // A product's only preselected variant stands in for a missing variant_id
+    preselected := map[uint]uint{}
+    for _, variant := range link.Variants {
+        if _, ok := preselected[variant.ProductID]; ok {
+            preselected[variant.ProductID] = 0
+            continue
+        }
+        preselected[variant.ProductID] = variant.ID
+    }
+
+    // Repeated lines of a product or variant are merged
+    quantities := map[checkoutLineKey]int{}
+    var keys []checkoutLineKey
+    var productIDs, variantIDs []uint
+    seenProducts := map[uint]bool{}
+    for _, line := range data.Products {
+        key := checkoutLineKey{ProductID: line.ProductID, VariantID: line.VariantID}
+        if key.VariantID == 0 {
+            key.VariantID = preselected[key.ProductID]
+        }
+        if _, seen := quantities[key]; !seen {
+            keys = append(keys, key)
+            if key.VariantID != 0 {
+                variantIDs = append(variantIDs, key.VariantID)
+            }
+        }
+        if !seenProducts[key.ProductID] {
+            seenProducts[key.ProductID] = true
+            productIDs = append(productIDs, key.ProductID)
+        }
+        quantities[key] += line.Quantity
+        if quantities[key] > maxCheckoutQuantity {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": fmt.Sprintf("at most %d units of a product per order", maxCheckoutQuantity),
+            })
+        }
+    }
+
+    transactionID, err := utils.GenerateToken(24)
+    if err != nil {
+        log.Printf("Failed to generate transaction ID: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create order",
+        })
+    }
+
+    now := time.Now()
+    expiresAt := now.Add(time.Duration(config.Get().CheckoutReservationMinutes) * time.Minute)
+    order := models.Order{
+        TransactionID:   "chk_" + transactionID,
+        Code:            link.Code,
+        AmbassadorEmail: link.User.Email,
+        FirstName:       data.FirstName,
+        LastName:        data.LastName,
+        Email:           data.Email,
+        Address:         strings.TrimSpace(data.Address),
+        City:            strings.TrimSpace(data.City),
+        Country:         strings.TrimSpace(data.Country),
+        Zip:             strings.TrimSpace(data.Zip),
+        Complete:        false,
+        ExpiresAt:       &expiresAt,
+        RequestIP:       c.IP(),
+    }
+    maxPending := int64(config.Get().CheckoutMaxPendingOrders)
+
+    var soldOut []uint
+    var shortages []models.StockShortage
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        pending, err := models.CountPendingCheckoutOrders(tx, order.Email, order.RequestIP, now)
+        if err != nil {
+            return err
+        }
+        if pending >= maxPending {
+            return fiber.NewError(fiber.StatusTooManyRequests, "too many unpaid orders; pay or cancel one first")
+        }
+
+        // Products first, then variants: the lock order every stock change uses
+        products, err := models.LockProducts(tx, productIDs)
+        if err != nil {
+            return err
+        }
+        if len(products) != len(productIDs) {
+            return fiber.NewError(fiber.StatusBadRequest, "one or more products do not exist")
+        }
+        variants, err := models.LockVariants(tx, variantIDs)
+        if err != nil {
+            return err
+        }
+        withVariants, err := models.ProductsWithVariants(tx, productIDs)
+        if err != nil {
+            return err
+        }
+        versions, err := models.LatestProductVersions(tx, productIDs)
+        if err != nil {
+            return err
+        }
+
+        productsByID := make(map[uint]*models.Product, len(products))
+        for i := range products {
+            productsByID[products[i].ID] = &products[i]
+        }
+        variantsByID := make(map[uint]*models.ProductVariant, len(variants))
+        for i := range variants {
+            variantsByID[variants[i].ID] = &variants[i]
+        }
+
+        // Charged at the effective price while the rows are locked
+        var lines []models.StockLine
+        for _, key := range keys {
+            product := productsByID[key.ProductID]
+            line := models.StockLine{Product: product, Quantity: quantities[key]}
+            price := product.EffectivePrice(now)
+            item := models.OrderItem{
+                ProductID:    product.ID,
+                ProductTitle: product.Title,
+                Quantity:     uint(quantities[key]),
+            }
+
+            switch {
+            case key.VariantID != 0:
+                variant, ok := variantsByID[key.VariantID]
+                if !ok || variant.ProductID != product.ID {
+                    return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("variant %d is not a variant of product %d", key.VariantID, product.ID))
+                }
+                line.Variant = variant
+                price = variant.PriceAt(product, now)
+                item.VariantID = &variant.ID
+                item.VariantSKU = variant.SKU
+                item.VariantAttributes = variant.Attributes
+            case withVariants[product.ID]:
+                return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("product %d requires a variant_id", product.ID))
+            }
+
+            total := price * float64(quantities[key])
+            item.Price = price
+            item.AdminRevenue = total * (1 - models.AmbassadorShare)
+            item.AmbassadorRevenue = total * models.AmbassadorShare
+            if version, ok := versions[product.ID]; ok {
+                item.ProductVersionID = &version.ID
+            }
+            order.OrderItems = append(order.OrderItems, item)
+            lines = append(lines, line)
+        }
+        if err := tx.Create(&order).Error; err != nil {
+            return err
+        }
+
+        soldOut, shortages, err = models.ReserveStock(tx, lines, order.ID, expiresAt)
+        return err
+    })
+    if err != nil {
+        var ferr *fiber.Error
+        switch {
+        case errors.Is(err, models.ErrInsufficientStock):
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+                "error": "insufficient stock",
+                "items": shortages,
+            })
+        case errors.As(err, &ferr):
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
+        log.Printf("Failed to create checkout order for link %s: %v", link.Code, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create order",
+        })
+    }
+
+    invalidateStockCaches(c.Context(), productIDs, soldOut)
+
+    order.Name = order.FullName()
+    order.Total = order.GetTotal()
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "order created; stock is reserved until expires_at",
+        "data":    order,
+    })
+}
+
+// ConfirmCheckoutOrder marks a pending order as paid: its reserved units
+// leave stock for good. Confirming a paid order again is a no-op.
+// Called by the payment provider; RequireCheckoutWebhook checks the signature.
+// POST /api/checkout/orders/confirm
+func ConfirmCheckoutOrder(c *fiber.Ctx) error {
+    var data CheckoutConfirmRequest
+    if err := c.BodyParser(&data); err != nil || strings.TrimSpace(data.TransactionID) == "" || data.Amount == nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "transaction_id and amount are required",
+        })
+    }
+
+    var order models.Order
+    var expired bool
+    var restocked []uint
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockCheckoutOrder(tx, data.TransactionID, &order); err != nil {
+            return err
+        }
+        if !order.IsPending() {
+            return nil
+        }
+
+        now := time.Now()
+        if order.ExpiresAt != nil && !now.Before(*order.ExpiresAt) {
+            // Too late: the stock goes back even if the expiry job hasn't run yet
+            expired = true
+            var err error
+            restocked, err = models.CancelCheckoutOrder(tx, &order, now)
+            return err
+        }
+
+        if math.Round(*data.Amount*100) != math.Round(order.GetTotal()*100) {
+            return fiber.NewError(fiber.StatusConflict, "amount does not match the order total")
+        }
+
+        if _, err := models.ConsumeOrderStock(tx, order.ID, now); err != nil {
+            return err
+        }
+        order.Complete = true
+        return tx.Model(&order).Update("complete", true).Error
+    })
+    if err != nil {
+        return checkoutOrderError(c, data.TransactionID, err)
+    }
+
+    if expired {
+        invalidateStockCaches(c.Context(), nil, restocked)
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "order expired; its stock was released",
+        })
+    }
+    if order.CancelledAt != nil {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "order was cancelled",
+        })
+    }
+
+    productIDs := orderProductIDs(&order)
+    if err := models.RefreshProductStats(database.DB, productIDs...); err != nil {
+        log.Printf("Failed to refresh product stats after order %d: %v", order.ID, err)
+    }
+    invalidateStockCaches(c.Context(), productIDs, nil)
+
+    return c.JSON(fiber.Map{
+        "message": "order confirmed",
+        "data":    order,
+    })
+}
+
+// CancelCheckoutOrder cancels a pending order and releases its stock.
+// Cancelling a cancelled order again is a no-op.
+// POST /api/checkout/orders/cancel
+func CancelCheckoutOrder(c *fiber.Ctx) error {
+    var data CheckoutTransactionRequest
+    if err := c.BodyParser(&data); err != nil || strings.TrimSpace(data.TransactionID) == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "transaction_id is required",
+        })
+    }
+
+    var order models.Order
+    var restocked []uint
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockCheckoutOrder(tx, data.TransactionID, &order); err != nil {
+            return err
+        }
+        if !order.IsPending() {
+            return nil
+        }
+        var err error
+        restocked, err = models.CancelCheckoutOrder(tx, &order, time.Now())
+        return err
+    })
+    if err != nil {
+        return checkoutOrderError(c, data.TransactionID, err)
+    }
+
+    if order.Complete {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "order is already paid",
+        })
+    }
+
+    invalidateStockCaches(c.Context(), orderProductIDs(&order), restocked)
+
+    return c.JSON(fiber.Map{
+        "message": "order cancelled",
+    })
+}
+
+// lockCheckoutOrder loads an order with its items, locking the order row
+func lockCheckoutOrder(tx *gorm.DB, transactionID string, order *models.Order) error {
+    return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Preload("OrderItems").
+        Where("transaction_id = ?", strings.TrimSpace(transactionID)).
+        First(order).Error
+}
+
+func checkoutOrderError(c *fiber.Ctx, transactionID string, err error) error {
+    var ferr *fiber.Error
+    switch {
+    case errors.Is(err, gorm.ErrRecordNotFound):
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+            "error": "order not found",
+        })
+    case errors.As(err, &ferr):
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+    log.Printf("Failed to update checkout order %s: %v", transactionID, err)
+    return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+        "error": "failed to update order",
+    })
+}
+
+func orderProductIDs(order *models.Order) []uint {
+    ids := make([]uint, 0, len(order.OrderItems))
+    for _, item := range order.OrderItems {
+        ids = append(ids, item.ProductID)
+    }
+    return ids
+}
+
+// invalidateStockCaches drops the cached detail of products whose stock
+// moved; listings are only cleared when a product's in_stock flag flipped
+func invalidateStockCaches(ctx context.Context, touched, flipped []uint) {
+    if len(flipped) > 0 {
+        database.ClearProductCaches(ctx, flipped...)
+    }
+    for _, id := range touched {
+        if err := database.CacheDelete(ctx, fmt.Sprintf("product:%d", id)); err != nil {
+            log.Printf("Failed to clear cache of product %d: %v", id, err)
+        }
+    }
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ImpersonateRequest struct {
+    Reason string `json:"reason" validate:"required,min=5,max=255"`
+}
+
+// Impersonate issues a short-lived bearer token for acting as an ambassador.
+// Every impersonation is recorded with the admin, reason and IP, and the token
+// stops working as soon as the record is ended or expires.
+// POST /api/admin/users/:id/impersonate
+func Impersonate(c *fiber.Ctx) error {
+    user, ferr := findManagedUser(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var data ImpersonateRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Reason = strings.TrimSpace(data.Reason)
+    if len(data.Reason) < 5 || len(data.Reason) > 255 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "reason must be between 5 and 255 characters",
+        })
+    }
+
+    // Only ambassadors: impersonating another admin would be privilege escalation
+    if !user.IsAmbassador {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "error": "only ambassadors can be impersonated",
+        })
+    }
+    if user.IsSuspended() {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "user is suspended",
+        })
+    }
+
+    adminID, err := middlewares.GetUserID(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    tokenID, err := utils.GenerateToken(16)
+    if err != nil {
+        log.Printf("Impersonation token ID generation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to start impersonation",
+        })
+    }
+
+    impersonation := models.Impersonation{
+        AdminID:   adminID,
+        UserID:    user.ID,
+        TokenID:   tokenID,
+        Reason:    data.Reason,
+        IP:        c.IP(),
+        ExpiresAt: time.Now().Add(time.Duration(config.Get().ImpersonationMinutes) * time.Minute),
+    }
+    if err := database.DB.Create(&impersonation).Error; err != nil {
+        log.Printf("Impersonation record failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to start impersonation",
+        })
+    }
+
+    token, err := middlewares.GenerateImpersonationToken(user.ID, adminID, tokenID, impersonation.ExpiresAt)
+    if err != nil {
+        log.Printf("Impersonation token signing failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to start impersonation",
+        })
+    }
+
+    audit.Record(c, audit.Entry{
+        Action:     "impersonation.start",
+        TargetType: "user",
+        TargetID:   user.ID,
+        After:      impersonation,
+    })
+
+    log.Printf("Admin %d started impersonating user %d (impersonation %d): %s", adminID, user.ID, impersonation.ID, data.Reason)
+
+    // Returned as a bearer token so the admin's own session cookie is untouched
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "impersonation": impersonation,
+        "token":         token,
+        "expires_at":    impersonation.ExpiresAt,
+    })
+}
+
+// Impersonations lists impersonation records, newest first (?active=true for running ones)
+// GET /api/admin/impersonations
+func Impersonations(c *fiber.Ctx) error {
+    page := max(1, atoi(c.Query("page", "1")))
+    perPage := clamp(atoi(c.Query("per_page", "20")), 1, 100)
+
+    db := database.DB.WithContext(c.Context()).Model(&models.Impersonation{})
+    if c.Query("active") == "true" {
+        db = db.Where("ended_at IS NULL AND expires_at > ?", time.Now())
+    }
+    if userID := atoi(c.Query("user_id")); userID > 0 {
+        db = db.Where("user_id = ?", userID)
+    }
+    if adminID := atoi(c.Query("admin_id")); adminID > 0 {
+        db = db.Where("admin_id = ?", adminID)
+    }
+
+    var total int64
+    if err := db.Count(&total).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to count impersonations",
+        })
+    }
+
+    var impersonations []models.Impersonation
+    if err := db.
+        Order("id DESC").
+        Offset((page - 1) * perPage).
+        Limit(perPage).
+        Find(&impersonations).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch impersonations",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "data":        impersonations,
+        "total_count": total,
+        "page":        page,
+        "per_page":    perPage,
+    })
+}
+
+// EndImpersonation invalidates an impersonation token before it expires
+// POST /api/admin/impersonations/:id/end
+func EndImpersonation(c *fiber.Ctx) error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid impersonation ID",
+        })
+    }
+
+    var impersonation models.Impersonation
+    if err := database.DB.First(&impersonation, id).Error; err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+            "error": "impersonation not found",
+        })
+    }
+
+    if impersonation.EndedAt == nil {
+        before := impersonation
+        now := time.Now()
+        if err := database.DB.Model(&impersonation).Update("ended_at", now).Error; err != nil {
+            log.Printf("Failed to end impersonation %d: %v", impersonation.ID, err)
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "failed to end impersonation",
+            })
+        }
+        impersonation.EndedAt = &now
+
+        audit.Record(c, audit.Entry{
+            Action:     "impersonation.end",
+            TargetType: "impersonation",
+            TargetID:   impersonation.ID,
+            Before:     before,
+            After:      impersonation,
+        })
+
+        adminID, _ := middlewares.GetUserID(c)
+        log.Printf("Admin %d ended impersonation %d", adminID, impersonation.ID)
+    }
+
+    return c.JSON(impersonation)
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errInviteUsed = errors.New("invite already used")
+
+type CreateInviteRequest struct {
+    Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type InviteResponse struct {
+    ID          uint       `json:"id"`
+    Email       string     `json:"email"`
+    InvitedByID *uint      `json:"invited_by_id"`
+    UsedByID    *uint      `json:"used_by_id"`
+    ExpiresAt   time.Time  `json:"expires_at"`
+    UsedAt      *time.Time `json:"used_at"`
+    CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateInvite issues a single-use admin invite bound to an email
+// POST /api/admin/invites
+func CreateInvite(c *fiber.Ctx) error {
+    adminID, err := middlewares.GetUserID(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var data CreateInviteRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Email = strings.ToLower(strings.TrimSpace(data.Email))
+    if !emailRegex.MatchString(data.Email) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid email format",
+        })
+    }
+
+    cfg := config.Get()
+    invite, token, err := models.NewAdminInvite(data.Email, &adminID, time.Duration(cfg.InviteExpireHours)*time.Hour)
+    if err != nil {
+        log.Printf("Invite token generation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create invite",
+        })
+    }
+
+    if err := database.DB.Create(invite).Error; err != nil {
+        log.Printf("Invite creation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create invite",
+        })
+    }
+
+    log.Printf("Admin %d invited %s (invite %d)", adminID, invite.Email, invite.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "invite.create",
+        TargetType: "invite",
+        TargetID:   invite.ID,
+        After:      toInviteResponse(invite),
+    })
+
+    // The plain token is returned once and never stored
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "invite created successfully",
+        "token":   token,
+        "data":    toInviteResponse(invite),
+    })
+}
+
+// Invites lists all invites with who invited whom
+// GET /api/admin/invites
+func Invites(c *fiber.Ctx) error {
+    var invites []models.AdminInvite
+    if err := database.DB.
+        Order("id DESC").
+        Find(&invites).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch invites",
+        })
+    }
+
+    data := make([]InviteResponse, len(invites))
+    for i := range invites {
+        data[i] = toInviteResponse(&invites[i])
+    }
+
+    return c.JSON(fiber.Map{
+        "data":       data,
+        "count":      len(data),
+        "is_success": true,
+    })
+}
+
+func toInviteResponse(invite *models.AdminInvite) InviteResponse {
+    return InviteResponse{
+        ID:          invite.ID,
+        Email:       invite.Email,
+        InvitedByID: invite.InvitedByID,
+        UsedByID:    invite.UsedByID,
+        ExpiresAt:   invite.ExpiresAt,
+        UsedAt:      invite.UsedAt,
+        CreatedAt:   invite.CreatedAt,
+    }
+}
+
+// findUsableInvite looks up an unused, unexpired invite for token bound to email
+func findUsableInvite(token, email string) (*models.AdminInvite, error) {
+    if token == "" {
+        return nil, errors.New("invite token required")
+    }
+
+    var invite models.AdminInvite
+    if err := database.DB.
+        Where("token_hash = ?", utils.HashToken(token)).
+        First(&invite).Error; err != nil {
+        return nil, err
+    }
+
+    if !invite.IsUsable(time.Now()) || invite.Email != email {
+        return nil, errors.New("invite not usable")
+    }
+
+    return &invite, nil
+}
+
+// redeemInvite marks the invite as used; the guarded UPDATE makes it single-use under concurrency
+func redeemInvite(tx *gorm.DB, invite *models.AdminInvite, userID uint) error {
+    now := time.Now()
+    result := tx.Model(&models.AdminInvite{}).
+        Where("id = ? AND used_at IS NULL", invite.ID).
+        Updates(map[string]interface{}{
+            "used_at":    now,
+            "used_by_id": userID,
+        })
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return errInviteUsed
+    }
+
+    invite.UsedAt = &now
+    invite.UsedByID = &userID
+    return nil
+}
@@ -4,6 +4,8 @@ import (
 	"ambassador/src/database"
 	"ambassador/src/middlewares"
 	"ambassador/src/models"
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -44,6 +46,7 @@ func Link(c *fiber.Ctx) error {
         WithContext(c.Context()).
         Preload("User").
         Preload("Products").
+        Preload("Variants").
         Where("user_id = ?", userID).
         Find(&links).Error; err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -65,7 +68,8 @@ func Link(c *fiber.Ctx) error {
 }	
 
 type CreateLinkRequest struct {
-    Products []int `json:"products"`
+    Products []int  `json:"products"`
+    Variants []uint `json:"variants"` // optional; each must belong to one of products
 }
 
 func CreateLink(c *fiber.Ctx) error {
@@ -83,37 +87,59 @@ func CreateLink(c *fiber.Ctx) error {
         })
     }
 
-    userID, err := middlewares.GetUserID(c)
+    user, err := middlewares.GetUser(c)
     if err != nil {
         return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
             "error": "Unauthorized",
         })
     }
 
+    // Links are only for ambassadors with a confirmed email
+    if !user.IsEmailVerified() {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "error": "Email verification required",
+        })
+    }
+
     // Create link
     link := models.Link{
-        UserID: userID,
+        UserID: user.ID,
         Code:   generateLinkCode(),
     }
 
-    if err := database.DB.Create(&link).Error; err != nil {
+    // The link and its products and variants are saved together or not at all
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&link).Error; err != nil {
+            return err
+        }
+
+        // Associate products
+        if err := associateProducts(tx, &link, request.Products); err != nil {
+            return fiber.NewError(fiber.StatusBadRequest, err.Error())
+        }
+        if err := associateVariants(tx, &link, request.Products, request.Variants); err != nil {
+            return fiber.NewError(fiber.StatusBadRequest, err.Error())
+        }
+        return nil
+    })
+    if err != nil {
+        var ferr *fiber.Error
+        if errors.As(err, &ferr) {
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "error": "Failed to create link",
         })
     }
 
-    // Associate products
-    if err := associateProducts(database.DB, &link, request.Products); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": err.Error(),
-        })
-    }
-
     // Return preloaded result
     var result models.Link
     if err := database.DB.
         Preload("User").
         Preload("Products").
+        Preload("Variants").
         First(&result, link.ID).Error; err != nil {
         
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -141,16 +167,25 @@ func Stats(c *fiber.Ctx) error {
         })
     }
 
+    result, err := linkStats(c.Context(), userID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "Failed to fetch links",
+        })
+    }
+
+    return c.JSON(result)
+}
+
+// linkStats returns completed order count and revenue for each of the user's links
+func linkStats(ctx context.Context, userID uint) ([]LinkStat, error) {
     // Get user's links
     var links []models.Link
     if err := database.DB.
-        WithContext(c.Context()).
+        WithContext(ctx).
         Where("user_id = ?", userID).
         Find(&links).Error; err != nil {
-        
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to fetch links",
-        })
+        return nil, err
     }
 
     var result []LinkStat
@@ -160,7 +195,7 @@ func Stats(c *fiber.Ctx) error {
         // Get completed orders for this link
         var orders []models.Order
         if err := database.DB.
-            WithContext(c.Context()).
+            WithContext(ctx).
             Preload("OrderItems").
             Where("code = ? AND complete = ?", link.Code, true).
             Find(&orders).Error; err != nil {
@@ -181,10 +216,9 @@ func Stats(c *fiber.Ctx) error {
         })
     }
 
-    return c.JSON(result)
+    return result, nil
 }
 
-
 func generateLinkCode() string {
     b := make([]byte, 7)
     charset := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -212,4 +246,24 @@ func associateProducts(db *gorm.DB, link *models.Link, productIDs []int) error {
 
     // Save associations
     return db.Model(link).Association("Products").Append(products)
-}
\ No newline at end of file
+}
+
+// associateVariants preselects variants of the link's products; checkout
+// uses a product's only preselected variant when the buyer picks none
+func associateVariants(db *gorm.DB, link *models.Link, productIDs []int, variantIDs []uint) error {
+    if len(variantIDs) == 0 {
+        return nil
+    }
+
+    var variants []models.ProductVariant
+    if err := db.Where("id IN ? AND product_id IN ?", variantIDs, productIDs).Find(&variants).Error; err != nil {
+        return fmt.Errorf("database error: %w", err)
+    }
+
+    if len(variants) != len(variantIDs) {
+        return fmt.Errorf("variants not found among the link's products: expected %d, got %d",
+            len(variantIDs), len(variants))
+    }
+
+    return db.Model(link).Association("Variants").Append(variants)
+}
@@ -0,0 +1,441 @@
+package controllers
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/ratelimit"
+	"ambassador/src/utils"
+	"crypto/rand"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// Allow one 30s step of clock drift either way
+const totpSkew = 1
+
+type MFACodeRequest struct {
+    Code string `json:"code" validate:"required,len=6"`
+}
+
+type MFALoginRequest struct {
+    MFAToken     string `json:"mfa_token" validate:"required"`
+    Code         string `json:"code"`
+    RecoveryCode string `json:"recovery_code"`
+}
+
+type MFADisableRequest struct {
+    Password string `json:"password" validate:"required"`
+    Code     string `json:"code" validate:"required,len=6"`
+}
+
+// MFAStatus reports whether TOTP is enabled for the authenticated user
+// GET /api/admin/mfa
+func MFAStatus(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var remaining int64
+    database.DB.Model(&models.RecoveryCode{}).
+        Where("user_id = ? AND used_at IS NULL", user.ID).
+        Count(&remaining)
+
+    return c.JSON(fiber.Map{
+        "enabled":                  user.MFAEnabled(),
+        "enabled_at":               user.TOTPEnabledAt,
+        "required":                 config.Get().MFARequireAdmin,
+        "recovery_codes_remaining": remaining,
+    })
+}
+
+// EnrollMFA creates a pending TOTP secret; it becomes active after VerifyMFA
+// POST /api/admin/mfa/enroll
+func EnrollMFA(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    if user.MFAEnabled() {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "MFA is already enabled",
+        })
+    }
+
+    secret, err := utils.GenerateTOTPSecret()
+    if err != nil {
+        log.Printf("TOTP secret generation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to start MFA enrollment",
+        })
+    }
+
+    if err := database.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+        log.Printf("Failed to store TOTP secret for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to start MFA enrollment",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "message":     "scan the QR code and confirm with a code",
+        "secret":      secret,
+        "otpauth_uri": utils.TOTPURI(config.Get().MFAIssuer, user.Email, secret),
+    })
+}
+
+// VerifyMFA confirms the pending secret and returns one-time recovery codes
+// POST /api/admin/mfa/verify
+func VerifyMFA(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var data MFACodeRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    if user.MFAEnabled() {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "MFA is already enabled",
+        })
+    }
+
+    if user.TOTPSecret == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "start enrollment first",
+        })
+    }
+
+    step, ok := utils.ValidateTOTP(user.TOTPSecret, data.Code, time.Now(), totpSkew)
+    if !ok {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid code",
+        })
+    }
+
+    codes, err := generateRecoveryCodes()
+    if err != nil {
+        log.Printf("Recovery code generation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to enable MFA",
+        })
+    }
+
+    now := time.Now()
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(user).Updates(map[string]interface{}{
+            "totp_enabled_at": now,
+            "totp_last_step":  step,
+        }).Error; err != nil {
+            return err
+        }
+        return replaceRecoveryCodes(tx, user.ID, codes)
+    })
+    if err != nil {
+        log.Printf("Failed to enable MFA for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to enable MFA",
+        })
+    }
+
+    log.Printf("MFA enabled for user %d", user.ID)
+
+    // Recovery codes are shown once and only stored hashed
+    return c.JSON(fiber.Map{
+        "message":        "MFA enabled successfully",
+        "recovery_codes": codes,
+    })
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes (requires a current TOTP code)
+// POST /api/admin/mfa/recovery-codes
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var data MFACodeRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    if !user.MFAEnabled() {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "MFA is not enabled",
+        })
+    }
+
+    if !consumeTOTP(user, data.Code) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid code",
+        })
+    }
+
+    codes, err := generateRecoveryCodes()
+    if err != nil {
+        log.Printf("Recovery code generation failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to regenerate recovery codes",
+        })
+    }
+
+    if err := replaceRecoveryCodes(database.DB, user.ID, codes); err != nil {
+        log.Printf("Failed to store recovery codes for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to regenerate recovery codes",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "message":        "recovery codes regenerated",
+        "recovery_codes": codes,
+    })
+}
+
+// DisableMFA turns TOTP off (requires password and a current code)
+// DELETE /api/admin/mfa
+func DisableMFA(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var data MFADisableRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    if !user.MFAEnabled() {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "MFA is not enabled",
+        })
+    }
+
+    if err := utils.CheckPassword(user.Password, data.Password); err != nil || !consumeTOTP(user, data.Code) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid password or code",
+        })
+    }
+
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(user).Updates(map[string]interface{}{
+            "totp_secret":     "",
+            "totp_enabled_at": nil,
+            "totp_last_step":  0,
+        }).Error; err != nil {
+            return err
+        }
+        return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+    })
+    if err != nil {
+        log.Printf("Failed to disable MFA for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to disable MFA",
+        })
+    }
+
+    log.Printf("MFA disabled for user %d", user.ID)
+
+    return c.JSON(fiber.Map{
+        "message": "MFA disabled successfully",
+    })
+}
+
+// LoginMFA completes a two-step login with a TOTP or recovery code
+// POST /api/admin/login/mfa
+func LoginMFA(c *fiber.Ctx) error {
+    var data MFALoginRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    if data.Code == "" && data.RecoveryCode == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "code or recovery_code is required",
+        })
+    }
+
+    userID, challengeID, err := middlewares.ParseMFAChallengeToken(data.MFAToken)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "invalid or expired MFA token",
+        })
+    }
+
+    var user models.User
+    if err := database.DB.First(&user, userID).Error; err != nil || !user.MFAEnabled() {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "invalid or expired MFA token",
+        })
+    }
+
+    ctx := c.Context()
+    if wait := ratelimit.AccountLockedFor(ctx, user.Email); wait > 0 {
+        return middlewares.TooManyRequests(c, wait)
+    }
+
+    // One attempt per challenge: a wrong code means logging in again
+    if !consumeMFAChallenge(user.ID, challengeID) {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "invalid or expired MFA token",
+        })
+    }
+
+    ok := false
+    if data.Code != "" {
+        ok = consumeTOTP(&user, data.Code)
+    } else {
+        ok = consumeRecoveryCode(user.ID, data.RecoveryCode)
+        if ok {
+            log.Printf("User %d logged in with a recovery code", user.ID)
+        }
+    }
+
+    // Wrong codes count towards the same lockout as wrong passwords
+    if !ok {
+        if wait := ratelimit.RecordFailure(ctx, user.Email); wait > 0 {
+            return middlewares.TooManyRequests(c, wait)
+        }
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "invalid code",
+        })
+    }
+
+    ratelimit.RecordSuccess(ctx, user.Email)
+
+    return completeLogin(c, &user)
+}
+
+// issueMFAChallenge records a pending second-factor step and signs its token
+func issueMFAChallenge(userID uint) (string, error) {
+    challengeID, err := utils.GenerateToken(16)
+    if err != nil {
+        return "", err
+    }
+
+    expiresAt := time.Now().Add(time.Duration(config.Get().MFAChallengeMinutes) * time.Minute)
+    challenge := models.MFAChallenge{
+        UserID:    userID,
+        TokenID:   challengeID,
+        ExpiresAt: expiresAt,
+    }
+    if err := database.DB.Create(&challenge).Error; err != nil {
+        return "", err
+    }
+
+    return middlewares.GenerateMFAChallengeToken(userID, challengeID, expiresAt)
+}
+
+// consumeMFAChallenge marks an open challenge as used; false when it was
+// already used, has expired or belongs to someone else
+func consumeMFAChallenge(userID uint, challengeID string) bool {
+    now := time.Now()
+    result := database.DB.Model(&models.MFAChallenge{}).
+        Where("token_id = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", challengeID, userID, now).
+        Update("used_at", now)
+    if result.Error != nil {
+        log.Printf("MFA challenge check failed for user %d: %v", userID, result.Error)
+        return false
+    }
+    return result.RowsAffected == 1
+}
+
+// consumeTOTP validates a code and records its time step so it can't be replayed
+func consumeTOTP(user *models.User, code string) bool {
+    step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), totpSkew)
+    if !ok || step <= user.TOTPLastStep {
+        return false
+    }
+
+    // Guarded update: a concurrent request with the same code loses
+    result := database.DB.Model(&models.User{}).
+        Where("id = ? AND totp_last_step < ?", user.ID, step).
+        Update("totp_last_step", step)
+    if result.Error != nil || result.RowsAffected == 0 {
+        return false
+    }
+
+    user.TOTPLastStep = step
+    return true
+}
+
+// consumeRecoveryCode marks a matching unused recovery code as used
+func consumeRecoveryCode(userID uint, code string) bool {
+    hash := utils.HashToken(normalizeRecoveryCode(code))
+
+    result := database.DB.Model(&models.RecoveryCode{}).
+        Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+        Limit(1).
+        Update("used_at", time.Now())
+
+    return result.Error == nil && result.RowsAffected > 0
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string) error {
+    if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+        return err
+    }
+
+    rows := make([]models.RecoveryCode, len(codes))
+    for i, code := range codes {
+        rows[i] = models.RecoveryCode{
+            UserID:   userID,
+            CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+        }
+    }
+    return tx.Create(&rows).Error
+}
+
+// generateRecoveryCodes returns codes like "k3m9x-2qp7d" (50 bits each)
+func generateRecoveryCodes() ([]string, error) {
+    const alphabet = "abcdefghjkmnpqrstuvwxyz23456789" // no look-alikes
+    codes := make([]string, recoveryCodeCount)
+
+    for i := range codes {
+        b := make([]byte, 10)
+        if _, err := rand.Read(b); err != nil {
+            return nil, err
+        }
+        for j := range b {
+            b[j] = alphabet[int(b[j])%len(alphabet)]
+        }
+        codes[i] = string(b[:5]) + "-" + string(b[5:])
+    }
+
+    return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+    return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
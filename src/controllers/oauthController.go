@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/oidc"
+	"ambassador/src/utils"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+    oidcFlowCookie = "oidc_flow"
+    oidcFlowTTL    = 10 * time.Minute
+)
+
+var (
+    errOIDCEmailUnverified = errors.New("the identity provider did not verify this email")
+    errOIDCCannotLink      = errors.New("this account cannot be linked to an external login")
+    errOIDCLocalUnverified = errors.New("an account with this email exists but its email is not verified; verify it first")
+)
+
+// OAuthLogin starts an authorization-code + PKCE login with the configured provider
+// GET /api/ambassador/oauth/login
+func OAuthLogin(c *fiber.Ctx) error {
+    provider, err := oidc.Default(c.Context())
+    if err != nil {
+        return oidcUnavailable(c, err)
+    }
+
+    state, err := utils.GenerateToken(24)
+    if err != nil {
+        return oidcUnavailable(c, err)
+    }
+    nonce, err := utils.GenerateToken(24)
+    if err != nil {
+        return oidcUnavailable(c, err)
+    }
+    verifier, err := oidc.NewCodeVerifier()
+    if err != nil {
+        return oidcUnavailable(c, err)
+    }
+
+    flowToken, err := middlewares.GenerateOIDCFlowToken(state, nonce, verifier, oidcFlowTTL)
+    if err != nil {
+        return oidcUnavailable(c, err)
+    }
+
+    // Lax so the cookie comes back on the provider's top-level redirect
+    c.Cookie(&fiber.Cookie{
+        Name:     oidcFlowCookie,
+        Value:    flowToken,
+        Expires:  time.Now().Add(oidcFlowTTL),
+        HTTPOnly: true,
+        Secure:   config.Get().IsProduction(),
+        SameSite: "Lax",
+        Path:     "/api/ambassador/oauth",
+    })
+
+    return c.Redirect(provider.AuthCodeURL(state, nonce, oidc.CodeChallengeS256(verifier)), fiber.StatusFound)
+}
+
+// OAuthCallback finishes the login: verifies the ID token, then finds, links or creates the ambassador
+// GET /api/ambassador/oauth/callback
+func OAuthCallback(c *fiber.Ctx) error {
+    provider, err := oidc.Default(c.Context())
+    if err != nil {
+        return oidcUnavailable(c, err)
+    }
+
+    // The flow cookie is single-use
+    flowCookie := c.Cookies(oidcFlowCookie)
+    c.Cookie(&fiber.Cookie{
+        Name:     oidcFlowCookie,
+        Value:    "",
+        Expires:  time.Now().Add(-time.Hour),
+        HTTPOnly: true,
+        Path:     "/api/ambassador/oauth",
+    })
+
+    if providerErr := c.Query("error"); providerErr != "" {
+        log.Printf("OIDC provider returned error: %s (%s)", providerErr, c.Query("error_description"))
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "login was cancelled or denied",
+        })
+    }
+
+    flow, err := middlewares.ParseOIDCFlowToken(flowCookie)
+    if err != nil || subtle.ConstantTimeCompare([]byte(flow.State), []byte(c.Query("state"))) != 1 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid or expired login state, please try again",
+        })
+    }
+
+    code := c.Query("code")
+    if code == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "authorization code is required",
+        })
+    }
+
+    token, err := provider.Exchange(c.Context(), code, flow.CodeVerifier)
+    if err != nil {
+        log.Printf("OIDC code exchange failed: %v", err)
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "login failed",
+        })
+    }
+
+    claims, err := provider.VerifyIDToken(c.Context(), token.IDToken, flow.Nonce)
+    if err != nil {
+        log.Printf("OIDC ID token rejected: %v", err)
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "login failed",
+        })
+    }
+
+    user, err := resolveOIDCUser(provider.Issuer(), claims)
+    if err != nil {
+        if errors.Is(err, errOIDCEmailUnverified) || errors.Is(err, errOIDCCannotLink) || errors.Is(err, errOIDCLocalUnverified) {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+                "error": err.Error(),
+            })
+        }
+        log.Printf("OIDC user resolution failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "login failed",
+        })
+    }
+
+    if user.IsSuspended() {
+        return accountSuspended(c)
+    }
+
+    // Browser flows land back on the web app with the session cookie set
+    if redirect := config.Get().OIDCSuccessRedirect; redirect != "" {
+        if err := middlewares.GenerateJWT(c, user.ID, ScopeAmbassador); err != nil {
+            log.Printf("JWT generation failed: %v", err)
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "Failed to generate token",
+            })
+        }
+        return c.Redirect(redirect, fiber.StatusFound)
+    }
+
+    return completeLogin(c, user)
+}
+
+// resolveOIDCUser returns the ambassador for an external identity:
+// an existing link, an existing ambassador whose email is verified both here and
+// by the provider, or a new ambassador.
+func resolveOIDCUser(issuer string, claims *oidc.IDTokenClaims) (*models.User, error) {
+    var identity models.UserIdentity
+    err := database.DB.
+        Preload("User").
+        Where("issuer = ? AND subject = ?", issuer, claims.Subject).
+        First(&identity).Error
+    if err == nil && identity.User.ID != 0 {
+        if !identity.User.IsAmbassador {
+            return nil, errOIDCCannotLink
+        }
+        return &identity.User, nil
+    }
+    if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, err
+    }
+
+    // Identity left behind by a deleted user: drop it so it can be re-linked
+    if err == nil {
+        if err := database.DB.Unscoped().Delete(&identity).Error; err != nil {
+            return nil, err
+        }
+    }
+
+    // Linking and sign-up both rely on the provider having verified the email
+    email := strings.ToLower(strings.TrimSpace(claims.Email))
+    if email == "" || !claims.EmailVerified || !emailRegex.MatchString(email) {
+        return nil, errOIDCEmailUnverified
+    }
+
+    var user models.User
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        err := tx.Where("email = ?", email).First(&user).Error
+        switch {
+        case err == nil:
+            // Admin accounts never sign in through an external provider
+            if !user.IsAmbassador {
+                return errOIDCCannotLink
+            }
+            // Whoever registered an unverified email may not own it; linking
+            // would hand them the provider account's sessions
+            if !user.IsEmailVerified() {
+                return errOIDCLocalUnverified
+            }
+            log.Printf("Linking OIDC identity %s to existing user %d", claims.Subject, user.ID)
+
+        case errors.Is(err, gorm.ErrRecordNotFound):
+            user, err = newOIDCUser(email, claims)
+            if err != nil {
+                return err
+            }
+            if err := tx.Create(&user).Error; err != nil {
+                return err
+            }
+            log.Printf("Created user %d from OIDC identity %s", user.ID, claims.Subject)
+
+        default:
+            return err
+        }
+
+        return tx.Create(&models.UserIdentity{
+            UserID:  user.ID,
+            Issuer:  issuer,
+            Subject: claims.Subject,
+            Email:   email,
+        }).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return &user, nil
+}
+
+// newOIDCUser builds an ambassador with an unusable random password
+// (they can set one later through the forgot-password flow)
+func newOIDCUser(email string, claims *oidc.IDTokenClaims) (models.User, error) {
+    randomPassword, err := utils.GenerateToken(32)
+    if err != nil {
+        return models.User{}, err
+    }
+    hashedPassword, err := utils.HashPassword(randomPassword)
+    if err != nil {
+        return models.User{}, err
+    }
+
+    firstName, lastName := claims.GivenName, claims.FamilyName
+    if firstName == "" && lastName == "" && claims.Name != "" {
+        parts := strings.SplitN(claims.Name, " ", 2)
+        firstName = parts[0]
+        if len(parts) > 1 {
+            lastName = parts[1]
+        }
+    }
+
+    now := time.Now()
+    return models.User{
+        FirstName:       firstName,
+        LastName:        lastName,
+        Email:           email,
+        Password:        hashedPassword,
+        IsAmbassador:    true,
+        EmailVerifiedAt: &now,
+    }, nil
+}
+
+func oidcUnavailable(c *fiber.Ctx, err error) error {
+    if errors.Is(err, oidc.ErrNotConfigured) {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+            "error": "external login is not enabled",
+        })
+    }
+
+    log.Printf("OIDC login unavailable: %v", err)
+    return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+        "error": "external login is temporarily unavailable",
+    })
+}
@@ -4,8 +4,11 @@ import (
 	"ambassador/src/database"
 	"ambassador/src/middlewares"
 	"ambassador/src/models"
+	"errors"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 const (
@@ -50,3 +53,52 @@ func Orders(c *fiber.Ctx) error {
     return c.JSON(orders)
 }
 
+
+// GetOrder returns one order with the product version each item was bought at
+// GET /api/admin/orders/:id
+func GetOrder(c *fiber.Ctx) error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid order ID",
+        })
+    }
+
+    var order models.Order
+    if err := database.DB.
+        WithContext(c.Context()).
+        Preload("OrderItems.ProductVersion").
+        First(&order, id).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+                "error": "order not found",
+            })
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch order",
+        })
+    }
+
+    // Items from before versioning: use the version in effect when the order was placed
+    for i := range order.OrderItems {
+        item := &order.OrderItems[i]
+        if item.ProductVersion != nil {
+            continue
+        }
+
+        var version models.ProductVersion
+        err := database.DB.
+            Where("product_id = ? AND created_at <= ?", item.ProductID, order.CreatedAt).
+            Order("version DESC").
+            First(&version).Error
+        if err == nil {
+            item.ProductVersion = &version
+            item.VersionInferred = true
+        }
+    }
+
+    order.Name = order.FullName()
+    order.Total = order.GetTotal()
+
+    return c.JSON(order)
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/mailer"
+	"ambassador/src/models"
+	"ambassador/src/passwordpolicy"
+	"ambassador/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errResetTokenInvalid = errors.New("invalid or expired reset token")
+
+type ForgotPasswordRequest struct {
+    Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+    Token           string `json:"token" validate:"required"`
+    Password        string `json:"password" validate:"required,min=8,max=72"`
+    PasswordConfirm string `json:"password_confirm" validate:"required"`
+}
+
+// ForgotPassword emails a reset link if the account exists.
+// The response is identical either way to prevent user enumeration.
+// POST /api/{admin,ambassador}/password/forgot
+func ForgotPassword(c *fiber.Ctx) error {
+    var data ForgotPasswordRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Email = strings.ToLower(strings.TrimSpace(data.Email))
+    if !emailRegex.MatchString(data.Email) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid email format",
+        })
+    }
+
+    // Same generic response whether or not the account exists
+    response := fiber.Map{
+        "message": "If an account exists for this email, a reset link has been sent",
+    }
+
+    // Admins and ambassadors reset through their own endpoints
+    isAmbassador := strings.HasPrefix(c.Path(), "/api/ambassador")
+
+    // The lookup and token work run after responding, so known and unknown
+    // emails take the same time to answer
+    go issuePasswordReset(strings.Clone(data.Email), isAmbassador, strings.Clone(c.IP()))
+
+    return c.JSON(response)
+}
+
+// issuePasswordReset creates a reset token for the account, if there is one,
+// and mails the link
+func issuePasswordReset(email string, isAmbassador bool, requestIP string) {
+    var user models.User
+    if err := database.DB.
+        Where("email = ? AND is_ambassador = ?", email, isAmbassador).
+        First(&user).Error; err != nil {
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+            log.Printf("Password reset lookup failed: %v", err)
+        }
+        return
+    }
+
+    token, err := utils.GenerateToken(32)
+    if err != nil {
+        log.Printf("Reset token generation failed: %v", err)
+        return
+    }
+
+    cfg := config.Get()
+    reset := models.PasswordReset{
+        UserID:    user.ID,
+        TokenHash: utils.HashToken(token),
+        ExpiresAt: time.Now().Add(time.Duration(cfg.PasswordResetExpireMinutes) * time.Minute),
+        RequestIP: requestIP,
+    }
+
+    // Only the newest link works: invalidate older unused tokens
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&models.PasswordReset{}).
+            Where("user_id = ? AND used_at IS NULL", user.ID).
+            Update("used_at", time.Now()).Error; err != nil {
+            return err
+        }
+        return tx.Create(&reset).Error
+    })
+    if err != nil {
+        log.Printf("Password reset creation failed for user %d: %v", user.ID, err)
+        return
+    }
+
+    sendPasswordResetEmail(&user, token, isAmbassador)
+}
+
+// ResetPassword sets a new password using a reset token and revokes existing sessions
+// POST /api/{admin,ambassador}/password/reset
+func ResetPassword(c *fiber.Ctx) error {
+    var data ResetPasswordRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Token = strings.TrimSpace(data.Token)
+    if data.Token == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "token is required",
+        })
+    }
+
+    isAmbassador := strings.HasPrefix(c.Path(), "/api/ambassador")
+
+    var reset models.PasswordReset
+    if err := database.DB.
+        Preload("User").
+        Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(data.Token), time.Now()).
+        First(&reset).Error; err != nil || reset.User.ID == 0 || reset.User.IsAmbassador != isAmbassador {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": errResetTokenInvalid.Error(),
+        })
+    }
+
+    // Same policy as registration, including reuse of recent passwords
+    if err := validatePassword(data.Password, data.PasswordConfirm, &reset.User); err != nil {
+        return passwordError(c, err)
+    }
+    if err := passwordpolicy.CheckReuse(database.DB, &reset.User, data.Password); err != nil {
+        return passwordError(c, err)
+    }
+
+    hashedPassword, err := utils.HashPassword(data.Password)
+    if err != nil {
+        log.Printf("Password hashing failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to reset password",
+        })
+    }
+
+    // JWT iat has second precision; tokens from earlier seconds are revoked
+    now := time.Now().Truncate(time.Second)
+
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        // Guarded update keeps the token single-use under concurrent requests
+        result := tx.Model(&models.PasswordReset{}).
+            Where("id = ? AND used_at IS NULL", reset.ID).
+            Update("used_at", now)
+        if result.Error != nil {
+            return result.Error
+        }
+        if result.RowsAffected == 0 {
+            return errResetTokenInvalid
+        }
+
+        // Any other outstanding links die with this one
+        if err := tx.Model(&models.PasswordReset{}).
+            Where("user_id = ? AND used_at IS NULL", reset.UserID).
+            Update("used_at", now).Error; err != nil {
+            return err
+        }
+
+        if err := passwordpolicy.Remember(tx, reset.UserID, reset.User.Password); err != nil {
+            return err
+        }
+
+        return tx.Model(&models.User{}).
+            Where("id = ?", reset.UserID).
+            Updates(map[string]interface{}{
+                "password":            hashedPassword,
+                "sessions_revoked_at": now,
+            }).Error
+    })
+    if err != nil {
+        if errors.Is(err, errResetTokenInvalid) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": errResetTokenInvalid.Error(),
+            })
+        }
+        log.Printf("Password reset failed for user %d: %v", reset.UserID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to reset password",
+        })
+    }
+
+    log.Printf("Password reset for user %d, existing sessions revoked", reset.UserID)
+
+    return c.JSON(fiber.Map{
+        "message": "password reset successfully, please log in again",
+    })
+}
+
+// sendPasswordResetEmail mails the reset link in the background
+func sendPasswordResetEmail(user *models.User, token string, isAmbassador bool) {
+    cfg := config.Get()
+
+    scope := ScopeAdmin
+    if isAmbassador {
+        scope = ScopeAmbassador
+    }
+    link := fmt.Sprintf("%s/reset-password?scope=%s&token=%s", cfg.FrontendURL, scope, url.QueryEscape(token))
+
+    msg := mailer.Message{
+        To:      user.Email,
+        Subject: "Reset your password",
+        Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %d minutes and can be used once. If you didn't request this, you can ignore this email.\n",
+            user.FirstName, link, cfg.PasswordResetExpireMinutes),
+    }
+
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        defer cancel()
+
+        if err := mailer.Send(ctx, msg); err != nil {
+            log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+        }
+    }()
+}
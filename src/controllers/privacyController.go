@@ -0,0 +1,443 @@
+package controllers
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/ratelimit"
+	"ambassador/src/utils"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errLastAdmin = errors.New("the last admin account can't be deleted")
+
+type DeleteAccountRequest struct {
+    Password string `json:"password" validate:"required"`
+}
+
+type BuyerErasureRequest struct {
+    Email string `json:"email" validate:"required,email"`
+}
+
+// DataExport is everything stored about a user, as returned by ExportMe
+type DataExport struct {
+    ExportedAt time.Time        `json:"exported_at"`
+    Profile    UserResponse     `json:"profile"`
+    Links      []ExportLink     `json:"links"`
+    Orders     []models.Order   `json:"orders"`
+    Stats      []LinkStat       `json:"stats"`
+    APIKeys    []APIKeyResponse `json:"api_keys"`
+    Identities []ExportIdentity `json:"identities"`
+}
+
+type ExportLink struct {
+    Code      string          `json:"code"`
+    CreatedAt time.Time       `json:"created_at"`
+    Products  []ExportProduct `json:"products"`
+}
+
+type ExportProduct struct {
+    ID    uint   `json:"id"`
+    Title string `json:"title"`
+}
+
+type ExportIdentity struct {
+    Issuer    string    `json:"issuer"`
+    Email     string    `json:"email"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportMe downloads the authenticated user's data as JSON or, with ?format=zip,
+// as a ZIP archive with one JSON file per section
+// GET /api/{admin,ambassador}/me/export
+func ExportMe(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    format := strings.ToLower(c.Query("format", "json"))
+    if format != "json" && format != "zip" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "format must be json or zip",
+        })
+    }
+
+    export, err := buildDataExport(c, user)
+    if err != nil {
+        log.Printf("Data export failed for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to export data",
+        })
+    }
+
+    filename := fmt.Sprintf("data-export-%d-%s", user.ID, export.ExportedAt.Format("20060102"))
+
+    if format == "json" {
+        c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+        return c.JSON(export)
+    }
+
+    archive, err := zipDataExport(export)
+    if err != nil {
+        log.Printf("Data export archive failed for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to export data",
+        })
+    }
+
+    c.Set(fiber.HeaderContentType, "application/zip")
+    c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+    return c.Send(archive)
+}
+
+// buildDataExport gathers the profile, links, orders, stats and credentials metadata
+func buildDataExport(c *fiber.Ctx, user *models.User) (*DataExport, error) {
+    ctx := c.Context()
+    db := database.DB.WithContext(ctx)
+
+    export := &DataExport{
+        ExportedAt: time.Now().UTC(),
+        Profile: UserResponse{
+            ID:              user.ID,
+            FirstName:       user.FirstName,
+            LastName:        user.LastName,
+            Email:           user.Email,
+            IsAmbassador:    user.IsAmbassador,
+            EmailVerifiedAt: user.EmailVerifiedAt,
+        },
+        Links:      []ExportLink{},
+        Orders:     []models.Order{},
+        Stats:      []LinkStat{},
+        APIKeys:    []APIKeyResponse{},
+        Identities: []ExportIdentity{},
+    }
+
+    var links []models.Link
+    if err := db.Preload("Products").Where("user_id = ?", user.ID).Find(&links).Error; err != nil {
+        return nil, err
+    }
+    for _, link := range links {
+        exported := ExportLink{Code: link.Code, CreatedAt: link.CreatedAt, Products: []ExportProduct{}}
+        for _, product := range link.Products {
+            exported.Products = append(exported.Products, ExportProduct{ID: product.ID, Title: product.Title})
+        }
+        export.Links = append(export.Links, exported)
+    }
+
+    // Orders the user placed or was credited for (same rows as the orders endpoint)
+    if err := db.Preload("OrderItems").
+        Where("user_id = ? OR ambassador_email = ?", user.ID, user.Email).
+        Find(&export.Orders).Error; err != nil {
+        return nil, err
+    }
+    for i, order := range export.Orders {
+        export.Orders[i].Name = order.FullName()
+        export.Orders[i].Total = order.GetTotal()
+    }
+
+    stats, err := linkStats(ctx, user.ID)
+    if err != nil {
+        return nil, err
+    }
+    if stats != nil {
+        export.Stats = stats
+    }
+
+    var keys []models.APIKey
+    if err := db.Where("user_id = ?", user.ID).Find(&keys).Error; err != nil {
+        return nil, err
+    }
+    for i := range keys {
+        export.APIKeys = append(export.APIKeys, toAPIKeyResponse(&keys[i]))
+    }
+
+    var identities []models.UserIdentity
+    if err := db.Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+        return nil, err
+    }
+    for _, identity := range identities {
+        export.Identities = append(export.Identities, ExportIdentity{
+            Issuer:    identity.Issuer,
+            Email:     identity.Email,
+            CreatedAt: identity.CreatedAt,
+        })
+    }
+
+    return export, nil
+}
+
+// zipDataExport packs each export section into its own JSON file
+func zipDataExport(export *DataExport) ([]byte, error) {
+    var buf bytes.Buffer
+    archive := zip.NewWriter(&buf)
+
+    files := []struct {
+        name string
+        data interface{}
+    }{
+        {"profile.json", export.Profile},
+        {"links.json", export.Links},
+        {"orders.json", export.Orders},
+        {"stats.json", export.Stats},
+        {"api_keys.json", export.APIKeys},
+        {"identities.json", export.Identities},
+    }
+
+    for _, file := range files {
+        w, err := archive.CreateHeader(&zip.FileHeader{
+            Name:     file.name,
+            Method:   zip.Deflate,
+            Modified: export.ExportedAt,
+        })
+        if err != nil {
+            return nil, err
+        }
+
+        encoder := json.NewEncoder(w)
+        encoder.SetIndent("", "  ")
+        if err := encoder.Encode(file.data); err != nil {
+            return nil, err
+        }
+    }
+
+    if err := archive.Close(); err != nil {
+        return nil, err
+    }
+
+    return buf.Bytes(), nil
+}
+
+// DeleteMe permanently anonymizes the authenticated account.
+// Orders keep their totals and revenue; buyer details and credentials are erased.
+// DELETE /api/{admin,ambassador}/me
+func DeleteMe(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    var data DeleteAccountRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    // Re-authenticate: a stolen session alone can't delete the account
+    if data.Password == "" || utils.CheckPassword(user.Password, data.Password) != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "password is incorrect",
+        })
+    }
+
+    originalEmail := user.Email
+
+    if err := deleteAccount(user); err != nil {
+        if errors.Is(err, errLastAdmin) {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+                "error": err.Error(),
+            })
+        }
+        log.Printf("Account deletion failed for user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to delete account",
+        })
+    }
+
+    // Drop any login failure history keyed by the old email
+    if err := ratelimit.Unlock(c.Context(), originalEmail); err != nil {
+        log.Printf("Failed to clear login history for deleted user %d: %v", user.ID, err)
+    }
+
+    log.Printf("User %d deleted their account", user.ID)
+
+    c.Cookie(&fiber.Cookie{
+        Name:     "jwt",
+        Value:    "",
+        Expires:  time.Now().Add(-time.Hour),
+        HTTPOnly: true,
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "account deleted",
+    })
+}
+
+// deleteAccount erases the user's personal data in one transaction and soft-deletes
+// the row so order foreign keys and revenue attribution stay intact
+func deleteAccount(user *models.User) error {
+    now := time.Now().Truncate(time.Second)
+    erasedEmail := fmt.Sprintf("deleted-%d@anonymized.invalid", user.ID)
+
+    return database.DB.Transaction(func(tx *gorm.DB) error {
+        // Lock every admin row in ID order so concurrent self-deletions run
+        // one after the other and the later one sees the earlier as deleted
+        if !user.IsAmbassador {
+            var admins []models.User
+            if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                Select("id", "suspended_at").
+                Where("is_ambassador = ?", false).
+                Order("id").
+                Find(&admins).Error; err != nil {
+                return err
+            }
+            others := 0
+            for _, admin := range admins {
+                if admin.ID != user.ID && !admin.IsSuspended() {
+                    others++
+                }
+            }
+            if others == 0 {
+                return errLastAdmin
+            }
+        }
+
+        // Orders the user bought: erase buyer details, keep totals
+        if _, err := models.AnonymizeOrders(tx.Where("user_id = ?", user.ID), now); err != nil {
+            return err
+        }
+
+        // Orders credited to the user keep their revenue under the erased address
+        if err := tx.Model(&models.Order{}).
+            Where("ambassador_email = ?", user.Email).
+            Update("ambassador_email", erasedEmail).Error; err != nil {
+            return err
+        }
+
+        // Links and their product and variant associations
+        if err := tx.Exec("DELETE FROM link_products WHERE link_id IN (SELECT id FROM links WHERE user_id = ?)", user.ID).Error; err != nil {
+            return err
+        }
+        if err := tx.Exec("DELETE FROM link_variants WHERE link_id IN (SELECT id FROM links WHERE user_id = ?)", user.ID).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("user_id = ?", user.ID).Delete(&models.Link{}).Error; err != nil {
+            return err
+        }
+
+        // Credentials and login artefacts
+        for _, model := range []interface{}{
+            &models.APIKey{},
+            &models.RecoveryCode{},
+            &models.UserIdentity{},
+            &models.PasswordReset{},
+            &models.PasswordHistory{},
+            &models.MFAChallenge{},
+            &models.Impersonation{},
+        } {
+            if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+                return err
+            }
+        }
+
+        if err := tx.Model(&models.AdminInvite{}).
+            Where("used_by_id = ?", user.ID).
+            Update("email", erasedEmail).Error; err != nil {
+            return err
+        }
+
+        if err := tx.Model(&models.User{}).
+            Where("id = ?", user.ID).
+            Updates(map[string]interface{}{
+                "first_name":          "Deleted",
+                "last_name":           "User",
+                "email":               erasedEmail,
+                "password":            nil,
+                "email_verified_at":   nil,
+                "totp_secret":         "",
+                "totp_enabled_at":     nil,
+                "sessions_revoked_at": now,
+            }).Error; err != nil {
+            return err
+        }
+
+        return tx.Delete(&models.User{}, user.ID).Error
+    })
+}
+
+// ExportBuyer returns the orders stored for a buyer email (data access requests)
+// GET /api/admin/buyers/export?email=
+func ExportBuyer(c *fiber.Ctx) error {
+    email := strings.ToLower(strings.TrimSpace(c.Query("email")))
+    if !emailRegex.MatchString(email) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid email format",
+        })
+    }
+
+    var orders []models.Order
+    if err := database.DB.
+        WithContext(c.Context()).
+        Preload("OrderItems").
+        Where("email = ?", email).
+        Find(&orders).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch orders",
+        })
+    }
+
+    for i, order := range orders {
+        orders[i].Name = order.FullName()
+        orders[i].Total = order.GetTotal()
+    }
+
+    return c.JSON(fiber.Map{
+        "email":       email,
+        "exported_at": time.Now().UTC(),
+        "orders":      orders,
+        "count":       len(orders),
+    })
+}
+
+// EraseBuyer anonymizes every order placed with a buyer email (erasure requests)
+// POST /api/admin/buyers/erase
+func EraseBuyer(c *fiber.Ctx) error {
+    var data BuyerErasureRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Email = strings.ToLower(strings.TrimSpace(data.Email))
+    if !emailRegex.MatchString(data.Email) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid email format",
+        })
+    }
+
+    erased, err := models.AnonymizeOrders(
+        database.DB.WithContext(c.Context()).Where("email = ?", data.Email),
+        time.Now().Truncate(time.Second),
+    )
+    if err != nil {
+        log.Printf("Buyer erasure failed: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to erase buyer data",
+        })
+    }
+
+    adminID, _ := middlewares.GetUserID(c)
+    log.Printf("Admin %d erased buyer data on %d orders", adminID, erased)
+
+    return c.JSON(fiber.Map{
+        "message":       "buyer data erased",
+        "orders_erased": erased,
+    })
+}
@@ -1,26 +1,77 @@
 package controllers
 
 import (
+	"ambassador/src/audit"
 	"ambassador/src/database"
 	"ambassador/src/models"
+	"ambassador/src/search"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var errProductNotDeleted = errors.New("no rows affected")
+
 type CreateProductRequest struct {
     Title        string  `json:"title" validate:"required,min=1,max=255"`
     Description string  `json:"description" validate:"required,min=10,max=1000"`
     Image       string  `json:"image" validate:"required,url"`
     Price       float64 `json:"price" validate:"required,min=0,max=100000"`
+    Stock       *int    `json:"stock" validate:"omitempty,min=0"` // omitted = stock not tracked
+    ExternalSKU string  `json:"external_sku" validate:"omitempty,max=64"`
+}
+
+// normalize trims the text fields
+func (r *CreateProductRequest) normalize() {
+    r.Title = strings.TrimSpace(r.Title)
+    r.Description = strings.TrimSpace(r.Description)
+    r.Image = strings.TrimSpace(r.Image)
+    r.ExternalSKU = strings.TrimSpace(r.ExternalSKU)
+}
+
+// validate checks a normalized import row against the rules in its tags and
+// returns every problem found. Bulk imports only: CreateProducts keeps its
+// own, looser checks and error messages.
+func (r *CreateProductRequest) validate() []string {
+    var problems []string
+    if n := utf8.RuneCountInString(r.Title); n == 0 || n > 255 {
+        problems = append(problems, "title must be 1-255 characters")
+    }
+    if n := utf8.RuneCountInString(r.Description); n < 10 || n > 1000 {
+        problems = append(problems, "description must be 10-1000 characters")
+    }
+    if !validImageURL(r.Image) {
+        problems = append(problems, "image must be an http(s) URL of at most 500 characters")
+    }
+    if r.Price <= 0 || r.Price > 100000 || math.IsNaN(r.Price) {
+        problems = append(problems, "price must be greater than 0 and at most 100000")
+    }
+    if r.Stock != nil && *r.Stock < 0 {
+        problems = append(problems, "stock cannot be negative")
+    }
+    if r.ExternalSKU != "" && !skuPattern.MatchString(r.ExternalSKU) {
+        problems = append(problems, "external_sku must be 1-64 letters, digits, dots, dashes or underscores")
+    }
+    return problems
+}
+
+func validImageURL(raw string) bool {
+    if raw == "" || len(raw) > 500 {
+        return false
+    }
+    u, err := url.Parse(raw)
+    return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
 
 type ProductResponse struct {
@@ -29,6 +80,25 @@ type ProductResponse struct {
     Description string  `json:"description"`
     Image       string  `json:"image"`
     Price       float64 `json:"price"`
+    Version     uint    `json:"version"`
+    ExternalSKU *string `json:"external_sku"`
+
+    // Original price is Price; EffectivePrice is what is charged right now
+    SalePrice      *float64   `json:"sale_price"`
+    SaleStartsAt   *time.Time `json:"sale_starts_at"`
+    SaleEndsAt     *time.Time `json:"sale_ends_at"`
+    OnSale         bool       `json:"on_sale" gorm:"-"`
+    EffectivePrice float64    `json:"effective_price" gorm:"-"`
+
+    // Stock nil = not tracked; Reserved units are held by pending checkouts
+    Stock    *int `json:"stock"`
+    Reserved int  `json:"reserved"`
+    InStock  bool `json:"in_stock" gorm:"-"`
+
+    Categories []models.Category       `json:"categories,omitempty" gorm:"-"`
+    Tags       []models.Tag            `json:"tags,omitempty" gorm:"-"`
+    Variants   []models.ProductVariant `json:"variants,omitempty" gorm:"-"`
+    Images     []ProductImageResponse  `json:"images,omitempty" gorm:"-"`
 }
 
 type ProductListResponse struct {
@@ -37,8 +107,34 @@ type ProductListResponse struct {
     Description string  `json:"description"`
     Image       string  `json:"image"`
     Price       float64 `json:"price"`
+
+    SalePrice      *float64   `json:"sale_price"`
+    SaleStartsAt   *time.Time `json:"sale_starts_at"`
+    SaleEndsAt     *time.Time `json:"sale_ends_at"`
+    OnSale         bool       `json:"on_sale" gorm:"-"`
+    EffectivePrice float64    `json:"effective_price" gorm:"-"`
+
+    // Stock levels stay internal; listings only say whether it can be ordered
+    Stock           *int `json:"-"`
+    Reserved        int  `json:"-"`
+    VariantCount    int  `json:"-"`
+    VariantsInStock int  `json:"-"`
+    InStock         bool `json:"in_stock" gorm:"-"`
+    HasVariants     bool `json:"has_variants" gorm:"-"`
+
+    // Search results only: MATCH score and <mark>-highlighted, HTML-escaped text
+    Relevance      *float64 `json:"relevance,omitempty"`
+    TitleHighlight string   `json:"title_highlight,omitempty" gorm:"-"`
+    Snippet        string   `json:"snippet,omitempty" gorm:"-"`
 }
 
+// Columns loaded into product responses; products with variants are in
+// stock while any variant is
+const productListColumns = "id, title, description, image, price, sale_price, sale_starts_at, sale_ends_at, stock, reserved," +
+    " (SELECT COUNT(*) FROM product_variants WHERE product_variants.product_id = products.id) AS variant_count," +
+    " (SELECT COUNT(*) FROM product_variants WHERE product_variants.product_id = products.id" +
+    " AND (product_variants.stock IS NULL OR product_variants.stock > product_variants.reserved)) AS variants_in_stock"
+
 func Products(c *fiber.Ctx) error {
     ctx := c.Context()
     cacheKey := "products_admin" // Admin-specific cache
@@ -48,6 +144,7 @@ func Products(c *fiber.Ctx) error {
         log.Printf(" Cache HIT - Products (admin)")
         var products []ProductListResponse
         if jsonErr := json.Unmarshal([]byte(cached), &products); jsonErr == nil {
+            applyListPricing(products, time.Now())
             return c.JSON(fiber.Map{
                 "data":   products,
                 "source": "cache",
@@ -62,7 +159,7 @@ func Products(c *fiber.Ctx) error {
     
     if err := database.DB.
         Model(&models.Product{}).
-        Select("id, title, description, image, price").
+        Select(productListColumns).
         Order("id ASC"). // Consistent order
         Find(&products).Error; err != nil {
         
@@ -72,6 +169,9 @@ func Products(c *fiber.Ctx) error {
         })
     }
 
+    applyListPricing(products, time.Now())
+    applyListStock(products)
+
     // 3. CACHE RESULTS (5min TTL)
     if jsonData, err := json.Marshal(products); err == nil {
         ttl := 5 * time.Minute
@@ -97,9 +197,7 @@ func CreateProducts(c *fiber.Ctx) error {
     }
 
 	// Normalize input
-    data.Title = strings.TrimSpace(data.Title)
-    data.Description = strings.TrimSpace(data.Description)
-    data.Image = strings.TrimSpace(data.Image)
+    data.normalize()
 
 	 // Validate (Register pattern)
     if data.Title == "" || data.Description == "" || data.Image == "" || data.Price < 0 {
@@ -114,30 +212,79 @@ func CreateProducts(c *fiber.Ctx) error {
         })
     }
 
+    if data.Stock != nil && *data.Stock < 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "stock cannot be negative",
+        })
+    }
+
+    if data.ExternalSKU != "" && !skuPattern.MatchString(data.ExternalSKU) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "external_sku must be 1-64 letters, digits, dots, dashes or underscores",
+        })
+    }
+
+    if data.ExternalSKU != "" {
+        var taken int64
+        if err := database.DB.Unscoped().Model(&models.Product{}).
+            Where("external_sku = ?", data.ExternalSKU).
+            Count(&taken).Error; err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "failed to check external_sku",
+            })
+        }
+        if taken > 0 {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+                "error": "external_sku already in use",
+            })
+        }
+    }
+
 	product := models.Product{
         Title:        data.Title,
         Description: data.Description,
         Image:       data.Image,
         Price:       data.Price,
+        Stock:       data.Stock,
+    }
+    if data.ExternalSKU != "" {
+        product.ExternalSKU = &data.ExternalSKU
     }
 
-    if err := database.DB.Create(&product).Error; err != nil {
+    actorID := actorIDPtr(c)
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&product).Error; err != nil {
+            return err
+        }
+        _, err := models.RecordProductVersion(tx, &product, models.ProductChangeCreate, actorID, nil)
+        return err
+    })
+    if err != nil {
+        // Lost a race for the SKU to a concurrent create or import
+        if data.ExternalSKU != "" && strings.Contains(err.Error(), "Duplicate entry") {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+                "error": "external_sku already in use",
+            })
+        }
+        log.Printf("Failed to create product: %v", err)
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create"})
     }
 
     // INSTANT CACHE INVALIDATION - REDIS
     database.ClearProductCaches(c.Context(),  uint(product.ID))
+    search.IndexProduct(&product)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.create",
+        TargetType: "product",
+        TargetID:   product.ID,
+        After:      toProductResponse(&product),
+    })
 
     // MANUAL MAPPING - Exact control
     return c.Status(fiber.StatusCreated).JSON(fiber.Map{
         "message": "product created successfully",
-        "data": ProductResponse{
-            ID:          product.ID,
-            Title:       product.Title,
-            Description: product.Description,
-            Image:       product.Image,
-            Price:       product.Price,
-        },
+        "data": toProductResponse(&product),
         "cache_cleared": true,
     })
 }
@@ -168,6 +315,8 @@ func GetProduct(c *fiber.Ctx) error {
         log.Printf("Cache HIT - Product %d", id)
         var product ProductResponse
         if jsonErr := json.Unmarshal([]byte(cached), &product); jsonErr == nil {
+            // Recomputed so a cached entry never shows a stale sale state
+            product.applyPricing(time.Now())
             return c.JSON(fiber.Map{
                 "data":   product,
                 "source": "cache",
@@ -182,7 +331,7 @@ func GetProduct(c *fiber.Ctx) error {
     if err := database.DB.
         Model(&models.Product{}).
         Where("id = ?", id).
-        Select("id, title, description, image, price").
+        Select(productListColumns + ", version").
         First(&product).Error; err != nil {
         
         if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -197,6 +346,23 @@ func GetProduct(c *fiber.Ctx) error {
         })
     }
 
+    // Categories, tags, variants and images are cached with the product and cleared when they change
+    owner := &models.Product{Model: models.Model{ID: product.ID}}
+    if err := database.DB.Model(owner).Association("Categories").Find(&product.Categories); err != nil {
+        log.Printf("Failed to fetch categories of product %d: %v", id, err)
+    }
+    if err := database.DB.Model(owner).Association("Tags").Find(&product.Tags); err != nil {
+        log.Printf("Failed to fetch tags of product %d: %v", id, err)
+    }
+    if err := database.DB.Where("product_id = ?", product.ID).Order("id").Find(&product.Variants).Error; err != nil {
+        log.Printf("Failed to fetch variants of product %d: %v", id, err)
+    }
+    if product.Images, err = loadProductImages(database.DB, product.ID); err != nil {
+        log.Printf("Failed to fetch images of product %d: %v", id, err)
+    }
+
+    product.applyPricing(time.Now())
+
     // 3. CACHE SINGLE PRODUCT (15min TTL - longer for single items)
     if jsonData, err := json.Marshal(product); err == nil {
         ttl := 15 * time.Minute // Single product = longer cache
@@ -256,45 +422,67 @@ func UpdateProduct(c *fiber.Ctx) error {
         })
     }
 
-	// Fetch existing product first
+	// Read, merge and snapshot under the row lock, so concurrent partial
+	// updates each build on the other's result and versions match real states
+    var before ProductResponse
     var existingProduct models.Product
-    if err := database.DB.First(&existingProduct, id).Error; err != nil {
-        if errors.Is(err, gorm.ErrRecordNotFound) {
-            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-                "error": "product not found",
-            })
+    actorID := actorIDPtr(c)
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&existingProduct, id).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return fiber.NewError(fiber.StatusNotFound, "product not found")
+            }
+            return err
         }
-        log.Printf("Failed to fetch product %d: %v", id, err)
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "failed to fetch product",
-        })
-    }
+        before = toProductResponse(&existingProduct)
+        original := existingProduct
 
-	// Update only provided fields (partial update)
-    updates := map[string]interface{}{}
+        // Update only provided fields (partial update)
+        updates := map[string]interface{}{}
 
-    if data.Title != "" {
-        existingProduct.Title = data.Title  // or Name if your model uses Name
-        updates["title"] = data.Title
-    }
+        if data.Title != "" {
+            existingProduct.Title = data.Title
+            updates["title"] = data.Title
+        }
 
-    if data.Description != "" {
-        existingProduct.Description = data.Description
-        updates["description"] = data.Description
-    }
+        if data.Description != "" {
+            existingProduct.Description = data.Description
+            updates["description"] = data.Description
+        }
 
-    if data.Image != "" {
-        existingProduct.Image = data.Image
-        updates["image"] = data.Image
-    }
+        if data.Image != "" {
+            existingProduct.Image = data.Image
+            updates["image"] = data.Image
+        }
 
-    if data.Price > 0 {
-        existingProduct.Price = data.Price
-        updates["price"] = data.Price
-    }
+        if data.Price > 0 {
+            // A running or scheduled sale must stay a discount
+            if existingProduct.SalePrice != nil && data.Price <= *existingProduct.SalePrice {
+                return fiber.NewError(fiber.StatusBadRequest, "price must be higher than the product's sale price")
+            }
+            existingProduct.Price = data.Price
+            updates["price"] = data.Price
+        }
 
-	// Save to database
-    if err := database.DB.Model(&existingProduct).Updates(updates).Error; err != nil {
+        // Products created before versioning get their original state recorded first
+        if original.Version == 0 {
+            if _, err := models.RecordProductVersion(tx, &original, models.ProductChangeBaseline, nil, nil); err != nil {
+                return err
+            }
+        }
+        if err := tx.Model(&models.Product{}).Where("id = ?", existingProduct.ID).Updates(updates).Error; err != nil {
+            return err
+        }
+        _, err := models.RecordProductVersion(tx, &existingProduct, models.ProductChangeUpdate, actorID, nil)
+        return err
+    })
+    if err != nil {
+        var ferr *fiber.Error
+        if errors.As(err, &ferr) {
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
         log.Printf("Failed to update product %d: %v", id, err)
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "error": "failed to update product",
@@ -303,15 +491,18 @@ func UpdateProduct(c *fiber.Ctx) error {
 
     // NSTANT CACHE INVALIDATION - REDIS
     database.ClearProductCaches(c.Context(),  existingProduct.ID)
+    search.IndexProduct(&existingProduct)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.update",
+        TargetType: "product",
+        TargetID:   existingProduct.ID,
+        Before:     before,
+        After:      toProductResponse(&existingProduct),
+    })
 
 	// Return shaped response (same as GetProduct)
-    response := ProductResponse{
-        ID:          existingProduct.ID,
-        Title:       existingProduct.Title,
-        Description: existingProduct.Description,
-        Image:       existingProduct.Image,
-        Price:       existingProduct.Price,
-    }
+    response := toProductResponse(&existingProduct)
 
    return c.JSON(fiber.Map{
         "message":       "product updated successfully",
@@ -352,17 +543,23 @@ func DeleteProduct(c *fiber.Ctx) error {
 	}
 
 	// Delete from database (perfect) - Soft delete with gorm.Model
-    result := database.DB.Delete(&existingProduct)
-    if result.Error != nil {
-        log.Printf("Failed to delete product %d: %v", id, result.Error)
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "failed to delete product",
-        })
-    }
-
-	// Verify delete worked
-    if result.RowsAffected == 0 {
-        log.Printf("No rows affected when deleting product %d", id)
+    // The final state is versioned so history stays complete
+    actorID := actorIDPtr(c)
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if _, err := models.RecordProductVersion(tx, &existingProduct, models.ProductChangeDelete, actorID, nil); err != nil {
+            return err
+        }
+        result := tx.Delete(&existingProduct)
+        if result.Error != nil {
+            return result.Error
+        }
+        if result.RowsAffected == 0 {
+            return errProductNotDeleted
+        }
+        return nil
+    })
+    if err != nil {
+        log.Printf("Failed to delete product %d: %v", id, err)
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "error": "failed to delete product",
         })
@@ -370,6 +567,14 @@ func DeleteProduct(c *fiber.Ctx) error {
 
      //  INSTANT CACHE INVALIDATION - REDIS
     database.ClearProductCaches(c.Context(),  existingProduct.ID)
+    search.RemoveProduct(existingProduct.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.delete",
+        TargetType: "product",
+        TargetID:   existingProduct.ID,
+        Before:     toProductResponse(&existingProduct),
+    })
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
         "message":       "product deleted successfully",
@@ -396,6 +601,7 @@ func ProductFrontEnd(c *fiber.Ctx) error {
         log.Printf("Cache HIT for %s", cacheKey)
         var response ProductFrontendResponse
         if jsonErr := json.Unmarshal([]byte(cached), &response); jsonErr == nil {
+            applyListPricing(response.Data, time.Now())
             response.Cached = true
             return c.JSON(response)
         }
@@ -411,7 +617,7 @@ func ProductFrontEnd(c *fiber.Ctx) error {
     if err := database.DB.
         Model(&models.Product{}).
         Count(&total).
-        Select("id, title, description, image, price").
+        Select(productListColumns).
         Order("id ASC").
         Find(&products).Error; err != nil {
         
@@ -421,6 +627,9 @@ func ProductFrontEnd(c *fiber.Ctx) error {
         })
     }
 
+    applyListPricing(products, time.Now())
+    applyListStock(products)
+
     // 3. BUILD RESPONSE WITH TIMESTAMP
     response := ProductFrontendResponse{
         Data:    products,
@@ -449,12 +658,23 @@ type ProductBackendResponse struct {
     Page    int                    `json:"page"`
     PerPage int                    `json:"per_page"`
     LastPage int                   `json:"last_page"`
+    NextCursor string              `json:"next_cursor,omitempty"` // cursor mode; empty on the last page
+    Facets  *ProductFacets         `json:"facets"`
+
+    // In-memory search only: the query matched more than search.MaxProductResults
+    // products; results, total_count and pages cover the best-ranked ones
+    ResultsCapped bool `json:"results_capped,omitempty"`
+    SearchMatches int  `json:"search_matches,omitempty"` // all matches, before filters
 }
 
 // ProductBackend handles product listing with search and sort functionality
 // GET /api/products - Returns all products (sorted by ID)
 // GET /api/products?s=macbook - Search products (sorted by price ASC by default)
 // GET /api/products?s=macbook&sort=desc - Search products (sorted by price DESC)
+// GET /api/products?s=macbook&sort=relevance&mode=boolean - Full-text search, best match first
+// GET /api/products?sort_by=popularity&order=desc - Best sellers (price|title|created_at|popularity|revenue)
+// GET /api/products?cursor=&per_page=20 - Keyset pagination, then ?cursor=<next_cursor>
+// GET /api/products?category=laptops&tag=sale,new&min_price=10&max_price=500 - Filtered (with facet counts)
 // ProductBackend handles product listing with FULL pagination, search, and sort
 func ProductBackend(c *fiber.Ctx) error {
     ctx := c.Context()
@@ -462,21 +682,54 @@ func ProductBackend(c *fiber.Ctx) error {
     // PAGINATION & QUERY PARAMETERS
     page := max(1, atoi(c.Query("page", "1")))
     perPage := clamp(atoi(c.Query("per_page", "10")), 1, 100)
-    sortDir := c.Query("sort", "asc")
     searchQuery := strings.TrimSpace(c.Query("s", ""))
-    normalizedQuery := strings.ToLower(searchQuery)
 
-    // DYNAMIC CACHE KEY (includes pagination!)
-    cacheKey := fmt.Sprintf("products:p:%d:pp:%d:s:%s:sort:%s", 
-        page, perPage, normalizedQuery, sortDir)
+    // CURSOR MODE: ?cursor= (empty for the first page) replaces page numbers
+    cursorMode := c.Context().QueryArgs().Has("cursor")
+    rawCursor := strings.TrimSpace(c.Query("cursor"))
+
+    // FILTERS (category, tag, min_price, max_price)
+    filter, ferr := parseProductFilter(c, database.DB)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+    sort, ferr := resolveProductSort(c, filter)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var cursor *productCursor
+    if cursorMode && rawCursor != "" {
+        decoded, err := decodeProductCursor(rawCursor, sort)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": err.Error(),
+            })
+        }
+        cursor = &decoded
+    }
 
-    log.Printf("Page %d/%d, Search: '%s', Sort: %s", page, perPage, searchQuery, sortDir)
+    // DYNAMIC CACHE KEY (includes pagination and filters!)
+    // products:p:* = page mode, products:c:* = cursor mode
+    cacheKey := fmt.Sprintf("products:p:%d:pp:%d:sort:%s:%s", 
+        page, perPage, sort, filter.cacheKey())
+    if cursorMode {
+        cacheKey = fmt.Sprintf("products:c:%s:pp:%d:sort:%s:%s",
+            rawCursor, perPage, sort, filter.cacheKey())
+    }
+
+    log.Printf("Page %d/%d, Search: '%s', Sort: %s", page, perPage, searchQuery, sort)
 
     // 1. CHECK CACHE FIRST
     if cached, err := database.CacheGet(ctx, cacheKey); err == nil {
         log.Printf("Cache HIT for %s", cacheKey)
         var response ProductBackendResponse
         if jsonErr := json.Unmarshal([]byte(cached), &response); jsonErr == nil {
+            applyListPricing(response.Data, time.Now())
             response.Cached = true
             response.Source = "cache"
             return c.JSON(response)
@@ -485,59 +738,66 @@ func ProductBackend(c *fiber.Ctx) error {
 
     // 2. TOTAL COUNT (CRITICAL for pagination)
     var total int64
-    countDB := database.DB.Model(&models.Product{})
-    if searchQuery != "" {
-        searchTerm := "%" + normalizedQuery + "%"
-        countDB = countDB.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ?", 
-            searchTerm, searchTerm)
-    }
+    countDB := filter.apply(database.DB.Model(&models.Product{}), "")
     if err := countDB.Count(&total).Error; err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "error": "failed to count products",
         })
     }
 
-    // 3. PAGINATED DATA QUERY
+    // 3. PAGINATED DATA QUERY (search and filters applied)
     var products []ProductListResponse
-    db := database.DB.Model(&models.Product{}).
-        Select("id, title, description, image, price").
-        Offset((page - 1) * perPage).  // OFFSET
-        Limit(perPage)                 // LIMIT (10 items max!)
-
-    // APPLY SEARCH
-    if searchQuery != "" {
-        searchTerm := "%" + normalizedQuery + "%"
-        db = db.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ?", 
-            searchTerm, searchTerm)
-    }
-
-    // SORTING LOGIC
-    if searchQuery != "" {
-        // SEARCH → PRICE SORTING
-        if sortDir == "desc" {
-            db = db.Order("price DESC, id ASC")
-        } else {
-            db = db.Order("price ASC, id ASC")
+    relevanceColumn, relevanceVars := filter.search.relevanceColumn()
+    db := filter.apply(database.DB.Model(&models.Product{}), "").
+        Select(productListColumns + relevanceColumn, relevanceVars...)
+
+    if cursorMode {
+        // KEYSET: continue after the cursor; one extra row tells if there is more
+        if cursor != nil {
+            db = cursor.apply(db, sort, filter)
         }
-        log.Printf("PRICE SORT (search: %s)", searchQuery)
+        db = db.Limit(perPage + 1)
     } else {
-        // NO SEARCH → ID SORTING
-        if sortDir == "desc" {
-            db = db.Order("id DESC")
-        } else {
-            db = db.Order("id ASC")
-        }
-        log.Printf("ID SORT")
+        db = db.
+            Offset((page - 1) * perPage).  // OFFSET
+            Limit(perPage)                 // LIMIT (10 items max!)
     }
 
+    // SORTING LOGIC (sort_by/order, or legacy sort: ID for listings, price or relevance for searches)
+    db = sort.order(db, filter)
+    log.Printf("SORT %s (search: %s)", sort, searchQuery)
+
     if err := db.Find(&products).Error; err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
             "error": "failed to fetch products",
         })
     }
 
-    // 4. CALCULATE LAST PAGE
+    applyListPricing(products, filter.now)
+    applyListStock(products)
+    filter.search.applyRelevance(products)
+
+    nextCursor := ""
+    if cursorMode && len(products) > perPage {
+        products = products[:perPage]
+        nextCursor = nextProductCursor(sort, products[perPage-1], filter)
+    }
+    filter.search.applySnippets(products)
+
+    // FACET COUNTS for the filter sidebar
+    facets, err := filter.facets(database.DB)
+    if err != nil {
+        log.Printf("Failed to compute product facets: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to compute facets",
+        })
+    }
+
+    // 4. CALCULATE LAST PAGE (page mode only)
     lastPage := int(math.Ceil(float64(total) / float64(perPage)))
+    if cursorMode {
+        page, lastPage = 0, 0
+    }
 
     // 5. PERFECT RESPONSE
     response := ProductBackendResponse{
@@ -549,6 +809,12 @@ func ProductBackend(c *fiber.Ctx) error {
         Page:       page,
         PerPage:    perPage,
         LastPage:   lastPage,
+        NextCursor: nextCursor,
+        Facets:     facets,
+    }
+    if filter.search.capped() {
+        response.ResultsCapped = true
+        response.SearchMatches = filter.search.matches
     }
 
     // 6. CACHE FULL RESPONSE
@@ -565,6 +831,67 @@ func ProductBackend(c *fiber.Ctx) error {
 
 
 // Helper functions
+func toProductResponse(product *models.Product) ProductResponse {
+    response := ProductResponse{
+        ID:           product.ID,
+        Title:        product.Title,
+        Description:  product.Description,
+        Image:        product.Image,
+        Price:        product.Price,
+        Version:      product.Version,
+        ExternalSKU:  product.ExternalSKU,
+        SalePrice:    product.SalePrice,
+        SaleStartsAt: product.SaleStartsAt,
+        SaleEndsAt:   product.SaleEndsAt,
+        Stock:        product.Stock,
+        Reserved:     product.Reserved,
+    }
+    response.applyPricing(time.Now())
+    return response
+}
+
+// applyPricing fills the sale state, effective price and availability (of
+// the product and its variants) for now
+func (p *ProductResponse) applyPricing(now time.Time) {
+    p.OnSale = models.SaleActive(p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, now)
+    p.EffectivePrice = models.EffectivePrice(p.Price, p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, now)
+
+    if len(p.Variants) == 0 {
+        p.InStock = models.StockAvailable(p.Stock, p.Reserved)
+        return
+    }
+    product := models.Product{Price: p.Price, SalePrice: p.SalePrice, SaleStartsAt: p.SaleStartsAt, SaleEndsAt: p.SaleEndsAt}
+    p.InStock = false
+    for i := range p.Variants {
+        p.Variants[i].Apply(&product, now)
+        p.InStock = p.InStock || p.Variants[i].InStock
+    }
+}
+
+// applyListPricing fills the sale state and effective price of each listed
+// product. It also runs on cached pages, so the price follows sale boundaries.
+func applyListPricing(products []ProductListResponse, now time.Time) {
+    for i := range products {
+        p := &products[i]
+        p.OnSale = models.SaleActive(p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, now)
+        p.EffectivePrice = models.EffectivePrice(p.Price, p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, now)
+    }
+}
+
+// applyListStock fills availability from freshly loaded rows; cached pages
+// keep theirs until a stock change clears them
+func applyListStock(products []ProductListResponse) {
+    for i := range products {
+        p := &products[i]
+        p.HasVariants = p.VariantCount > 0
+        if p.HasVariants {
+            p.InStock = p.VariantsInStock > 0
+        } else {
+            p.InStock = models.StockAvailable(p.Stock, p.Reserved)
+        }
+    }
+}
+
 func atoi(s string) int {
     i, _ := strconv.Atoi(s)
     return i
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"ambassador/src/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Sort keys of ProductBackend (?sort_by=)
+const (
+    sortByID         = "id"
+    sortByPrice      = "price"      // effective price, sales included
+    sortByTitle      = "title"
+    sortByCreatedAt  = "created_at"
+    sortByPopularity = "popularity" // completed orders, from product_stats
+    sortByRevenue    = "revenue"    // sales revenue, from product_stats
+    sortByRelevance  = "relevance"  // best match first; searches only
+)
+
+// productSort is a sort key and direction. Every order ends on the product
+// ID ascending (or the ID itself), so keyset pagination has a unique position.
+type productSort struct {
+    By   string
+    Desc bool
+}
+
+func (s productSort) String() string {
+    if s.By == sortByRelevance {
+        return s.By
+    }
+    if s.Desc {
+        return s.By + "_desc"
+    }
+    return s.By + "_asc"
+}
+
+// resolveProductSort reads ?sort_by= and ?order=. Without sort_by the legacy
+// ?sort=asc|desc|relevance applies: listings by ID, searches by price.
+func resolveProductSort(c *fiber.Ctx, f productFilter) (productSort, *fiber.Error) {
+    sortBy := c.Query("sort_by")
+    if sortBy == "" {
+        sortDir := c.Query("sort", "asc")
+        switch {
+        case f.search.query == "":
+            return productSort{By: sortByID, Desc: sortDir == "desc"}, nil
+        case sortDir == "relevance":
+            return productSort{By: sortByRelevance, Desc: true}, nil
+        }
+        return productSort{By: sortByPrice, Desc: sortDir == "desc"}, nil
+    }
+
+    s := productSort{By: sortBy}
+    switch sortBy {
+    case sortByID, sortByPrice, sortByTitle, sortByCreatedAt:
+    case sortByPopularity, sortByRevenue:
+        s.Desc = true // best sellers first unless asked otherwise
+    case sortByRelevance:
+        if f.search.query == "" {
+            return s, fiber.NewError(fiber.StatusBadRequest, "sort_by=relevance requires a search term")
+        }
+        s.Desc = true
+        return s, nil
+    default:
+        return s, fiber.NewError(fiber.StatusBadRequest, "sort_by must be one of price, title, created_at, popularity, revenue, relevance")
+    }
+
+    switch c.Query("order") {
+    case "":
+    case "asc":
+        s.Desc = false
+    case "desc":
+        s.Desc = true
+    default:
+        return s, fiber.NewError(fiber.StatusBadRequest, "order must be asc or desc")
+    }
+    return s, nil
+}
+
+// key is the SQL of the sort key with its bound values
+func (s productSort) key(f productFilter) (string, []interface{}) {
+    switch s.By {
+    case sortByPrice:
+        return "(" + models.EffectivePriceSQL + ")", []interface{}{f.now, f.now}
+    case sortByTitle:
+        return "products.title", nil
+    case sortByCreatedAt:
+        return "products.created_at", nil
+    case sortByPopularity:
+        return "COALESCE(product_stats.order_count, 0)", nil
+    case sortByRevenue:
+        return "COALESCE(product_stats.revenue, 0)", nil
+    }
+    return "products.id", nil
+}
+
+func (s productSort) needsStats() bool {
+    return s.By == sortByPopularity || s.By == sortByRevenue
+}
+
+func (s productSort) direction() string {
+    if s.Desc {
+        return "DESC"
+    }
+    return "ASC"
+}
+
+// order adds the ORDER BY clause (and the stats join it needs)
+func (s productSort) order(db *gorm.DB, f productFilter) *gorm.DB {
+    if s.needsStats() {
+        db = db.Joins("LEFT JOIN product_stats ON product_stats.product_id = products.id")
+    }
+
+    switch s.By {
+    case sortByRelevance:
+        return f.search.relevanceOrder(db)
+    case sortByID:
+        return db.Order("id " + s.direction())
+    }
+
+    key, vars := s.key(f)
+    return db.Order(clauseExpr(key+" "+s.direction()+", products.id ASC", vars...))
+}
+
+// productCursor is the position after the last product of a page. Clients
+// get it base64-encoded and must treat it as opaque.
+type productCursor struct {
+    Sort  string  `json:"s"`
+    Value float64 `json:"v,omitempty"` // price, relevance score or rank
+    Text  string  `json:"t,omitempty"` // title
+    ID    uint    `json:"id"`
+}
+
+func (cur productCursor) encode() string {
+    data, _ := json.Marshal(cur)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeProductCursor parses a cursor and checks it belongs to sort
+func decodeProductCursor(raw string, sort productSort) (productCursor, error) {
+    var cur productCursor
+    data, err := base64.RawURLEncoding.DecodeString(raw)
+    if err != nil || json.Unmarshal(data, &cur) != nil || cur.ID == 0 {
+        return cur, errInvalidCursor
+    }
+    if cur.Sort != sort.String() {
+        return cur, errors.New("cursor was issued for a different sort order")
+    }
+    return cur, nil
+}
+
+// apply restricts a query to the products after the cursor
+func (cur productCursor) apply(db *gorm.DB, sort productSort, f productFilter) *gorm.DB {
+    if sort.By == sortByID {
+        if sort.Desc {
+            return db.Where("products.id < ?", cur.ID)
+        }
+        return db.Where("products.id > ?", cur.ID)
+    }
+    if sort.By == sortByRelevance {
+        return cur.applyRelevance(db, f.search)
+    }
+
+    // The cursor's key: carried in the cursor for price and title; looked up
+    // for the rest so sales figures never leave the server
+    var value string
+    var valueVars []interface{}
+    switch sort.By {
+    case sortByPrice:
+        value, valueVars = "?", []interface{}{cur.Value}
+    case sortByTitle:
+        value, valueVars = "?", []interface{}{cur.Text}
+    case sortByCreatedAt:
+        value, valueVars = "(SELECT created_at FROM products AS cursor_product WHERE cursor_product.id = ?)", []interface{}{cur.ID}
+    case sortByPopularity:
+        value, valueVars = "(SELECT COALESCE(MAX(order_count), 0) FROM product_stats AS cursor_stats WHERE cursor_stats.product_id = ?)", []interface{}{cur.ID}
+    case sortByRevenue:
+        value, valueVars = "(SELECT COALESCE(MAX(revenue), 0) FROM product_stats AS cursor_stats WHERE cursor_stats.product_id = ?)", []interface{}{cur.ID}
+    }
+
+    op := ">"
+    if sort.Desc {
+        op = "<"
+    }
+    key, keyVars := sort.key(f)
+
+    vars := append(append([]interface{}{}, keyVars...), valueVars...)
+    vars = append(append(vars, keyVars...), valueVars...)
+    vars = append(vars, cur.ID)
+    return db.Where(key+" "+op+" "+value+" OR ("+key+" = "+value+" AND products.id > ?)", vars...)
+}
+
+func (cur productCursor) applyRelevance(db *gorm.DB, s productSearch) *gorm.DB {
+    if s.memory {
+        // Value is the rank in the index's result list
+        rank := int(cur.Value)
+        if rank+1 >= len(s.ids) {
+            return db.Where("1 = 0")
+        }
+        return db.Where("products.id IN ?", s.ids[rank+1:])
+    }
+    if s.fulltext {
+        return db.Where(s.matchSQL()+" < ? OR ("+s.matchSQL()+" = ? AND products.id > ?)",
+            s.query, cur.Value, s.query, cur.Value, cur.ID)
+    }
+    // LIKE fallback ranks title matches (1) before description matches (0)
+    like := "%" + s.likeTerm() + "%"
+    return db.Where("(LOWER(products.title) LIKE ?) < ? OR ((LOWER(products.title) LIKE ?) = ? AND products.id > ?)",
+        like, cur.Value, like, cur.Value, cur.ID)
+}
+
+// nextProductCursor is the cursor after last, the final product of a page
+func nextProductCursor(sort productSort, last ProductListResponse, f productFilter) string {
+    cur := productCursor{Sort: sort.String(), ID: last.ID}
+    switch sort.By {
+    case sortByPrice:
+        cur.Value = last.EffectivePrice
+    case sortByTitle:
+        cur.Text = last.Title
+    case sortByRelevance:
+        s := f.search
+        switch {
+        case s.memory:
+            for rank, id := range s.ids {
+                if id == last.ID {
+                    cur.Value = float64(rank)
+                    break
+                }
+            }
+        case s.fulltext && last.Relevance != nil:
+            cur.Value = *last.Relevance
+        case !s.fulltext:
+            if s.titleMatches(last.Title) {
+                cur.Value = 1
+            }
+        }
+    }
+    return cur.encode()
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestProductCursorRoundTrip(t *testing.T) {
+    tests := []struct {
+        name string
+        sort productSort
+        cur  productCursor
+    }{
+        {"id ascending", productSort{By: sortByID}, productCursor{ID: 17}},
+        {"id descending", productSort{By: sortByID, Desc: true}, productCursor{ID: 17}},
+        {"price", productSort{By: sortByPrice}, productCursor{Value: 19.99, ID: 3}},
+        {"price descending", productSort{By: sortByPrice, Desc: true}, productCursor{Value: 0, ID: 3}},
+        {"title with unicode and quotes", productSort{By: sortByTitle}, productCursor{Text: `Café "Deluxe" mug`, ID: 9}},
+        {"created at", productSort{By: sortByCreatedAt, Desc: true}, productCursor{ID: 120}},
+        {"popularity", productSort{By: sortByPopularity, Desc: true}, productCursor{ID: 5}},
+        {"revenue", productSort{By: sortByRevenue}, productCursor{ID: 5}},
+        {"relevance", productSort{By: sortByRelevance, Desc: true}, productCursor{Value: 2.75, ID: 44}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tt.cur.Sort = tt.sort.String()
+            raw := tt.cur.encode()
+
+            got, err := decodeProductCursor(raw, tt.sort)
+            if err != nil {
+                t.Fatalf("decodeProductCursor(%q): %v", raw, err)
+            }
+            if got != tt.cur {
+                t.Errorf("decoded %+v, want %+v", got, tt.cur)
+            }
+        })
+    }
+}
+
+func TestDecodeProductCursorRejects(t *testing.T) {
+    priceAsc := productSort{By: sortByPrice}
+    encodeJSON := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+    tests := []struct {
+        name    string
+        raw     string
+        invalid bool // errInvalidCursor; otherwise the sort mismatch error
+    }{
+        {"empty", "", true},
+        {"not base64", "%%%", true},
+        {"not JSON", encodeJSON("hello"), true},
+        {"wrong types", encodeJSON(`{"s":"price_asc","v":"cheap","id":1}`), true},
+        {"no product ID", encodeJSON(`{"s":"price_asc","v":3}`), true},
+        {"other direction", productCursor{Sort: "price_desc", Value: 3, ID: 1}.encode(), false},
+        {"other sort key", productCursor{Sort: "title_asc", Text: "Mug", ID: 1}.encode(), false},
+        {"legacy cursor without a sort", encodeJSON(`{"v":3,"id":1}`), false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := decodeProductCursor(tt.raw, priceAsc)
+            if err == nil {
+                t.Fatalf("cursor %q was accepted", tt.raw)
+            }
+            if errors.Is(err, errInvalidCursor) != tt.invalid {
+                t.Errorf("err = %v, invalid cursor = %v, want %v", err, errors.Is(err, errInvalidCursor), tt.invalid)
+            }
+        })
+    }
+}
+
+func TestNextProductCursor(t *testing.T) {
+    score := 4.5
+    last := ProductListResponse{ID: 12, Title: "Blue Mug", EffectivePrice: 7.5, Relevance: &score}
+
+    tests := []struct {
+        name   string
+        sort   productSort
+        search productSearch
+        want   productCursor
+    }{
+        {"id", productSort{By: sortByID}, productSearch{}, productCursor{Sort: "id_asc", ID: 12}},
+        {"price carries the effective price", productSort{By: sortByPrice, Desc: true}, productSearch{}, productCursor{Sort: "price_desc", Value: 7.5, ID: 12}},
+        {"title carries the title", productSort{By: sortByTitle}, productSearch{}, productCursor{Sort: "title_asc", Text: "Blue Mug", ID: 12}},
+        {"created at carries only the ID", productSort{By: sortByCreatedAt}, productSearch{}, productCursor{Sort: "created_at_asc", ID: 12}},
+        {"revenue never leaves the server", productSort{By: sortByRevenue, Desc: true}, productSearch{}, productCursor{Sort: "revenue_desc", ID: 12}},
+        {"fulltext relevance carries the score", productSort{By: sortByRelevance, Desc: true}, productSearch{query: "mug", fulltext: true}, productCursor{Sort: "relevance", Value: 4.5, ID: 12}},
+        {"memory relevance carries the rank", productSort{By: sortByRelevance, Desc: true}, productSearch{memory: true, ids: []uint{3, 8, 12, 20}}, productCursor{Sort: "relevance", Value: 2, ID: 12}},
+        {"LIKE fallback: title match", productSort{By: sortByRelevance, Desc: true}, productSearch{query: "mug"}, productCursor{Sort: "relevance", Value: 1, ID: 12}},
+        {"LIKE fallback: description match", productSort{By: sortByRelevance, Desc: true}, productSearch{query: "ceramic"}, productCursor{Sort: "relevance", Value: 0, ID: 12}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            raw := nextProductCursor(tt.sort, last, productFilter{search: tt.search})
+            got, err := decodeProductCursor(raw, tt.sort)
+            if err != nil {
+                t.Fatalf("decodeProductCursor: %v", err)
+            }
+            if got != tt.want {
+                t.Errorf("cursor %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"ambassador/src/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Facet names, passed as skip to productFilter.apply so a facet's counts
+// ignore its own filter (picking one category still shows the others)
+const (
+    facetCategory = "category"
+    facetTag      = "tag"
+    facetPrice    = "price"
+)
+
+// productFilter holds the catalog filters of ProductBackend
+type productFilter struct {
+    search      productSearch
+    category    string   // slug
+    categoryIDs []uint   // category and its descendants
+    tags        []string // slugs; products must carry all of them
+    minPrice    *float64 // effective price, inclusive
+    maxPrice    *float64
+    now         time.Time
+}
+
+type CategoryFacet struct {
+    ID       uint   `json:"id"`
+    Name     string `json:"name"`
+    Slug     string `json:"slug"`
+    ParentID *uint  `json:"parent_id"`
+    Count    int64  `json:"count"` // includes products in subcategories
+}
+
+type TagFacet struct {
+    ID    uint   `json:"id"`
+    Name  string `json:"name"`
+    Slug  string `json:"slug"`
+    Count int64  `json:"count"`
+}
+
+type PriceFacet struct {
+    Min *float64 `json:"min"`
+    Max *float64 `json:"max"`
+}
+
+type ProductFacets struct {
+    Categories []CategoryFacet `json:"categories"`
+    Tags       []TagFacet      `json:"tags"`
+    Price      PriceFacet      `json:"price"`
+}
+
+// parseProductFilter reads s, mode, category, tag (comma-separated), min_price and max_price
+func parseProductFilter(c *fiber.Ctx, db *gorm.DB) (productFilter, *fiber.Error) {
+    f := productFilter{
+        category: strings.ToLower(strings.TrimSpace(c.Query("category"))),
+        now:      time.Now(),
+    }
+
+    var err *fiber.Error
+    if f.search, err = parseProductSearch(c.Query("s", ""), c.Query("mode")); err != nil {
+        return f, err
+    }
+
+    for _, tag := range strings.Split(c.Query("tag"), ",") {
+        if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+            f.tags = append(f.tags, tag)
+        }
+    }
+    sort.Strings(f.tags) // stable cache keys
+
+    if f.minPrice, err = parsePriceParam(c, "min_price"); err != nil {
+        return f, err
+    }
+    if f.maxPrice, err = parsePriceParam(c, "max_price"); err != nil {
+        return f, err
+    }
+    if f.minPrice != nil && f.maxPrice != nil && *f.minPrice > *f.maxPrice {
+        return f, fiber.NewError(fiber.StatusBadRequest, "min_price must not exceed max_price")
+    }
+
+    if f.category != "" {
+        var category models.Category
+        if err := db.Where("slug = ?", f.category).First(&category).Error; err != nil {
+            return f, fiber.NewError(fiber.StatusBadRequest, "unknown category")
+        }
+        ids, err := models.CategoryDescendantIDs(db, category.ID)
+        if err != nil {
+            return f, fiber.NewError(fiber.StatusInternalServerError, "failed to load categories")
+        }
+        f.categoryIDs = ids
+    }
+
+    return f, nil
+}
+
+func parsePriceParam(c *fiber.Ctx, name string) (*float64, *fiber.Error) {
+    raw := strings.TrimSpace(c.Query(name))
+    if raw == "" {
+        return nil, nil
+    }
+    value, err := strconv.ParseFloat(raw, 64)
+    if err != nil || value < 0 {
+        return nil, fiber.NewError(fiber.StatusBadRequest, name+" must be a non-negative number")
+    }
+    return &value, nil
+}
+
+// cacheKey describes the filters for paginated cache keys
+func (f productFilter) cacheKey() string {
+    price := func(p *float64) string {
+        if p == nil {
+            return ""
+        }
+        return strconv.FormatFloat(*p, 'f', 2, 64)
+    }
+    return fmt.Sprintf("s:%s:m:%s:c:%s:t:%s:min:%s:max:%s",
+        strings.ToLower(f.search.query), f.search.mode, f.category, strings.Join(f.tags, ","), price(f.minPrice), price(f.maxPrice))
+}
+
+// apply adds every filter except the one named by skip to a products query
+func (f productFilter) apply(db *gorm.DB, skip string) *gorm.DB {
+    db = f.search.apply(db)
+
+    if skip != facetCategory && len(f.categoryIDs) > 0 {
+        db = db.Where("products.id IN (SELECT product_id FROM product_categories WHERE category_id IN ?)", f.categoryIDs)
+    }
+
+    if skip != facetTag && len(f.tags) > 0 {
+        db = db.Where("products.id IN (SELECT pt.product_id FROM product_tags pt"+
+            " JOIN tags ON tags.id = pt.tag_id WHERE tags.slug IN ?"+
+            " GROUP BY pt.product_id HAVING COUNT(DISTINCT tags.id) = ?)", f.tags, len(f.tags))
+    }
+
+    if skip != facetPrice {
+        if f.minPrice != nil {
+            db = db.Where(models.EffectivePriceSQL+" >= ?", f.now, f.now, *f.minPrice)
+        }
+        if f.maxPrice != nil {
+            db = db.Where(models.EffectivePriceSQL+" <= ?", f.now, f.now, *f.maxPrice)
+        }
+    }
+
+    return db
+}
+
+// facets counts matching products per category, per tag and the effective
+// price range, each ignoring its own filter
+func (f productFilter) facets(db *gorm.DB) (*ProductFacets, error) {
+    facets := &ProductFacets{
+        Categories: []CategoryFacet{},
+        Tags:       []TagFacet{},
+    }
+
+    // Categories: roll product sets up the tree so parents count distinct products
+    var pairs []struct {
+        CategoryID uint
+        ProductID  uint
+    }
+    inCategories := f.apply(db.Model(&models.Product{}).Select("products.id"), facetCategory)
+    if err := db.Table("product_categories").
+        Select("category_id, product_id").
+        Where("product_id IN (?)", inCategories).
+        Scan(&pairs).Error; err != nil {
+        return nil, err
+    }
+
+    var categories []models.Category
+    if err := db.Order("name").Find(&categories).Error; err != nil {
+        return nil, err
+    }
+    parents := make(map[uint]*uint, len(categories))
+    for _, category := range categories {
+        parents[category.ID] = category.ParentID
+    }
+
+    products := make(map[uint]map[uint]bool)
+    for _, pair := range pairs {
+        // Walk to the root; the depth guard stops on a corrupt cycle
+        id := &pair.CategoryID
+        for depth := 0; id != nil && depth < len(categories); depth++ {
+            if products[*id] == nil {
+                products[*id] = make(map[uint]bool)
+            }
+            products[*id][pair.ProductID] = true
+            id = parents[*id]
+        }
+    }
+    for _, category := range categories {
+        if count := len(products[category.ID]); count > 0 {
+            facets.Categories = append(facets.Categories, CategoryFacet{
+                ID:       category.ID,
+                Name:     category.Name,
+                Slug:     category.Slug,
+                ParentID: category.ParentID,
+                Count:    int64(count),
+            })
+        }
+    }
+
+    // Tags
+    inTags := f.apply(db.Model(&models.Product{}).Select("products.id"), facetTag)
+    if err := db.Table("product_tags").
+        Select("tags.id, tags.name, tags.slug, COUNT(DISTINCT product_tags.product_id) AS count").
+        Joins("JOIN tags ON tags.id = product_tags.tag_id").
+        Where("product_tags.product_id IN (?)", inTags).
+        Group("tags.id, tags.name, tags.slug").
+        Order("count DESC, tags.name").
+        Scan(&facets.Tags).Error; err != nil {
+        return nil, err
+    }
+
+    // Effective price range
+    priceSQL := fmt.Sprintf("MIN(%s) AS min, MAX(%s) AS max", models.EffectivePriceSQL, models.EffectivePriceSQL)
+    if err := f.apply(db.Model(&models.Product{}), facetPrice).
+        Select(priceSQL, f.now, f.now, f.now, f.now).
+        Scan(&facets.Price).Error; err != nil {
+        return nil, err
+    }
+
+    return facets, nil
+}
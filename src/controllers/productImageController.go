@@ -0,0 +1,498 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/imaging"
+	"ambassador/src/models"
+	"ambassador/src/storage"
+	"ambassador/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxProductImages caps the images of one product
+const maxProductImages = 20
+
+// primaryImageSize is the rendition mirrored into Product.Image
+const primaryImageSize = "large"
+
+type ProductImageOrderRequest struct {
+    ImageIDs []uint `json:"image_ids" validate:"required"` // every image of the product, in the new order
+}
+
+type ImageRenditionResponse struct {
+    URL    string `json:"url"`
+    Width  int    `json:"width"`
+    Height int    `json:"height"`
+}
+
+type ProductImageResponse struct {
+    ID         uint                              `json:"id"`
+    Position   int                               `json:"position"`
+    Width      int                               `json:"width"`
+    Height     int                               `json:"height"`
+    Renditions map[string]ImageRenditionResponse `json:"renditions"` // original, thumb, small, medium, large
+}
+
+func toProductImageResponse(img *models.ProductImage) ProductImageResponse {
+    response := ProductImageResponse{
+        ID:         img.ID,
+        Position:   img.Position,
+        Width:      img.Width,
+        Height:     img.Height,
+        Renditions: make(map[string]ImageRenditionResponse, len(img.Renditions)),
+    }
+    for name, rendition := range img.Renditions {
+        response.Renditions[name] = ImageRenditionResponse{
+            URL:    storage.Default.URL(rendition.Key),
+            Width:  rendition.Width,
+            Height: rendition.Height,
+        }
+    }
+    return response
+}
+
+// loadProductImages returns a product's images in display order
+func loadProductImages(db *gorm.DB, productID uint) ([]ProductImageResponse, error) {
+    var images []models.ProductImage
+    if err := db.Where("product_id = ?", productID).Order("position, id").Find(&images).Error; err != nil {
+        return nil, err
+    }
+
+    responses := make([]ProductImageResponse, len(images))
+    for i := range images {
+        responses[i] = toProductImageResponse(&images[i])
+    }
+    return responses, nil
+}
+
+// ProductImages lists a product's images in display order
+// GET /api/admin/products/:id/images, GET /api/ambassador/products/:id/images
+func ProductImages(c *fiber.Ctx) error {
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    images, err := loadProductImages(database.DB.WithContext(c.Context()), product.ID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch images",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "data": images,
+    })
+}
+
+// UploadProductImages adds images to the end of a product's gallery. The
+// multipart "images" (or "image") files are checked by their magic bytes,
+// re-encoded and stored with a thumbnail in every size.
+// POST /api/admin/products/:id/images
+func UploadProductImages(c *fiber.Ctx) error {
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    form, err := c.MultipartForm()
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "expected a multipart/form-data body with image files",
+        })
+    }
+    files := append(form.File["images"], form.File["image"]...)
+    if len(files) == 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "no files in the images field",
+        })
+    }
+
+    // Process everything before storing anything, so one bad file rejects the upload
+    cfg := config.Get()
+    processed := make([][]imaging.Rendition, len(files))
+    for i, file := range files {
+        renditions, status, err := processUpload(file, cfg)
+        if err != nil {
+            return c.Status(status).JSON(fiber.Map{
+                "error": fmt.Sprintf("%s: %v", file.Filename, err),
+            })
+        }
+        processed[i] = renditions
+    }
+
+    ctx := c.Context()
+    images := make([]models.ProductImage, 0, len(processed))
+    var stored []string
+    for _, renditions := range processed {
+        image, keys, err := storeImage(ctx, product.ID, renditions)
+        stored = append(stored, keys...)
+        if err != nil {
+            log.Printf("Failed to store image of product %d: %v", product.ID, err)
+            deleteStoredKeys(ctx, stored)
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "failed to store image",
+            })
+        }
+        images = append(images, image)
+    }
+
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        // The product row lock serializes the image count and position assignment
+        if _, err := models.LockProducts(tx, []uint{product.ID}); err != nil {
+            return err
+        }
+        var existing int64
+        if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).Count(&existing).Error; err != nil {
+            return err
+        }
+        if int(existing)+len(images) > maxProductImages {
+            return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("a product can have at most %d images", maxProductImages))
+        }
+        var last int
+        if err := tx.Model(&models.ProductImage{}).
+            Where("product_id = ?", product.ID).
+            Select("COALESCE(MAX(position), -1)").
+            Scan(&last).Error; err != nil {
+            return err
+        }
+        for i := range images {
+            images[i].Position = last + 1 + i
+        }
+        return tx.Create(&images).Error
+    })
+    if err != nil {
+        deleteStoredKeys(ctx, stored)
+        var ferr *fiber.Error
+        if errors.As(err, &ferr) {
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
+        log.Printf("Failed to save images of product %d: %v", product.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to save images",
+        })
+    }
+
+    syncPrimaryImage(c, product.ID)
+    database.ClearProductCaches(ctx, product.ID)
+
+    responses := make([]ProductImageResponse, len(images))
+    for i := range images {
+        responses[i] = toProductImageResponse(&images[i])
+    }
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.images_upload",
+        TargetType: "product",
+        TargetID:   product.ID,
+        After:      responses,
+    })
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "images uploaded successfully",
+        "data":    responses,
+    })
+}
+
+// ReorderProductImages sets the display order; the first image becomes the
+// product's main image
+// PUT /api/admin/products/:id/images/order
+func ReorderProductImages(c *fiber.Ctx) error {
+    var data ProductImageOrderRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var before []models.ProductImage
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if _, err := models.LockProducts(tx, []uint{product.ID}); err != nil {
+            return err
+        }
+        if err := tx.Where("product_id = ?", product.ID).Order("position, id").Find(&before).Error; err != nil {
+            return err
+        }
+
+        current := make(map[uint]bool, len(before))
+        for _, img := range before {
+            current[img.ID] = true
+        }
+        if len(data.ImageIDs) != len(before) {
+            return fiber.NewError(fiber.StatusBadRequest, "image_ids must list every image of the product exactly once")
+        }
+        for position, id := range data.ImageIDs {
+            if !current[id] {
+                return fiber.NewError(fiber.StatusBadRequest, "image_ids must list every image of the product exactly once")
+            }
+            delete(current, id)
+            if err := tx.Model(&models.ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        var ferr *fiber.Error
+        if errors.As(err, &ferr) {
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
+        log.Printf("Failed to reorder images of product %d: %v", product.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to reorder images",
+        })
+    }
+
+    syncPrimaryImage(c, product.ID)
+    database.ClearProductCaches(c.Context(), product.ID)
+
+    images, _ := loadProductImages(database.DB, product.ID)
+    beforeIDs := make([]uint, len(before))
+    for i, img := range before {
+        beforeIDs[i] = img.ID
+    }
+    audit.Record(c, audit.Entry{
+        Action:     "product.images_reorder",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     fiber.Map{"image_ids": beforeIDs},
+        After:      fiber.Map{"image_ids": data.ImageIDs},
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "images reordered successfully",
+        "data":    images,
+    })
+}
+
+// DeleteProductImage removes an image and its stored files
+// DELETE /api/admin/products/:id/images/:imageId
+func DeleteProductImage(c *fiber.Ctx) error {
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    imageID, err := strconv.Atoi(c.Params("imageId"))
+    if err != nil || imageID <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid image ID",
+        })
+    }
+
+    var image models.ProductImage
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if _, err := models.LockProducts(tx, []uint{product.ID}); err != nil {
+            return err
+        }
+        if err := tx.Where("id = ? AND product_id = ?", imageID, product.ID).First(&image).Error; err != nil {
+            return err
+        }
+        if err := tx.Delete(&image).Error; err != nil {
+            return err
+        }
+        // Close the gap so positions stay 0..n-1
+        return tx.Model(&models.ProductImage{}).
+            Where("product_id = ? AND position > ?", product.ID, image.Position).
+            UpdateColumn("position", gorm.Expr("position - 1")).Error
+    })
+    if err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+                "error": "image not found",
+            })
+        }
+        log.Printf("Failed to delete image %d of product %d: %v", imageID, product.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to delete image",
+        })
+    }
+
+    // Files go after the row; a failure only leaves unreferenced files behind
+    deleteStoredKeys(c.Context(), image.Keys())
+    syncPrimaryImage(c, product.ID)
+    database.ClearProductCaches(c.Context(), product.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.image_delete",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     toProductImageResponse(&image),
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "image deleted successfully",
+    })
+}
+
+// processUpload reads and converts one uploaded file; the status code
+// describes why a file was rejected
+func processUpload(file *multipart.FileHeader, cfg *config.Config) ([]imaging.Rendition, int, error) {
+    limit := int64(cfg.ImageMaxUploadMB) << 20
+    if file.Size > limit {
+        return nil, fiber.StatusRequestEntityTooLarge, fmt.Errorf("file exceeds %d MB", cfg.ImageMaxUploadMB)
+    }
+
+    f, err := file.Open()
+    if err != nil {
+        return nil, fiber.StatusBadRequest, errors.New("unreadable file")
+    }
+    defer f.Close()
+
+    data, err := io.ReadAll(io.LimitReader(f, limit+1))
+    if err != nil {
+        return nil, fiber.StatusBadRequest, errors.New("unreadable file")
+    }
+    if int64(len(data)) > limit {
+        return nil, fiber.StatusRequestEntityTooLarge, fmt.Errorf("file exceeds %d MB", cfg.ImageMaxUploadMB)
+    }
+
+    renditions, err := imaging.Process(data, cfg.ImageMaxPixels)
+    switch {
+    case errors.Is(err, imaging.ErrUnsupported):
+        return nil, fiber.StatusUnsupportedMediaType, err
+    case err != nil:
+        return nil, fiber.StatusUnprocessableEntity, err
+    }
+    return renditions, 0, nil
+}
+
+// storeImage writes the renditions under a fresh random prefix and returns
+// the image row to save with the keys written so far
+func storeImage(ctx context.Context, productID uint, renditions []imaging.Rendition) (models.ProductImage, []string, error) {
+    image := models.ProductImage{ProductID: productID, Renditions: models.ImageRenditions{}}
+
+    token, err := utils.GenerateToken(9)
+    if err != nil {
+        return image, nil, err
+    }
+
+    var keys []string
+    for _, rendition := range renditions {
+        key := fmt.Sprintf("products/%d/%s/%s.%s", productID, token, rendition.Name, rendition.Format.Ext())
+        if err := storage.Default.Put(ctx, key, rendition.Data, rendition.Format.ContentType()); err != nil {
+            return image, keys, err
+        }
+        keys = append(keys, key)
+
+        image.Renditions[rendition.Name] = models.ImageRendition{
+            Key:         key,
+            Width:       rendition.Width,
+            Height:      rendition.Height,
+            ContentType: rendition.Format.ContentType(),
+            Size:        len(rendition.Data),
+        }
+        if rendition.Name == imaging.Original {
+            image.Width, image.Height = rendition.Width, rendition.Height
+        }
+    }
+    return image, keys, nil
+}
+
+func deleteStoredKeys(ctx context.Context, keys []string) {
+    for _, key := range keys {
+        if err := storage.Default.Delete(ctx, key); err != nil {
+            log.Printf("Failed to delete stored file %s: %v", key, err)
+        }
+    }
+}
+
+// syncPrimaryImage points Product.Image at the first gallery image, as a
+// new product version. Products without uploads keep their image URL; once
+// the last upload is deleted the product gets back the last image URL it had
+// from elsewhere, or none.
+func syncPrimaryImage(c *fiber.Ctx, productID uint) {
+    actorID := actorIDPtr(c)
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        var product models.Product
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+            return err
+        }
+        url, err := primaryImageURL(tx, &product)
+        if err != nil {
+            return err
+        }
+        if product.Image == url {
+            return nil
+        }
+        if product.Version == 0 {
+            if _, err := models.RecordProductVersion(tx, &product, models.ProductChangeBaseline, nil, nil); err != nil {
+                return err
+            }
+        }
+        if err := tx.Model(&models.Product{}).Where("id = ?", productID).Update("image", url).Error; err != nil {
+            return err
+        }
+        product.Image = url
+        _, err = models.RecordProductVersion(tx, &product, models.ProductChangeUpdate, actorID, nil)
+        return err
+    })
+    if err != nil {
+        log.Printf("Failed to update main image of product %d: %v", productID, err)
+    }
+}
+
+// primaryImageURL returns the image URL a product should show given its
+// gallery; call it with the product row locked
+func primaryImageURL(tx *gorm.DB, product *models.Product) (string, error) {
+    var primary []models.ProductImage
+    if err := tx.Where("product_id = ?", product.ID).Order("position, id").Limit(1).Find(&primary).Error; err != nil {
+        return "", err
+    }
+    if len(primary) > 0 {
+        rendition, ok := primary[0].Renditions[primaryImageSize]
+        if !ok {
+            return product.Image, nil
+        }
+        return storage.Default.URL(rendition.Key), nil
+    }
+
+    uploads := storage.Default.URL(fmt.Sprintf("products/%d/", product.ID))
+    if !strings.HasPrefix(product.Image, uploads) {
+        return product.Image, nil
+    }
+
+    // The gallery is empty but the product still shows an upload
+    var versions []models.ProductVersion
+    if err := tx.Select("image").
+        Where("product_id = ? AND image NOT LIKE ?", product.ID, likeEscaper.Replace(uploads)+"%").
+        Order("version DESC").
+        Limit(1).
+        Find(&versions).Error; err != nil {
+        return "", err
+    }
+    if len(versions) == 0 {
+        return "", nil
+    }
+    return versions[0].Image, nil
+}
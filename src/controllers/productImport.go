@@ -0,0 +1,518 @@
+package controllers
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"ambassador/src/search"
+	"ambassador/src/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+    maxImportRows = 10000
+
+    // maxPendingImports caps the jobs (queued or running) one instance holds
+    // in memory
+    maxPendingImports = 5
+
+    // importProgressEvery is how often (in rows) a running job saves its progress
+    importProgressEvery = 100
+)
+
+// Columns (CSV) and keys (JSON lines) an import row may have
+var importFields = map[string]bool{
+    "external_sku": true,
+    "title":        true,
+    "description":  true,
+    "image":        true,
+    "price":        true,
+    "stock":        true,
+}
+
+var requiredImportFields = []string{"title", "description", "image", "price"}
+
+// errImportDryRun rolls back a dry-run row once it has been fully applied
+var errImportDryRun = errors.New("dry run")
+
+// importSlot lets one import write at a time; later jobs wait queued
+var importSlot = make(chan struct{}, 1)
+
+// importPending holds a token for every job this instance has accepted and
+// not finished; ImportProducts turns jobs away when it is full
+var importPending = make(chan struct{}, maxPendingImports)
+
+// importOwner identifies this instance on the jobs it runs
+var importOwner = newImportOwner()
+
+func newImportOwner() string {
+    host, _ := os.Hostname()
+    token, err := utils.GenerateToken(6)
+    if err != nil {
+        token = strconv.FormatInt(time.Now().UnixNano(), 36)
+    }
+    if len(host) > 90 {
+        host = host[:90]
+    }
+    return host + "/" + token
+}
+
+// importRow is one parsed data row. HasStock says whether the row sets stock
+// at all: updates keep the current stock when the column is left out.
+type importRow struct {
+    Number    int
+    Request   CreateProductRequest
+    HasStock  bool
+    Malformed bool     // the row couldn't be read at all
+    Problems  []string // parse errors, found before validation
+}
+
+// rowProblem is a row-level failure found while applying a row
+type rowProblem string
+
+func (p rowProblem) Error() string { return string(p) }
+
+// parseImport splits an import file into rows. Errors are about the file as a
+// whole; problems with single rows are kept on the row for the report.
+func parseImport(format string, data []byte) ([]importRow, error) {
+    data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+    var rows []importRow
+    var err error
+    switch format {
+    case "csv":
+        rows, err = parseImportCSV(data)
+    case "jsonl":
+        rows, err = parseImportJSONL(data)
+    default:
+        return nil, fmt.Errorf("unsupported format %q", format)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if len(rows) == 0 {
+        return nil, errors.New("the file has no data rows")
+    }
+    return rows, nil
+}
+
+// parseImportCSV reads a CSV file with a header row naming the columns
+func parseImportCSV(data []byte) ([]importRow, error) {
+    reader := csv.NewReader(bytes.NewReader(data))
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err == io.EOF {
+        return nil, errors.New("the file is empty")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("invalid CSV header: %w", err)
+    }
+
+    columns := make(map[string]int, len(header))
+    for i, name := range header {
+        name = strings.ToLower(strings.TrimSpace(name))
+        if !importFields[name] {
+            return nil, fmt.Errorf("unknown column %q", name)
+        }
+        if _, dup := columns[name]; dup {
+            return nil, fmt.Errorf("duplicate column %q", name)
+        }
+        columns[name] = i
+    }
+    for _, name := range requiredImportFields {
+        if _, ok := columns[name]; !ok {
+            return nil, fmt.Errorf("missing required column %q", name)
+        }
+    }
+    stockColumn, hasStock := columns["stock"]
+
+    field := func(record []string, name string) string {
+        if i, ok := columns[name]; ok {
+            return record[i]
+        }
+        return ""
+    }
+
+    var rows []importRow
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("invalid CSV: %w", err)
+        }
+        if len(rows) == maxImportRows {
+            return nil, fmt.Errorf("too many rows (max %d)", maxImportRows)
+        }
+
+        row := importRow{Number: len(rows) + 1, HasStock: hasStock}
+        if len(record) != len(header) {
+            row.Malformed = true
+            row.Problems = []string{fmt.Sprintf("expected %d fields, got %d", len(header), len(record))}
+            rows = append(rows, row)
+            continue
+        }
+
+        row.Request = CreateProductRequest{
+            Title:       field(record, "title"),
+            Description: field(record, "description"),
+            Image:       field(record, "image"),
+            ExternalSKU: field(record, "external_sku"),
+        }
+        if price, err := strconv.ParseFloat(strings.TrimSpace(field(record, "price")), 64); err == nil {
+            row.Request.Price = price
+        } else {
+            row.Problems = append(row.Problems, "price must be a number")
+        }
+        // An empty stock cell means stock is not tracked
+        if hasStock {
+            if cell := strings.TrimSpace(record[stockColumn]); cell != "" {
+                if stock, err := strconv.Atoi(cell); err == nil {
+                    row.Request.Stock = &stock
+                } else {
+                    row.Problems = append(row.Problems, "stock must be a whole number")
+                }
+            }
+        }
+        rows = append(rows, row)
+    }
+    return rows, nil
+}
+
+// parseImportJSONL reads one JSON object per line; blank lines are skipped
+func parseImportJSONL(data []byte) ([]importRow, error) {
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+    var rows []importRow
+    for scanner.Scan() {
+        line := bytes.TrimSpace(scanner.Bytes())
+        if len(line) == 0 {
+            continue
+        }
+        if len(rows) == maxImportRows {
+            return nil, fmt.Errorf("too many rows (max %d)", maxImportRows)
+        }
+
+        row := importRow{Number: len(rows) + 1}
+        var raw map[string]json.RawMessage
+        if err := json.Unmarshal(line, &raw); err != nil {
+            row.Malformed = true
+            row.Problems = []string{"invalid JSON object"}
+            rows = append(rows, row)
+            continue
+        }
+        for key := range raw {
+            if !importFields[key] {
+                row.Problems = append(row.Problems, fmt.Sprintf("unknown field %q", key))
+            }
+        }
+        if err := json.Unmarshal(line, &row.Request); err != nil {
+            row.Problems = append(row.Problems, jsonFieldError(err))
+        }
+        _, row.HasStock = raw["stock"]
+        rows = append(rows, row)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("invalid JSON lines: %w", err)
+    }
+    return rows, nil
+}
+
+func jsonFieldError(err error) string {
+    var typeErr *json.UnmarshalTypeError
+    if errors.As(err, &typeErr) && typeErr.Field != "" {
+        return typeErr.Field + " has the wrong type"
+    }
+    return "invalid JSON object"
+}
+
+// runProductImport applies the rows of a queued job and stores its report.
+// Caches are cleared once, after the last row.
+func runProductImport(job *models.ProductImport, rows []importRow) {
+    defer func() { <-importPending }()
+
+    db := database.DB
+    stopHeartbeat := startImportHeartbeat(db, job.ID)
+    defer stopHeartbeat()
+
+    importSlot <- struct{}{}
+    defer func() { <-importSlot }()
+    defer func() {
+        if r := recover(); r != nil {
+            log.Printf("Product import %d panicked: %v", job.ID, r)
+            now := time.Now()
+            db.Model(job).Updates(map[string]interface{}{
+                "status":      models.ImportFailed,
+                "error":       "internal error",
+                "finished_at": now,
+            })
+        }
+    }()
+
+    started := time.Now()
+    job.Status = models.ImportRunning
+    job.StartedAt = &started
+    if err := db.Model(job).Select("status", "started_at").Updates(job).Error; err != nil {
+        log.Printf("Product import %d could not start: %v", job.ID, err)
+        return
+    }
+
+    report := make([]models.ImportRowResult, 0, len(rows))
+    seen := make(map[string]int, len(rows)) // external SKU -> first row using it
+    var touched []uint
+
+    for i, row := range rows {
+        result, product := importProductRow(db, row, job.DryRun, job.CreatedByID, seen)
+        report = append(report, result)
+
+        switch result.Action {
+        case models.ImportActionCreate:
+            job.Created++
+        case models.ImportActionUpdate:
+            job.Updated++
+        case models.ImportActionUnchanged:
+            job.Unchanged++
+        default:
+            job.Failed++
+        }
+        if product != nil && !job.DryRun {
+            touched = append(touched, product.ID)
+            search.IndexProduct(product)
+        }
+
+        job.Processed = i + 1
+        if job.Processed%importProgressEvery == 0 {
+            db.Model(job).Select("processed", "created", "updated", "unchanged", "failed").Updates(job)
+        }
+    }
+
+    if len(touched) > 0 {
+        if err := database.ClearProductCachesSync(context.Background(), touched...); err != nil {
+            log.Printf("Product import %d: cache invalidation failed: %v", job.ID, err)
+        }
+    }
+
+    finished := time.Now()
+    job.Status = models.ImportCompleted
+    job.FinishedAt = &finished
+    job.Report = report
+    if err := db.Model(job).
+        Select("status", "finished_at", "processed", "created", "updated", "unchanged", "failed", "report").
+        Updates(job).Error; err != nil {
+        log.Printf("Product import %d: failed to save the report: %v", job.ID, err)
+        return
+    }
+
+    log.Printf("Product import %d done in %v (dry run: %v): %d created, %d updated, %d unchanged, %d failed",
+        job.ID, finished.Sub(started), job.DryRun, job.Created, job.Updated, job.Unchanged, job.Failed)
+}
+
+// startImportHeartbeat keeps a job's heartbeat fresh until the returned func
+// is called, so other instances don't take it for abandoned
+func startImportHeartbeat(db *gorm.DB, id uint) func() {
+    done := make(chan struct{})
+    go func() {
+        ticker := time.NewTicker(models.ImportHeartbeatInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-done:
+                return
+            case now := <-ticker.C:
+                if err := models.TouchImport(db, id, now); err != nil {
+                    log.Printf("Product import %d: heartbeat failed: %v", id, err)
+                }
+            }
+        }
+    }()
+    return func() { close(done) }
+}
+
+// importProductRow validates and applies one row in its own transaction; a
+// dry run goes through the same steps and rolls back. The product is
+// returned when the row changed one.
+func importProductRow(db *gorm.DB, row importRow, dryRun bool, actorID *uint, seen map[string]int) (models.ImportRowResult, *models.Product) {
+    data := row.Request
+    data.normalize()
+
+    result := models.ImportRowResult{Row: row.Number, ExternalSKU: data.ExternalSKU}
+    fail := func(problems ...string) (models.ImportRowResult, *models.Product) {
+        result.Action = models.ImportActionError
+        result.Errors = problems
+        return result, nil
+    }
+
+    problems := rowProblems(row, &data)
+    if data.ExternalSKU != "" {
+        if first, dup := seen[data.ExternalSKU]; dup {
+            problems = append(problems, fmt.Sprintf("external_sku already used by row %d", first))
+        } else {
+            seen[data.ExternalSKU] = row.Number
+        }
+    }
+    if len(problems) > 0 {
+        return fail(problems...)
+    }
+
+    var product models.Product
+    err := db.Transaction(func(tx *gorm.DB) error {
+        var existing models.Product
+        if data.ExternalSKU != "" {
+            if err := tx.Unscoped().
+                Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("external_sku = ?", data.ExternalSKU).
+                Limit(1).
+                Find(&existing).Error; err != nil {
+                return err
+            }
+        }
+
+        if existing.ID == 0 {
+            result.Action = models.ImportActionCreate
+            product = models.Product{
+                Title:       data.Title,
+                Description: data.Description,
+                Image:       data.Image,
+                Price:       data.Price,
+                Stock:       data.Stock,
+            }
+            if data.ExternalSKU != "" {
+                product.ExternalSKU = &data.ExternalSKU
+            }
+            if err := tx.Create(&product).Error; err != nil {
+                return err
+            }
+            if _, err := models.RecordProductVersion(tx, &product, models.ProductChangeCreate, actorID, nil); err != nil {
+                return err
+            }
+        } else {
+            if existing.DeletedAt.Valid {
+                return rowProblem("external_sku belongs to a deleted product")
+            }
+            product = existing
+            result.ProductID = &product.ID
+
+            updates := importUpdates(&product, data, row.HasStock)
+            if len(updates) == 0 {
+                result.Action = models.ImportActionUnchanged
+                return nil
+            }
+            result.Action = models.ImportActionUpdate
+
+            // Same rules as UpdateProduct and SetProductStock
+            if product.SalePrice != nil && product.Price <= *product.SalePrice {
+                return rowProblem("price must be higher than the product's sale price")
+            }
+            if product.Stock != nil && *product.Stock < product.Reserved {
+                return rowProblem("stock cannot be lower than the units reserved by pending checkouts")
+            }
+
+            if existing.Version == 0 {
+                if _, err := models.RecordProductVersion(tx, &existing, models.ProductChangeBaseline, nil, nil); err != nil {
+                    return err
+                }
+            }
+            if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(updates).Error; err != nil {
+                return err
+            }
+            if _, err := models.RecordProductVersion(tx, &product, models.ProductChangeUpdate, actorID, nil); err != nil {
+                return err
+            }
+        }
+
+        if dryRun {
+            return errImportDryRun
+        }
+        return nil
+    })
+
+    var problem rowProblem
+    switch {
+    case errors.As(err, &problem):
+        return fail(string(problem))
+    case err != nil && !errors.Is(err, errImportDryRun):
+        log.Printf("Product import row %d failed: %v", row.Number, err)
+        return fail("failed to save product")
+    }
+
+    if result.Action == models.ImportActionCreate && !dryRun {
+        result.ProductID = &product.ID
+    }
+    if dryRun || result.Action == models.ImportActionUnchanged {
+        return result, nil
+    }
+    return result, &product
+}
+
+// rowProblems merges a row's parse errors with its validation errors. Both
+// start with the field name; a field that failed to parse is reported once.
+func rowProblems(row importRow, data *CreateProductRequest) []string {
+    problems := append([]string(nil), row.Problems...)
+    if row.Malformed {
+        return problems
+    }
+
+    parsed := make(map[string]bool, len(problems))
+    for _, problem := range problems {
+        field, _, _ := strings.Cut(problem, " ")
+        parsed[field] = true
+    }
+    for _, problem := range data.validate() {
+        if field, _, _ := strings.Cut(problem, " "); !parsed[field] {
+            problems = append(problems, problem)
+        }
+    }
+    return problems
+}
+
+// importUpdates copies a row onto product and returns the changed columns
+func importUpdates(product *models.Product, data CreateProductRequest, hasStock bool) map[string]interface{} {
+    updates := map[string]interface{}{}
+    if product.Title != data.Title {
+        product.Title = data.Title
+        updates["title"] = data.Title
+    }
+    if product.Description != data.Description {
+        product.Description = data.Description
+        updates["description"] = data.Description
+    }
+    if product.Image != data.Image {
+        product.Image = data.Image
+        updates["image"] = data.Image
+    }
+    if product.Price != data.Price {
+        product.Price = data.Price
+        updates["price"] = data.Price
+    }
+    if hasStock && !sameStock(product.Stock, data.Stock) {
+        product.Stock = data.Stock
+        updates["stock"] = data.Stock
+    }
+    return updates
+}
+
+func sameStock(a, b *int) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return *a == *b
+}
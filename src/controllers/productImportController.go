@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ImportProducts queues a bulk import from a CSV file (header row naming the
+// columns) or JSON lines, one product per row with the fields of
+// CreateProducts. Rows with an external_sku update the product holding that
+// SKU and create it otherwise. With dry_run=true every row is checked and
+// applied in a rolled-back transaction, so the report shows what would happen.
+// The file is the "file" form field or the raw body (text/csv or
+// application/x-ndjson); ?format=csv|jsonl overrides detection. Each
+// instance holds at most maxPendingImports unfinished jobs.
+// POST /api/admin/products/import
+func ImportProducts(c *fiber.Ctx) error {
+    data, filename, format, ferr := readImportFile(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    rows, err := parseImport(format, data)
+    if err != nil {
+        return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+            "error": err.Error(),
+        })
+    }
+
+    // Released by runProductImport once the job has finished
+    select {
+    case importPending <- struct{}{}:
+    default:
+        return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+            "error": "too many imports in progress; try again later",
+        })
+    }
+
+    now := time.Now()
+    job := models.ProductImport{
+        CreatedByID: actorIDPtr(c),
+        Filename:    filename,
+        Format:      format,
+        DryRun:      c.QueryBool("dry_run"),
+        Status:      models.ImportQueued,
+        Owner:       importOwner,
+        HeartbeatAt: &now,
+        TotalRows:   len(rows),
+    }
+    if err := database.DB.Create(&job).Error; err != nil {
+        <-importPending
+        log.Printf("Failed to create product import: %v", err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to queue import",
+        })
+    }
+
+    go runProductImport(&models.ProductImport{
+        ID:          job.ID,
+        CreatedByID: job.CreatedByID,
+        DryRun:      job.DryRun,
+        TotalRows:   job.TotalRows,
+    }, rows)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.import",
+        TargetType: "product_import",
+        TargetID:   job.ID,
+        After:      job,
+    })
+
+    return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+        "message": "import queued",
+        "data":    job,
+    })
+}
+
+// ProductImports lists recent import jobs without their reports
+// GET /api/admin/products/imports
+func ProductImports(c *fiber.Ctx) error {
+    var jobs []models.ProductImport
+    if err := database.DB.WithContext(c.Context()).
+        Omit("report").
+        Order("id DESC").
+        Limit(50).
+        Find(&jobs).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch imports",
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "data": jobs,
+    })
+}
+
+// GetProductImport returns an import job's progress and, once it has
+// finished, its per-row report (?errors_only=true keeps failed rows only)
+// GET /api/admin/products/imports/:importId
+func GetProductImport(c *fiber.Ctx) error {
+    id, err := strconv.Atoi(c.Params("importId"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid import ID",
+        })
+    }
+
+    var job models.ProductImport
+    if err := database.DB.WithContext(c.Context()).First(&job, id).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+                "error": "import not found",
+            })
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch import",
+        })
+    }
+
+    if c.QueryBool("errors_only") {
+        failed := make([]models.ImportRowResult, 0, job.Failed)
+        for _, row := range job.Report {
+            if row.Action == models.ImportActionError {
+                failed = append(failed, row)
+            }
+        }
+        job.Report = failed
+    }
+
+    return c.JSON(fiber.Map{
+        "data": job,
+    })
+}
+
+// readImportFile returns the uploaded file, its name and its format
+func readImportFile(c *fiber.Ctx) ([]byte, string, string, *fiber.Error) {
+    format := strings.ToLower(c.Query("format"))
+
+    var data []byte
+    var filename string
+    if file, err := c.FormFile("file"); err == nil {
+        filename = filepath.Base(file.Filename)
+        if len(filename) > 255 {
+            filename = filename[:255]
+        }
+        if format == "" {
+            format = importFormat(filepath.Ext(filename), file.Header.Get("Content-Type"))
+        }
+
+        src, err := file.Open()
+        if err != nil {
+            return nil, "", "", fiber.NewError(fiber.StatusBadRequest, "could not read the uploaded file")
+        }
+        defer src.Close()
+        if data, err = io.ReadAll(src); err != nil {
+            return nil, "", "", fiber.NewError(fiber.StatusBadRequest, "could not read the uploaded file")
+        }
+    } else {
+        if format == "" {
+            format = importFormat("", string(c.Request().Header.ContentType()))
+        }
+        data = c.Body()
+    }
+
+    switch {
+    case len(data) == 0:
+        return nil, "", "", fiber.NewError(fiber.StatusBadRequest, "send the import as the \"file\" form field or as the request body")
+    case format != "csv" && format != "jsonl":
+        return nil, "", "", fiber.NewError(fiber.StatusUnsupportedMediaType, "format must be csv or jsonl")
+    }
+    return data, filename, format, nil
+}
+
+// importFormat guesses csv or jsonl from a file extension or content type
+func importFormat(ext, contentType string) string {
+    switch strings.ToLower(ext) {
+    case ".csv":
+        return "csv"
+    case ".jsonl", ".ndjson":
+        return "jsonl"
+    }
+
+    mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+    switch strings.TrimSpace(mediaType) {
+    case "text/csv", "application/csv":
+        return "csv"
+    case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+        return "jsonl"
+    }
+    return ""
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+// importRowSummary is the part of a parsed row the tests compare
+type importRowSummary struct {
+    Number    int
+    Request   CreateProductRequest
+    HasStock  bool
+    Malformed bool
+    Problems  []string
+}
+
+func summarizeRows(rows []importRow) []importRowSummary {
+    out := make([]importRowSummary, len(rows))
+    for i, row := range rows {
+        out[i] = importRowSummary(row)
+    }
+    return out
+}
+
+func TestParseImportCSV(t *testing.T) {
+    header := "external_sku,title,description,image,price,stock\n"
+    tests := []struct {
+        name string
+        data string
+        want []importRowSummary
+        err  string // substring of the file-level error
+    }{
+        {
+            name: "rows with and without stock",
+            data: header +
+                "SKU-1,Mug,A large coffee mug,https://img.example.com/mug.jpg,12.5,40\n" +
+                "SKU-2,Cap,A cap for sunny days,https://img.example.com/cap.jpg,9,\n",
+            want: []importRowSummary{
+                {Number: 1, HasStock: true, Request: CreateProductRequest{ExternalSKU: "SKU-1", Title: "Mug", Description: "A large coffee mug", Image: "https://img.example.com/mug.jpg", Price: 12.5, Stock: intPtr(40)}},
+                {Number: 2, HasStock: true, Request: CreateProductRequest{ExternalSKU: "SKU-2", Title: "Cap", Description: "A cap for sunny days", Image: "https://img.example.com/cap.jpg", Price: 9}},
+            },
+        },
+        {
+            name: "header names are trimmed and case-insensitive, in any order",
+            data: " Price , TITLE,image,description\n3,Pen,https://img.example.com/pen.jpg,A pen that writes\n",
+            want: []importRowSummary{
+                {Number: 1, Request: CreateProductRequest{Title: "Pen", Description: "A pen that writes", Image: "https://img.example.com/pen.jpg", Price: 3}},
+            },
+        },
+        {
+            name: "quoted fields keep commas and newlines",
+            data: "title,description,image,price\n\"Mug, large\",\"Line one\nline two\",https://img.example.com/mug.jpg,4\n",
+            want: []importRowSummary{
+                {Number: 1, Request: CreateProductRequest{Title: "Mug, large", Description: "Line one\nline two", Image: "https://img.example.com/mug.jpg", Price: 4}},
+            },
+        },
+        {
+            name: "bad cells are reported on the row",
+            data: header + "SKU-1,Mug,A large coffee mug,https://img.example.com/mug.jpg,cheap,lots\n",
+            want: []importRowSummary{
+                {Number: 1, HasStock: true, Request: CreateProductRequest{ExternalSKU: "SKU-1", Title: "Mug", Description: "A large coffee mug", Image: "https://img.example.com/mug.jpg"},
+                    Problems: []string{"price must be a number", "stock must be a whole number"}},
+            },
+        },
+        {
+            name: "a row with the wrong number of fields is malformed, the rest still parse",
+            data: header + "SKU-1,Mug\nSKU-2,Cap,A cap for sunny days,https://img.example.com/cap.jpg,9,1\n",
+            want: []importRowSummary{
+                {Number: 1, HasStock: true, Malformed: true, Problems: []string{"expected 6 fields, got 2"}},
+                {Number: 2, HasStock: true, Request: CreateProductRequest{ExternalSKU: "SKU-2", Title: "Cap", Description: "A cap for sunny days", Image: "https://img.example.com/cap.jpg", Price: 9, Stock: intPtr(1)}},
+            },
+        },
+        {name: "empty file", data: "", err: "the file is empty"},
+        {name: "unknown column", data: "title,description,image,price,colour\n", err: `unknown column "colour"`},
+        {name: "duplicate column", data: "title,description,image,price,Title\n", err: `duplicate column "title"`},
+        {name: "missing required column", data: "title,description,price\n", err: `missing required column "image"`},
+        {name: "broken quoting", data: "title,description,image,price\n\"Mug,desc,img,1\n", err: "invalid CSV"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rows, err := parseImportCSV([]byte(tt.data))
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("err = %v, want %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("parseImportCSV: %v", err)
+            }
+            if got := summarizeRows(rows); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("rows:\n got %+v\nwant %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseImportJSONL(t *testing.T) {
+    tests := []struct {
+        name string
+        data string
+        want []importRowSummary
+        err  string
+    }{
+        {
+            name: "rows with and without stock, blank lines skipped",
+            data: `{"external_sku":"SKU-1","title":"Mug","description":"A large coffee mug","image":"https://img.example.com/mug.jpg","price":12.5,"stock":40}` + "\n\n" +
+                `  {"title":"Cap","description":"A cap for sunny days","image":"https://img.example.com/cap.jpg","price":9}  ` + "\n",
+            want: []importRowSummary{
+                {Number: 1, HasStock: true, Request: CreateProductRequest{ExternalSKU: "SKU-1", Title: "Mug", Description: "A large coffee mug", Image: "https://img.example.com/mug.jpg", Price: 12.5, Stock: intPtr(40)}},
+                {Number: 2, Request: CreateProductRequest{Title: "Cap", Description: "A cap for sunny days", Image: "https://img.example.com/cap.jpg", Price: 9}},
+            },
+        },
+        {
+            name: "null stock stops tracking",
+            data: `{"title":"Mug","price":1,"stock":null}`,
+            want: []importRowSummary{
+                {Number: 1, HasStock: true, Request: CreateProductRequest{Title: "Mug", Price: 1}},
+            },
+        },
+        {
+            name: "unknown fields and wrong types are reported on the row",
+            data: `{"title":"Mug","price":"12","colour":"red"}`,
+            want: []importRowSummary{
+                {Number: 1, Request: CreateProductRequest{Title: "Mug"}, Problems: []string{`unknown field "colour"`, "price has the wrong type"}},
+            },
+        },
+        {
+            name: "a line that is not a JSON object is malformed, the rest still parse",
+            data: "{\"title\":\n" + `{"title":"Cap","price":2}`,
+            want: []importRowSummary{
+                {Number: 1, Malformed: true, Problems: []string{"invalid JSON object"}},
+                {Number: 2, Request: CreateProductRequest{Title: "Cap", Price: 2}},
+            },
+        },
+        {name: "line over 1 MB", data: `{"title":"` + strings.Repeat("x", 1<<20) + `"}`, err: "invalid JSON lines"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rows, err := parseImportJSONL([]byte(tt.data))
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("err = %v, want %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("parseImportJSONL: %v", err)
+            }
+            if got := summarizeRows(rows); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("rows:\n got %+v\nwant %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseImport(t *testing.T) {
+    tests := []struct {
+        name   string
+        format string
+        data   string
+        rows   int
+        err    string
+    }{
+        {"csv with byte order mark", "csv", "\xef\xbb\xbftitle,description,image,price\nMug,A large coffee mug,https://img.example.com/mug.jpg,1\n", 1, ""},
+        {"jsonl", "jsonl", `{"title":"Mug"}` + "\n" + `{"title":"Cap"}`, 2, ""},
+        {"csv header only", "csv", "title,description,image,price\n", 0, "no data rows"},
+        {"jsonl blank", "jsonl", "\n\n", 0, "no data rows"},
+        {"unsupported format", "xml", "<products/>", 0, "unsupported format"},
+        {"too many csv rows", "csv", "title,description,image,price\n" + strings.Repeat("Mug,d,i,1\n", maxImportRows+1), 0, fmt.Sprintf("too many rows (max %d)", maxImportRows)},
+        {"too many jsonl rows", "jsonl", strings.Repeat("{}\n", maxImportRows+1), 0, fmt.Sprintf("too many rows (max %d)", maxImportRows)},
+        {"exactly the row limit", "jsonl", strings.Repeat("{}\n", maxImportRows), maxImportRows, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rows, err := parseImport(tt.format, []byte(tt.data))
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("err = %v, want %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("parseImport: %v", err)
+            }
+            if len(rows) != tt.rows {
+                t.Errorf("got %d rows, want %d", len(rows), tt.rows)
+            }
+        })
+    }
+}
+
+func TestImportRowProblems(t *testing.T) {
+    valid := CreateProductRequest{Title: "Mug", Description: "A large coffee mug", Image: "https://img.example.com/mug.jpg", Price: 12.5}
+    tests := []struct {
+        name string
+        row  importRow
+        want []string
+    }{
+        {"valid", importRow{Request: valid}, nil},
+        {"text is trimmed before checking", importRow{Request: CreateProductRequest{Title: "  Mug ", Description: " A large coffee mug ", Image: " https://img.example.com/mug.jpg ", Price: 1}}, nil},
+        {"every rule", importRow{Request: CreateProductRequest{Description: "short", Image: "ftp://img.example.com/a.jpg", Price: 0, Stock: intPtr(-1), ExternalSKU: "bad sku"}}, []string{
+            "title must be 1-255 characters",
+            "description must be 10-1000 characters",
+            "image must be an http(s) URL of at most 500 characters",
+            "price must be greater than 0 and at most 100000",
+            "stock cannot be negative",
+            "external_sku must be 1-64 letters, digits, dots, dashes or underscores",
+        }},
+        {"price over the maximum", importRow{Request: CreateProductRequest{Title: "Mug", Description: "A large coffee mug", Image: "https://img.example.com/mug.jpg", Price: 100000.01}}, []string{
+            "price must be greater than 0 and at most 100000",
+        }},
+        {"a field that failed to parse is reported once", importRow{Request: CreateProductRequest{Title: "Mug", Description: "A large coffee mug", Image: "https://img.example.com/mug.jpg"}, Problems: []string{"price must be a number"}}, []string{
+            "price must be a number",
+        }},
+        {"malformed rows are not validated", importRow{Malformed: true, Problems: []string{"invalid JSON object"}}, []string{
+            "invalid JSON object",
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            data := tt.row.Request
+            data.normalize()
+            if got := rowProblems(tt.row, &data); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("problems:\n got %q\nwant %q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"ambassador/src/scheduler"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProductSaleRequest struct {
+    SalePrice float64    `json:"sale_price" validate:"required,gt=0"`
+    StartsAt  *time.Time `json:"starts_at"` // omitted = starts now
+    EndsAt    *time.Time `json:"ends_at"`   // omitted = runs until removed
+}
+
+// SetProductSale schedules (or replaces) a sale price for a product
+// PUT /api/admin/products/:id/sale
+func SetProductSale(c *fiber.Ctx) error {
+    var data ProductSaleRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    if data.SalePrice <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "sale_price must be greater than 0 and lower than the product price",
+        })
+    }
+    if data.StartsAt != nil && data.EndsAt != nil && !data.EndsAt.After(*data.StartsAt) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "ends_at must be after starts_at",
+        })
+    }
+    if data.EndsAt != nil && !data.EndsAt.After(time.Now()) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "ends_at must be in the future",
+        })
+    }
+
+    return saveProductSale(c, "product.sale", func(product *models.Product) *fiber.Error {
+        if data.SalePrice >= product.Price {
+            return fiber.NewError(fiber.StatusBadRequest, "sale_price must be greater than 0 and lower than the product price")
+        }
+
+        // Stored with second precision so boundaries fire on exact seconds
+        salePrice := data.SalePrice
+        product.SalePrice = &salePrice
+        product.SaleStartsAt = truncateSaleTime(data.StartsAt)
+        product.SaleEndsAt = truncateSaleTime(data.EndsAt)
+        return nil
+    })
+}
+
+// RemoveProductSale cancels a product's sale, running or scheduled
+// DELETE /api/admin/products/:id/sale
+func RemoveProductSale(c *fiber.Ctx) error {
+    return saveProductSale(c, "product.sale_remove", func(product *models.Product) *fiber.Error {
+        if product.SalePrice == nil {
+            return fiber.NewError(fiber.StatusConflict, "product has no sale")
+        }
+
+        product.SalePrice = nil
+        product.SaleStartsAt = nil
+        product.SaleEndsAt = nil
+        return nil
+    })
+}
+
+// saveProductSale locks the :id product, lets apply change its sale fields and
+// saves them as a new product version, then clears caches and lets the
+// scheduler pick up the new boundaries. Checks and snapshot see the locked
+// row, so concurrent changes can't slip in between.
+func saveProductSale(c *fiber.Ctx, action string, apply func(product *models.Product) *fiber.Error) error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid product ID",
+        })
+    }
+
+    var before, product models.Product
+    actorID := actorIDPtr(c)
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before, id).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return fiber.NewError(fiber.StatusNotFound, "product not found")
+            }
+            return err
+        }
+        product = before
+        if ferr := apply(&product); ferr != nil {
+            return ferr
+        }
+
+        if before.Version == 0 {
+            if _, err := models.RecordProductVersion(tx, &before, models.ProductChangeBaseline, nil, nil); err != nil {
+                return err
+            }
+        }
+        if err := tx.Model(&models.Product{}).
+            Where("id = ?", product.ID).
+            Updates(map[string]interface{}{
+                "sale_price":     product.SalePrice,
+                "sale_starts_at": product.SaleStartsAt,
+                "sale_ends_at":   product.SaleEndsAt,
+            }).Error; err != nil {
+            return err
+        }
+        _, err := models.RecordProductVersion(tx, &product, models.ProductChangeSale, actorID, nil)
+        return err
+    })
+    if err != nil {
+        var ferr *fiber.Error
+        if errors.As(err, &ferr) {
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
+        log.Printf("Failed to update sale of product %d: %v", id, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to update sale",
+        })
+    }
+
+    database.ClearProductCaches(c.Context(), product.ID)
+    scheduler.NotifySaleChanged()
+
+    audit.Record(c, audit.Entry{
+        Action:     action,
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     toProductResponse(&before),
+        After:      toProductResponse(&product),
+    })
+
+    return c.JSON(fiber.Map{
+        "message":       "sale updated successfully",
+        "data":          toProductResponse(&product),
+        "cache_cleared": true,
+    })
+}
+
+func findProductParam(c *fiber.Ctx) (*models.Product, *fiber.Error) {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return nil, fiber.NewError(fiber.StatusBadRequest, "invalid product ID")
+    }
+
+    var product models.Product
+    if err := database.DB.First(&product, id).Error; err != nil {
+        return nil, fiber.NewError(fiber.StatusNotFound, "product not found")
+    }
+
+    return &product, nil
+}
+
+func truncateSaleTime(t *time.Time) *time.Time {
+    if t == nil {
+        return nil
+    }
+    truncated := t.UTC().Truncate(time.Second)
+    return &truncated
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"ambassador/src/search"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Search modes of ProductBackend (?mode=)
+const (
+    searchModeNatural = "natural"
+    searchModeBoolean = "boolean"
+)
+
+const (
+    // InnoDB ignores words shorter than innodb_ft_min_token_size (default 3);
+    // queries without a longer word fall back to LIKE
+    fulltextMinTokenSize = 3
+    maxSearchLength      = 200
+    snippetLength        = 160
+)
+
+// productSearch is a parsed ?s= query, run against MySQL FULLTEXT or,
+// with SEARCH_BACKEND=memory, the in-process index of the search package
+type productSearch struct {
+    query    string // as sent to MySQL
+    mode     string
+    terms    []string // words to highlight, lowercase; "term*" matches prefixes
+    fulltext bool     // false = LIKE fallback
+
+    // SEARCH_BACKEND=memory: ranked IDs from the in-process index, at most
+    // search.MaxProductResults of matches
+    memory  bool
+    ids     []uint
+    scores  map[uint]float64
+    matches int
+}
+
+// capped reports whether matches were dropped past search.MaxProductResults
+func (s productSearch) capped() bool {
+    return s.memory && s.matches > len(s.ids)
+}
+
+func parseProductSearch(q, mode string) (productSearch, *fiber.Error) {
+    s := productSearch{query: strings.TrimSpace(q), mode: strings.ToLower(strings.TrimSpace(mode))}
+    if s.mode == "" {
+        s.mode = searchModeNatural
+    }
+    if s.mode != searchModeNatural && s.mode != searchModeBoolean {
+        return s, fiber.NewError(fiber.StatusBadRequest, "mode must be natural or boolean")
+    }
+    if len(s.query) > maxSearchLength {
+        return s, fiber.NewError(fiber.StatusBadRequest, "search query too long")
+    }
+    if s.query == "" {
+        return s, nil
+    }
+
+    // The in-memory index has no boolean syntax; operators are ignored by its tokenizer
+    if search.ProductsEnabled() {
+        hits := search.Products.Search(s.query, search.MaxProductResults)
+        s.memory = true
+        s.matches = hits.Total
+        s.terms = hits.Words
+        s.scores = make(map[uint]float64, len(hits.Results))
+        for _, result := range hits.Results {
+            s.ids = append(s.ids, result.ID)
+            s.scores[result.ID] = result.Score
+        }
+        return s, nil
+    }
+
+    if s.mode == searchModeBoolean {
+        s.query = sanitizeBooleanQuery(s.query)
+    }
+
+    for _, word := range strings.Fields(s.query) {
+        // Excluded words ("-refurbished") are never highlighted
+        excluded := s.mode == searchModeBoolean && strings.HasPrefix(strings.TrimLeft(word, `+<>~("`), "-")
+        prefix := s.mode == searchModeBoolean && strings.HasSuffix(strings.TrimRight(word, `)"`), "*")
+
+        for _, term := range strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+            return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+        }) {
+            if utf8.RuneCountInString(term) >= fulltextMinTokenSize {
+                s.fulltext = true
+            }
+            if excluded {
+                continue
+            }
+            if prefix {
+                term += "*"
+            }
+            s.terms = append(s.terms, term)
+        }
+    }
+
+    return s, nil
+}
+
+// sanitizeBooleanQuery drops unbalanced quotes and parentheses and the
+// proximity operator, which MySQL rejects or misparses
+func sanitizeBooleanQuery(q string) string {
+    q = strings.ReplaceAll(q, "@", " ")
+    if strings.Count(q, `"`)%2 != 0 {
+        q = strings.ReplaceAll(q, `"`, " ")
+    }
+    depth := 0
+    balanced := true
+    for _, r := range q {
+        switch r {
+        case '(':
+            depth++
+        case ')':
+            depth--
+            if depth < 0 {
+                balanced = false
+            }
+        }
+    }
+    if !balanced || depth != 0 {
+        q = strings.NewReplacer("(", " ", ")", " ").Replace(q)
+    }
+    return strings.TrimSpace(q)
+}
+
+// matchSQL is the MATCH ... AGAINST expression over the FULLTEXT index
+// (models.ProductFulltextIndex); bind query once
+func (s productSearch) matchSQL() string {
+    modeSQL := "IN NATURAL LANGUAGE MODE"
+    if s.mode == searchModeBoolean {
+        modeSQL = "IN BOOLEAN MODE"
+    }
+    return "MATCH(products.title, products.description) AGAINST (? " + modeSQL + ")"
+}
+
+// apply restricts a products query to matches
+func (s productSearch) apply(db *gorm.DB) *gorm.DB {
+    if s.query == "" {
+        return db
+    }
+    if s.memory {
+        if len(s.ids) == 0 {
+            return db.Where("1 = 0")
+        }
+        return db.Where("products.id IN ?", s.ids)
+    }
+    if s.fulltext {
+        return db.Where(s.matchSQL(), s.query)
+    }
+
+    searchTerm := "%" + s.likeTerm() + "%"
+    return db.Where("LOWER(products.title) LIKE ? OR LOWER(products.description) LIKE ?",
+        searchTerm, searchTerm)
+}
+
+// likeTerm is the query as matched by the LIKE fallback
+func (s productSearch) likeTerm() string {
+    return strings.ToLower(s.query)
+}
+
+// titleMatches reports whether the LIKE fallback matched title (vs description only)
+func (s productSearch) titleMatches(title string) bool {
+    return strings.Contains(strings.ToLower(title), s.likeTerm())
+}
+
+// highlighter matches the search terms as whole words (or word prefixes)
+func (s productSearch) highlighter() *regexp.Regexp {
+    if len(s.terms) == 0 {
+        return nil
+    }
+    alternatives := make([]string, 0, len(s.terms))
+    for _, term := range s.terms {
+        if strings.HasSuffix(term, "*") {
+            alternatives = append(alternatives, regexp.QuoteMeta(strings.TrimSuffix(term, "*"))+`[\p{L}\p{N}]*`)
+        } else {
+            alternatives = append(alternatives, regexp.QuoteMeta(term))
+        }
+    }
+    return regexp.MustCompile(`(?i)\b(` + strings.Join(alternatives, "|") + `)\b`)
+}
+
+// applySnippets fills the highlighted title and description snippet of each product
+func (s productSearch) applySnippets(products []ProductListResponse) {
+    re := s.highlighter()
+    if re == nil {
+        return
+    }
+    for i := range products {
+        products[i].TitleHighlight = highlight(re, products[i].Title)
+        products[i].Snippet = snippet(re, products[i].Description, snippetLength)
+    }
+}
+
+// applyRelevance fills the BM25 scores of the in-memory index
+func (s productSearch) applyRelevance(products []ProductListResponse) {
+    if !s.memory {
+        return
+    }
+    for i := range products {
+        if score, ok := s.scores[products[i].ID]; ok {
+            products[i].Relevance = &score
+        }
+    }
+}
+
+// highlight HTML-escapes text and wraps matches in <mark>
+func highlight(re *regexp.Regexp, text string) string {
+    var b strings.Builder
+    last := 0
+    for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+        start, end := m[2], m[3]
+        b.WriteString(html.EscapeString(text[last:start]))
+        b.WriteString("<mark>")
+        b.WriteString(html.EscapeString(text[start:end]))
+        b.WriteString("</mark>")
+        last = end
+    }
+    b.WriteString(html.EscapeString(text[last:]))
+    return b.String()
+}
+
+// snippet cuts about length bytes of text around the first match and highlights it
+func snippet(re *regexp.Regexp, text string, length int) string {
+    if len(text) <= length {
+        return highlight(re, text)
+    }
+
+    start := 0
+    if m := re.FindStringSubmatchIndex(text); m != nil {
+        start = m[2] - length/3
+    }
+    if start < 0 {
+        start = 0
+    }
+    end := start + length
+    if end > len(text) {
+        end = len(text)
+        start = max(0, end-length)
+    }
+
+    // Cut on rune boundaries, then widen to whole words
+    for start > 0 && !utf8.RuneStart(text[start]) {
+        start--
+    }
+    for end < len(text) && !utf8.RuneStart(text[end]) {
+        end++
+    }
+    if start > 0 {
+        if i := strings.IndexByte(text[start:end], ' '); i >= 0 {
+            start += i + 1
+        }
+    }
+    if end < len(text) {
+        if i := strings.LastIndexByte(text[start:end], ' '); i > 0 {
+            end = start + i
+        }
+    }
+
+    result := highlight(re, text[start:end])
+    if start > 0 {
+        result = "…" + result
+    }
+    if end < len(text) {
+        result += "…"
+    }
+    return result
+}
+
+// relevanceOrder sorts search results best match first
+func (s productSearch) relevanceOrder(db *gorm.DB) *gorm.DB {
+    if s.memory {
+        if len(s.ids) == 0 {
+            return db
+        }
+        // Keep the index's ranking: FIELD gives each ID its position in the list
+        vars := make([]interface{}, len(s.ids))
+        for i, id := range s.ids {
+            vars[i] = id
+        }
+        return db.Order(clauseExpr("FIELD(products.id"+strings.Repeat(", ?", len(s.ids))+"), products.id ASC", vars...))
+    }
+    if s.fulltext {
+        return db.Order(clauseExpr(s.matchSQL()+" DESC, products.id ASC", s.query))
+    }
+    // LIKE fallback: title matches before description-only matches
+    return db.Order(clauseExpr("LOWER(products.title) LIKE ? DESC, products.id ASC", "%"+s.likeTerm()+"%"))
+}
+
+// relevanceColumn selects the MATCH score as "relevance" (MySQL fulltext only)
+func (s productSearch) relevanceColumn() (string, []interface{}) {
+    if s.memory || !s.fulltext {
+        return "", nil
+    }
+    return ", " + s.matchSQL() + " AS relevance", []interface{}{s.query}
+}
+
+// clauseExpr builds an ORDER BY expression with bound values
+func clauseExpr(sql string, vars ...interface{}) clause.OrderBy {
+    return clause.OrderBy{Expression: clause.Expr{SQL: sql, Vars: vars}}
+}
+
+// SuggestProducts returns product titles for a search box prefix, most
+// ordered first, from the in-memory trie (?q=, ?limit= up to 10)
+// GET /api/ambassador/products/suggest
+func SuggestProducts(c *fiber.Ctx) error {
+    q := strings.TrimSpace(c.Query("q"))
+    if len(q) > maxSearchLength {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "query too long",
+        })
+    }
+    limit := clamp(atoi(c.Query("limit", "8")), 1, search.MaxSuggestions)
+
+    return c.JSON(fiber.Map{
+        "query": q,
+        "data":  search.Suggestions.Lookup(q, limit),
+    })
+}
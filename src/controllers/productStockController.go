@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errStockBelowReserved = errors.New("stock below reserved")
+
+type ProductStockRequest struct {
+    Stock *int `json:"stock" validate:"omitempty,min=0"` // null = stop tracking stock
+}
+
+// SetProductStock sets a product's quantity on hand. It can't go below the
+// units held by pending checkouts.
+// PUT /api/admin/products/:id/stock
+func SetProductStock(c *fiber.Ctx) error {
+    var data ProductStockRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+    if data.Stock != nil && *data.Stock < 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "stock cannot be negative",
+        })
+    }
+
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var before models.Product
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        locked, err := models.LockProducts(tx, []uint{product.ID})
+        if err != nil {
+            return err
+        }
+        if len(locked) == 0 {
+            return gorm.ErrRecordNotFound
+        }
+        before = locked[0]
+        if data.Stock != nil && *data.Stock < before.Reserved {
+            return errStockBelowReserved
+        }
+
+        *product = before
+        product.Stock = data.Stock
+        return tx.Model(&models.Product{}).Where("id = ?", product.ID).
+            UpdateColumn("stock", data.Stock).Error
+    })
+    if err != nil {
+        switch {
+        case errors.Is(err, errStockBelowReserved):
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+                "error":    "stock cannot be lower than the units reserved by pending checkouts",
+                "reserved": before.Reserved,
+            })
+        case errors.Is(err, gorm.ErrRecordNotFound):
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+                "error": "product not found",
+            })
+        }
+        log.Printf("Failed to update stock of product %d: %v", product.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to update stock",
+        })
+    }
+
+    if before.InStock() != product.InStock() {
+        database.ClearProductCaches(c.Context(), product.ID)
+    } else {
+        invalidateStockCaches(c.Context(), []uint{product.ID}, nil)
+    }
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.stock",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     toProductResponse(&before),
+        After:      toProductResponse(product),
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "stock updated successfully",
+        "data":    toProductResponse(product),
+    })
+}
@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const maxVariantAttributes = 10
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+type ProductVariantRequest struct {
+    SKU        string            `json:"sku" validate:"required,max=64"`
+    Attributes map[string]string `json:"attributes" validate:"required"`
+    Price      *float64          `json:"price" validate:"omitempty,gt=0"` // null = the product's price
+    Stock      *int              `json:"stock" validate:"omitempty,min=0"` // null = not tracked
+}
+
+// PublicVariant is a variant as shown to ambassadors and buyers
+type PublicVariant struct {
+    ID             uint                     `json:"id"`
+    SKU            string                   `json:"sku"`
+    Attributes     models.VariantAttributes `json:"attributes"`
+    EffectivePrice float64                  `json:"effective_price"`
+    InStock        bool                     `json:"in_stock"`
+}
+
+// ProductVariants lists a product's variants
+// GET /api/admin/products/:id/variants, GET /api/ambassador/products/:id/variants
+func ProductVariants(c *fiber.Ctx) error {
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var variants []models.ProductVariant
+    if err := database.DB.WithContext(c.Context()).
+        Where("product_id = ?", product.ID).
+        Order("id").
+        Find(&variants).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch variants",
+        })
+    }
+
+    now := time.Now()
+    for i := range variants {
+        variants[i].Apply(product, now)
+    }
+
+    if !strings.HasPrefix(c.Path(), "/api/ambassador") {
+        return c.JSON(fiber.Map{
+            "data": variants,
+        })
+    }
+
+    // Stock levels stay internal outside the admin API
+    public := make([]PublicVariant, len(variants))
+    for i, variant := range variants {
+        public[i] = PublicVariant{
+            ID:             variant.ID,
+            SKU:            variant.SKU,
+            Attributes:     variant.Attributes,
+            EffectivePrice: variant.EffectivePrice,
+            InStock:        variant.InStock,
+        }
+    }
+    return c.JSON(fiber.Map{
+        "data": public,
+    })
+}
+
+// CreateProductVariant adds a variant to a product
+// POST /api/admin/products/:id/variants
+func CreateProductVariant(c *fiber.Ctx) error {
+    var data ProductVariantRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    variant := models.ProductVariant{ProductID: product.ID}
+    if ferr := applyVariantRequest(&variant, data, 0); ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    if err := database.DB.Create(&variant).Error; err != nil {
+        log.Printf("Failed to create variant of product %d: %v", product.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to create variant",
+        })
+    }
+    variant.Apply(product, time.Now())
+
+    // Listings change too: the product now sells (and stocks) per variant
+    database.ClearProductCaches(c.Context(), product.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.variant_create",
+        TargetType: "product",
+        TargetID:   product.ID,
+        After:      variant,
+    })
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "variant created successfully",
+        "data":    variant,
+    })
+}
+
+// UpdateProductVariant replaces a variant's SKU, attributes, price and stock.
+// Stock can't go below the units held by pending checkouts.
+// PUT /api/admin/products/:id/variants/:variantId
+func UpdateProductVariant(c *fiber.Ctx) error {
+    var data ProductVariantRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var before, variant models.ProductVariant
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        locked, ferr := lockProductVariant(tx, c, product.ID)
+        if ferr != nil {
+            return ferr
+        }
+        before, variant = *locked, *locked
+
+        if ferr := applyVariantRequest(&variant, data, variant.ID); ferr != nil {
+            return ferr
+        }
+        if variant.Stock != nil && *variant.Stock < variant.Reserved {
+            return fiber.NewError(fiber.StatusConflict, "stock cannot be lower than the units reserved by pending checkouts")
+        }
+
+        // Selected so null price and stock are written too
+        return tx.Model(&variant).
+            Select("sku", "attributes", "price", "stock").
+            Updates(&variant).Error
+    })
+    if err != nil {
+        return variantError(c, err, "failed to update variant")
+    }
+
+    now := time.Now()
+    before.Apply(product, now)
+    variant.Apply(product, now)
+    database.ClearProductCaches(c.Context(), product.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.variant_update",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     before,
+        After:      variant,
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "variant updated successfully",
+        "data":    variant,
+    })
+}
+
+// DeleteProductVariant removes a variant without pending reservations.
+// Orders keep the SKU and attributes they were placed with.
+// DELETE /api/admin/products/:id/variants/:variantId
+func DeleteProductVariant(c *fiber.Ctx) error {
+    product, ferr := findProductParam(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var variant models.ProductVariant
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        locked, ferr := lockProductVariant(tx, c, product.ID)
+        if ferr != nil {
+            return ferr
+        }
+        variant = *locked
+
+        if variant.Reserved > 0 {
+            return fiber.NewError(fiber.StatusConflict, "variant has units reserved by pending checkouts")
+        }
+        if err := tx.Exec("DELETE FROM link_variants WHERE product_variant_id = ?", variant.ID).Error; err != nil {
+            return err
+        }
+        return tx.Delete(&models.ProductVariant{}, variant.ID).Error
+    })
+    if err != nil {
+        return variantError(c, err, "failed to delete variant")
+    }
+
+    database.ClearProductCaches(c.Context(), product.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.variant_delete",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     variant,
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "variant deleted successfully",
+    })
+}
+
+// applyVariantRequest validates a request and copies it onto variant;
+// selfID is the variant being updated (0 when creating)
+func applyVariantRequest(variant *models.ProductVariant, data ProductVariantRequest, selfID uint) *fiber.Error {
+    sku := strings.TrimSpace(data.SKU)
+    if !skuPattern.MatchString(sku) {
+        return fiber.NewError(fiber.StatusBadRequest, "sku must be 1-64 letters, digits, dots, dashes or underscores")
+    }
+    if data.Price != nil && (*data.Price <= 0 || *data.Price > 100000) {
+        return fiber.NewError(fiber.StatusBadRequest, "price must be between 0 and 100000")
+    }
+    if data.Stock != nil && *data.Stock < 0 {
+        return fiber.NewError(fiber.StatusBadRequest, "stock cannot be negative")
+    }
+    if len(data.Attributes) == 0 || len(data.Attributes) > maxVariantAttributes {
+        return fiber.NewError(fiber.StatusBadRequest, "attributes must have between 1 and 10 entries")
+    }
+
+    attributes := models.VariantAttributes{}
+    for key, value := range data.Attributes {
+        key = strings.ToLower(strings.TrimSpace(key))
+        value = strings.TrimSpace(value)
+        if key == "" || len(key) > 50 || value == "" || len(value) > 100 {
+            return fiber.NewError(fiber.StatusBadRequest, "attribute names must be 1-50 and values 1-100 characters")
+        }
+        attributes[key] = value
+    }
+
+    var taken int64
+    if err := database.DB.Model(&models.ProductVariant{}).
+        Where("sku = ? AND id <> ?", sku, selfID).
+        Count(&taken).Error; err != nil {
+        return fiber.NewError(fiber.StatusInternalServerError, "failed to check sku")
+    }
+    if taken > 0 {
+        return fiber.NewError(fiber.StatusConflict, "sku already in use")
+    }
+
+    variant.SKU = sku
+    variant.Attributes = attributes
+    variant.Price = data.Price
+    variant.Stock = data.Stock
+    return nil
+}
+
+// lockProductVariant loads the :variantId variant of a product FOR UPDATE
+func lockProductVariant(tx *gorm.DB, c *fiber.Ctx, productID uint) (*models.ProductVariant, *fiber.Error) {
+    variantID, err := strconv.Atoi(c.Params("variantId"))
+    if err != nil || variantID <= 0 {
+        return nil, fiber.NewError(fiber.StatusBadRequest, "invalid variant ID")
+    }
+
+    variants, err := models.LockVariants(tx, []uint{uint(variantID)})
+    if err != nil {
+        return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to fetch variant")
+    }
+    if len(variants) == 0 || variants[0].ProductID != productID {
+        return nil, fiber.NewError(fiber.StatusNotFound, "variant not found")
+    }
+    return &variants[0], nil
+}
+
+func variantError(c *fiber.Ctx, err error, message string) error {
+    var ferr *fiber.Error
+    if errors.As(err, &ferr) {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+    log.Printf("Variant change failed: %v", err)
+    return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+        "error": message,
+    })
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"ambassador/src/audit"
+	"ambassador/src/database"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"ambassador/src/scheduler"
+	"ambassador/src/search"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevertProductRequest struct {
+    Version uint `json:"version" validate:"required,gt=0"`
+}
+
+type PricePoint struct {
+    Version   uint      `json:"version"`
+    Price     float64   `json:"price"`
+    ChangedAt time.Time `json:"changed_at"`
+}
+
+// ProductHistory lists every version of a product (newest first) and its price history
+// GET /api/admin/products/:id/history
+func ProductHistory(c *fiber.Ctx) error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid product ID",
+        })
+    }
+
+    // Deleted products keep their history
+    var product models.Product
+    if err := database.DB.Unscoped().First(&product, id).Error; err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+            "error": "product not found",
+        })
+    }
+
+    var versions []models.ProductVersion
+    if err := database.DB.
+        WithContext(c.Context()).
+        Where("product_id = ?", product.ID).
+        Order("version ASC").
+        Find(&versions).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch product history",
+        })
+    }
+
+    // Only versions where the price actually moved
+    prices := []PricePoint{}
+    for i, version := range versions {
+        if i == 0 || version.Price != versions[i-1].Price {
+            prices = append(prices, PricePoint{
+                Version:   version.Version,
+                Price:     version.Price,
+                ChangedAt: version.CreatedAt,
+            })
+        }
+    }
+
+    // Newest first for display
+    for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+        versions[i], versions[j] = versions[j], versions[i]
+    }
+
+    return c.JSON(fiber.Map{
+        "product_id":      product.ID,
+        "current_version": product.Version,
+        "deleted":         product.DeletedAt.Valid,
+        "versions":        versions,
+        "price_history":   prices,
+    })
+}
+
+// RevertProduct restores a product's fields from an earlier version.
+// The revert itself becomes a new version, so history is never rewritten.
+// POST /api/admin/products/:id/revert
+func RevertProduct(c *fiber.Ctx) error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid product ID",
+        })
+    }
+
+    var data RevertProductRequest
+    if err := c.BodyParser(&data); err != nil || data.Version == 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "version is required",
+        })
+    }
+
+    var product models.Product
+    var before ProductResponse
+    var version *models.ProductVersion
+    actorID := actorIDPtr(c)
+    err = database.DB.Transaction(func(tx *gorm.DB) error {
+        // Lock the row so the version check, the snapshot and the write see
+        // the same product as concurrent updates, sale changes and image syncs
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return fiber.NewError(fiber.StatusNotFound, "product not found")
+            }
+            return err
+        }
+
+        var target models.ProductVersion
+        if err := tx.
+            Where("product_id = ? AND version = ?", product.ID, data.Version).
+            First(&target).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return fiber.NewError(fiber.StatusNotFound, "version not found")
+            }
+            return err
+        }
+
+        if data.Version == product.Version {
+            return fiber.NewError(fiber.StatusConflict, "product is already at this version")
+        }
+
+        before = toProductResponse(&product)
+        product.Title = target.Title
+        product.Description = target.Description
+        product.Image = target.Image
+        product.Price = target.Price
+        product.SalePrice = target.SalePrice
+        product.SaleStartsAt = target.SaleStartsAt
+        product.SaleEndsAt = target.SaleEndsAt
+
+        if err := tx.Model(&models.Product{}).
+            Where("id = ?", product.ID).
+            Updates(map[string]interface{}{
+                "title":          product.Title,
+                "description":    product.Description,
+                "image":          product.Image,
+                "price":          product.Price,
+                "sale_price":     product.SalePrice,
+                "sale_starts_at": product.SaleStartsAt,
+                "sale_ends_at":   product.SaleEndsAt,
+            }).Error; err != nil {
+            return err
+        }
+
+        var err error
+        version, err = models.RecordProductVersion(tx, &product, models.ProductChangeRevert, actorID, &target.Version)
+        return err
+    })
+    if err != nil {
+        var ferr *fiber.Error
+        if errors.As(err, &ferr) {
+            return c.Status(ferr.Code).JSON(fiber.Map{
+                "error": ferr.Message,
+            })
+        }
+        log.Printf("Failed to revert product %d to version %d: %v", id, data.Version, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to revert product",
+        })
+    }
+
+    database.ClearProductCaches(c.Context(), product.ID)
+    scheduler.NotifySaleChanged()
+    search.IndexProduct(&product)
+
+    audit.Record(c, audit.Entry{
+        Action:     "product.revert",
+        TargetType: "product",
+        TargetID:   product.ID,
+        Before:     before,
+        After:      toProductResponse(&product),
+    })
+
+    return c.JSON(fiber.Map{
+        "message":       "product reverted successfully",
+        "data":          toProductResponse(&product),
+        "version":       version,
+        "cache_cleared": true,
+    })
+}
+
+// actorIDPtr returns the authenticated user's ID for "changed by" columns
+func actorIDPtr(c *fiber.Ctx) *uint {
+    id, err := middlewares.GetUserID(c)
+    if err != nil {
+        return nil
+    }
+    return &id
+}
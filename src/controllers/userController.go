@@ -1,10 +1,20 @@
 package controllers
 
 import (
+	"ambassador/src/audit"
 	"ambassador/src/database"
+	"ambassador/src/middlewares"
 	"ambassador/src/models"
+	"ambassador/src/ratelimit"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type AmbassadorResponse struct {
@@ -77,4 +87,445 @@ func Rankings(c *fiber.Ctx) error {
         "count": len(rankings),
         "is_success": true,
     })
-}
\ No newline at end of file
+}
+// UnlockUser clears a login lockout and failure history for a user
+// POST /api/admin/users/:id/unlock
+func UnlockUser(c *fiber.Ctx) error {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid user ID",
+        })
+    }
+
+    var user models.User
+    if err := database.DB.First(&user, id).Error; err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+            "error": "user not found",
+        })
+    }
+
+    if err := ratelimit.Unlock(c.Context(), user.Email); err != nil {
+        log.Printf("Failed to unlock user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to unlock user",
+        })
+    }
+
+    log.Printf("User %d unlocked", user.ID)
+
+    audit.Record(c, audit.Entry{
+        Action:     "user.unlock",
+        TargetType: "user",
+        TargetID:   user.ID,
+    })
+
+    return c.JSON(fiber.Map{
+        "message": "user unlocked successfully",
+        "user_id": user.ID,
+    })
+}
+
+// likeEscaper escapes LIKE wildcards in user supplied search terms
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+type AdminUserResponse struct {
+    ID              uint       `json:"id"`
+    FirstName       string     `json:"first_name"`
+    LastName        string     `json:"last_name"`
+    Email           string     `json:"email"`
+    Role            string     `json:"role"`
+    EmailVerifiedAt *time.Time `json:"email_verified_at"`
+    MFAEnabled      bool       `json:"mfa_enabled"`
+    SuspendedAt     *time.Time `json:"suspended_at"`
+    SuspendedReason string     `json:"suspended_reason,omitempty"`
+    CreatedAt       time.Time  `json:"created_at"`
+    DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+type UserListResponse struct {
+    TotalCount int64               `json:"total_count"`
+    Data       []AdminUserResponse `json:"data"`
+    Query      string              `json:"query"`
+    Page       int                 `json:"page"`
+    PerPage    int                 `json:"per_page"`
+    LastPage   int                 `json:"last_page"`
+}
+
+type SuspendUserRequest struct {
+    Reason string `json:"reason" validate:"max=255"`
+}
+
+type UpdateRoleRequest struct {
+    Role string `json:"role" validate:"required,oneof=admin ambassador"`
+}
+
+// Users lists admins and ambassadors with search, filters and pagination
+// GET /api/admin/users?s=&role=admin|ambassador&status=active|suspended|deleted&page=&per_page=
+func Users(c *fiber.Ctx) error {
+    page := max(1, atoi(c.Query("page", "1")))
+    perPage := clamp(atoi(c.Query("per_page", "20")), 1, 100)
+    searchQuery := strings.TrimSpace(c.Query("s", ""))
+    role := c.Query("role")
+    status := c.Query("status")
+
+    db := database.DB.WithContext(c.Context()).Model(&models.User{})
+
+    switch status {
+    case "":
+    case "active":
+        db = db.Where("suspended_at IS NULL")
+    case "suspended":
+        db = db.Where("suspended_at IS NOT NULL")
+    case "deleted":
+        db = db.Unscoped().Where("deleted_at IS NOT NULL")
+    default:
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "status must be active, suspended or deleted",
+        })
+    }
+
+    switch role {
+    case "":
+    case ScopeAdmin:
+        db = db.Where("is_ambassador = ?", false)
+    case ScopeAmbassador:
+        db = db.Where("is_ambassador = ?", true)
+    default:
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "role must be admin or ambassador",
+        })
+    }
+
+    if searchQuery != "" {
+        searchTerm := "%" + likeEscaper.Replace(strings.ToLower(searchQuery)) + "%"
+        if id, err := strconv.Atoi(searchQuery); err == nil {
+            db = db.Where("id = ? OR LOWER(email) LIKE ?", id, searchTerm)
+        } else {
+            db = db.Where("LOWER(email) LIKE ? OR LOWER(CONCAT(first_name, ' ', last_name)) LIKE ?", searchTerm, searchTerm)
+        }
+    }
+
+    var total int64
+    if err := db.Count(&total).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to count users",
+        })
+    }
+
+    var users []models.User
+    if err := db.
+        Order("id ASC").
+        Offset((page - 1) * perPage).
+        Limit(perPage).
+        Find(&users).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to fetch users",
+        })
+    }
+
+    data := make([]AdminUserResponse, len(users))
+    for i := range users {
+        data[i] = toAdminUserResponse(&users[i])
+    }
+
+    return c.JSON(UserListResponse{
+        TotalCount: total,
+        Data:       data,
+        Query:      searchQuery,
+        Page:       page,
+        PerPage:    perPage,
+        LastPage:   int(math.Ceil(float64(total) / float64(perPage))),
+    })
+}
+
+// SuspendUser blocks a user from logging in and invalidates their sessions and API keys
+// POST /api/admin/users/:id/suspend
+func SuspendUser(c *fiber.Ctx) error {
+    user, ferr := findManagedUser(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var data SuspendUserRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    data.Reason = strings.TrimSpace(data.Reason)
+    if len(data.Reason) > 255 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "reason must be at most 255 characters",
+        })
+    }
+
+    if user.IsSuspended() {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "user is already suspended",
+        })
+    }
+
+    // Suspending the last active admin would lock everyone out
+    if !user.IsAmbassador {
+        if err := ensureOtherActiveAdmin(user.ID); err != nil {
+            return adminGuardError(c, err)
+        }
+    }
+
+    now := time.Now().Truncate(time.Second)
+    if err := database.DB.Model(&models.User{}).
+        Where("id = ?", user.ID).
+        Updates(map[string]interface{}{
+            "suspended_at":        now,
+            "suspended_reason":    data.Reason,
+            "sessions_revoked_at": now,
+        }).Error; err != nil {
+        log.Printf("Failed to suspend user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to suspend user",
+        })
+    }
+    before := toAdminUserResponse(user)
+    user.SuspendedAt = &now
+    user.SuspendedReason = data.Reason
+    recordUserChange(c, "user.suspend", before, user)
+
+    adminID, _ := middlewares.GetUserID(c)
+    log.Printf("Admin %d suspended user %d", adminID, user.ID)
+
+    return c.JSON(toAdminUserResponse(user))
+}
+
+// UnsuspendUser lifts a suspension; the user has to log in again
+// POST /api/admin/users/:id/unsuspend
+func UnsuspendUser(c *fiber.Ctx) error {
+    user, ferr := findManagedUser(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    if !user.IsSuspended() {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "user is not suspended",
+        })
+    }
+
+    if err := database.DB.Model(&models.User{}).
+        Where("id = ?", user.ID).
+        Updates(map[string]interface{}{
+            "suspended_at":     nil,
+            "suspended_reason": "",
+        }).Error; err != nil {
+        log.Printf("Failed to unsuspend user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to unsuspend user",
+        })
+    }
+    before := toAdminUserResponse(user)
+    user.SuspendedAt = nil
+    user.SuspendedReason = ""
+    recordUserChange(c, "user.unsuspend", before, user)
+
+    adminID, _ := middlewares.GetUserID(c)
+    log.Printf("Admin %d unsuspended user %d", adminID, user.ID)
+
+    return c.JSON(toAdminUserResponse(user))
+}
+
+// UpdateUserRole promotes an ambassador to admin or demotes an admin to ambassador.
+// Existing sessions carry the old scope, so they are revoked; API keys are revoked
+// on promotion since admins can't use them.
+// PUT /api/admin/users/:id/role
+func UpdateUserRole(c *fiber.Ctx) error {
+    user, ferr := findManagedUser(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    var data UpdateRoleRequest
+    if err := c.BodyParser(&data); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid request body",
+        })
+    }
+
+    if data.Role != ScopeAdmin && data.Role != ScopeAmbassador {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "role must be admin or ambassador",
+        })
+    }
+
+    isAmbassador := data.Role == ScopeAmbassador
+    if user.IsAmbassador == isAmbassador {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "user already has this role",
+        })
+    }
+
+    if isAmbassador {
+        if err := ensureOtherActiveAdmin(user.ID); err != nil {
+            return adminGuardError(c, err)
+        }
+    }
+
+    now := time.Now().Truncate(time.Second)
+    err := database.DB.Transaction(func(tx *gorm.DB) error {
+        if !isAmbassador {
+            if err := tx.Model(&models.APIKey{}).
+                Where("user_id = ? AND revoked_at IS NULL", user.ID).
+                Update("revoked_at", now).Error; err != nil {
+                return err
+            }
+        }
+
+        return tx.Model(&models.User{}).
+            Where("id = ?", user.ID).
+            Updates(map[string]interface{}{
+                "is_ambassador":       isAmbassador,
+                "sessions_revoked_at": now,
+            }).Error
+    })
+    if err != nil {
+        log.Printf("Failed to change role of user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to update role",
+        })
+    }
+    before := toAdminUserResponse(user)
+    user.IsAmbassador = isAmbassador
+    recordUserChange(c, "user.role", before, user)
+
+    adminID, _ := middlewares.GetUserID(c)
+    log.Printf("Admin %d changed role of user %d to %s", adminID, user.ID, data.Role)
+
+    return c.JSON(toAdminUserResponse(user))
+}
+
+// DeleteUser soft-deletes a user; their orders and revenue history are kept
+// DELETE /api/admin/users/:id
+func DeleteUser(c *fiber.Ctx) error {
+    user, ferr := findManagedUser(c)
+    if ferr != nil {
+        return c.Status(ferr.Code).JSON(fiber.Map{
+            "error": ferr.Message,
+        })
+    }
+
+    if !user.IsAmbassador {
+        if err := ensureOtherActiveAdmin(user.ID); err != nil {
+            return adminGuardError(c, err)
+        }
+    }
+
+    if err := database.DB.Delete(&models.User{}, user.ID).Error; err != nil {
+        log.Printf("Failed to delete user %d: %v", user.ID, err)
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+            "error": "failed to delete user",
+        })
+    }
+
+    audit.Record(c, audit.Entry{
+        Action:     "user.delete",
+        TargetType: "user",
+        TargetID:   user.ID,
+        Before:     toAdminUserResponse(user),
+    })
+
+    adminID, _ := middlewares.GetUserID(c)
+    log.Printf("Admin %d deleted user %d", adminID, user.ID)
+
+    return c.JSON(fiber.Map{
+        "message": "user deleted successfully",
+        "user_id": user.ID,
+    })
+}
+
+// findManagedUser loads the :id user for an admin action; admins can't act on themselves
+func findManagedUser(c *fiber.Ctx) (*models.User, *fiber.Error) {
+    id, err := strconv.Atoi(c.Params("id"))
+    if err != nil || id <= 0 {
+        return nil, fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+    }
+
+    if adminID, _ := middlewares.GetUserID(c); adminID == uint(id) {
+        return nil, fiber.NewError(fiber.StatusBadRequest, "you can't perform this action on your own account")
+    }
+
+    var user models.User
+    if err := database.DB.First(&user, id).Error; err != nil {
+        return nil, fiber.NewError(fiber.StatusNotFound, "user not found")
+    }
+
+    return &user, nil
+}
+
+// adminGuardError maps ensureOtherActiveAdmin failures to a response
+func adminGuardError(c *fiber.Ctx, err error) error {
+    if errors.Is(err, errLastAdmin) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": "at least one other active admin is required",
+        })
+    }
+    log.Printf("Admin count failed: %v", err)
+    return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+        "error": "failed to check admins",
+    })
+}
+
+// ensureOtherActiveAdmin fails if userID is the only unsuspended admin
+func ensureOtherActiveAdmin(userID uint) error {
+    var others int64
+    if err := database.DB.Model(&models.User{}).
+        Where("is_ambassador = ? AND suspended_at IS NULL AND id <> ?", false, userID).
+        Count(&others).Error; err != nil {
+        return err
+    }
+    if others == 0 {
+        return errLastAdmin
+    }
+    return nil
+}
+
+// recordUserChange audits an admin change to user against its earlier state
+func recordUserChange(c *fiber.Ctx, action string, before AdminUserResponse, user *models.User) {
+    audit.Record(c, audit.Entry{
+        Action:     action,
+        TargetType: "user",
+        TargetID:   user.ID,
+        Before:     before,
+        After:      toAdminUserResponse(user),
+    })
+}
+
+func toAdminUserResponse(user *models.User) AdminUserResponse {
+    role := ScopeAdmin
+    if user.IsAmbassador {
+        role = ScopeAmbassador
+    }
+
+    response := AdminUserResponse{
+        ID:              user.ID,
+        FirstName:       user.FirstName,
+        LastName:        user.LastName,
+        Email:           user.Email,
+        Role:            role,
+        EmailVerifiedAt: user.EmailVerifiedAt,
+        MFAEnabled:      user.MFAEnabled(),
+        SuspendedAt:     user.SuspendedAt,
+        SuspendedReason: user.SuspendedReason,
+        CreatedAt:       user.CreatedAt,
+    }
+    if user.DeletedAt.Valid {
+        response.DeletedAt = &user.DeletedAt.Time
+    }
+
+    return response
+}
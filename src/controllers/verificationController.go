@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/mailer"
+	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifyEmail confirms ownership of an email address
+// GET /api/verify-email?token=...
+func VerifyEmail(c *fiber.Ctx) error {
+    token := c.Query("token")
+    if token == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "token is required",
+        })
+    }
+
+    userID, email, err := middlewares.ParseEmailVerificationToken(token)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid or expired verification link",
+        })
+    }
+
+    var user models.User
+    if err := database.DB.First(&user, userID).Error; err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid or expired verification link",
+        })
+    }
+
+    // Token was issued for a previous email address
+    if user.Email != email {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "invalid or expired verification link",
+        })
+    }
+
+    if !user.IsEmailVerified() {
+        now := time.Now()
+        if err := database.DB.Model(&user).Update("email_verified_at", now).Error; err != nil {
+            log.Printf("Email verification failed for user %d: %v", user.ID, err)
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+                "error": "failed to verify email",
+            })
+        }
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "email verified successfully",
+    })
+}
+
+// ResendVerification emails a fresh verification link to the authenticated user
+// POST /api/{admin,ambassador}/verify-email/resend
+func ResendVerification(c *fiber.Ctx) error {
+    user, err := middlewares.GetUser(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    if user.IsEmailVerified() {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+            "error": "email already verified",
+        })
+    }
+
+    sendVerificationEmail(user)
+
+    return c.JSON(fiber.Map{
+        "message": "verification email sent",
+    })
+}
+
+// sendVerificationEmail mails a signed verification link in the background
+func sendVerificationEmail(user *models.User) {
+    token, err := middlewares.GenerateEmailVerificationToken(user.ID, user.Email)
+    if err != nil {
+        log.Printf("Verification token generation failed for user %d: %v", user.ID, err)
+        return
+    }
+
+    cfg := config.Get()
+    link := fmt.Sprintf("%s/api/verify-email?token=%s", cfg.AppURL, url.QueryEscape(token))
+
+    msg := mailer.Message{
+        To:      user.Email,
+        Subject: "Verify your email address",
+        Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours.\n",
+            user.FirstName, link, cfg.EmailVerificationExpireHours),
+    }
+
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        defer cancel()
+
+        if err := mailer.Send(ctx, msg); err != nil {
+            log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+        }
+    }()
+}
@@ -140,13 +140,34 @@ func AutoMigrate() error {
     if err := DB.AutoMigrate(
         &models.User{},
         &models.Product{},
+        &models.ProductVersion{},
+        &models.Category{},
+        &models.Tag{},
         &models.Link{},
         &models.Order{},
         &models.OrderItem{},
+        &models.ProductStat{},
+        &models.ProductVariant{},
+        &models.ProductImage{},
+        &models.StockReservation{},
+        &models.ProductImport{},
+        &models.AdminInvite{},
+        &models.PasswordReset{},
+        &models.RecoveryCode{},
+        &models.MFAChallenge{},
+        &models.APIKey{},
+        &models.UserIdentity{},
+        &models.PasswordHistory{},
+        &models.Impersonation{},
+        &models.AuditLog{},
     ); err != nil {
         return fmt.Errorf("auto migrate failed: %w", err)
     }
 
+    if err := RunMigrations(); err != nil {
+        return fmt.Errorf("migrations failed: %w", err)
+    }
+
     log.Println("Database migrated successfully")
     return nil
 }
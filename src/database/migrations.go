@@ -0,0 +1,116 @@
+package database
+
+import (
+	"ambassador/src/models"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigration records a migration that has been applied
+type SchemaMigration struct {
+    Version   string    `gorm:"primaryKey;size:100"`
+    AppliedAt time.Time `gorm:"not null"`
+}
+
+// migration is a schema change AutoMigrate can't express (FULLTEXT indexes,
+// index changes, data fixes). Versions sort by date and are never renamed;
+// each migration must be safe to re-run if it fails halfway, since MySQL DDL
+// is not transactional.
+type migration struct {
+    Version string
+    Up      func(db *gorm.DB) error
+}
+
+// emailVerificationReleasedAt is when signups started needing a verified email
+var emailVerificationReleasedAt = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+// migrations run in order after AutoMigrate; append new ones at the end
+var migrations = []migration{
+    {
+        Version: "20261018_01_products_fulltext",
+        Up: func(db *gorm.DB) error {
+            if db.Migrator().HasIndex(&models.Product{}, models.ProductFulltextIndex) {
+                return nil
+            }
+            return db.Exec("ALTER TABLE products ADD FULLTEXT INDEX " + models.ProductFulltextIndex + " (title, description)").Error
+        },
+    },
+    {
+        // Seed the sales summary from existing orders
+        Version: "20261018_02_product_stats_backfill",
+        Up: func(db *gorm.DB) error {
+            return models.RefreshProductStats(db)
+        },
+    },
+    {
+        // Checkout creates many orders per link code; the index was unique
+        Version: "20261018_03_orders_code_non_unique",
+        Up: func(db *gorm.DB) error {
+            var nonUnique []int
+            if err := db.Raw("SELECT non_unique FROM information_schema.statistics"+
+                " WHERE table_schema = DATABASE() AND table_name = 'orders' AND index_name = 'idx_orders_code'").
+                Scan(&nonUnique).Error; err != nil {
+                return err
+            }
+            if len(nonUnique) > 0 && nonUnique[0] == 1 {
+                return nil
+            }
+            if len(nonUnique) > 0 {
+                if err := db.Exec("ALTER TABLE orders DROP INDEX idx_orders_code").Error; err != nil {
+                    return err
+                }
+            }
+            return db.Exec("CREATE INDEX idx_orders_code ON orders (code)").Error
+        },
+    },
+    {
+        // Accounts from before email verification count as verified at signup,
+        // otherwise existing ambassadors can no longer create links. The fixed
+        // cutoff (the release of verification) keeps signups made since then
+        // unverified, whenever a deployment gets round to this migration.
+        Version: "20261018_04_users_email_verified_backfill",
+        Up: func(db *gorm.DB) error {
+            return db.Exec("UPDATE users SET email_verified_at = created_at"+
+                " WHERE email_verified_at IS NULL AND created_at < ?", emailVerificationReleasedAt).Error
+        },
+    },
+}
+
+// RunMigrations applies pending migrations and records them in schema_migrations
+func RunMigrations() error {
+    if DB == nil {
+        return fmt.Errorf("database not initialized")
+    }
+
+    if err := DB.AutoMigrate(&SchemaMigration{}); err != nil {
+        return fmt.Errorf("schema_migrations table: %w", err)
+    }
+
+    var applied []string
+    if err := DB.Model(&SchemaMigration{}).Pluck("version", &applied).Error; err != nil {
+        return fmt.Errorf("load applied migrations: %w", err)
+    }
+    done := make(map[string]bool, len(applied))
+    for _, version := range applied {
+        done[version] = true
+    }
+
+    for _, m := range migrations {
+        if done[m.Version] {
+            continue
+        }
+
+        log.Printf("Applying migration %s", m.Version)
+        if err := m.Up(DB); err != nil {
+            return fmt.Errorf("migration %s: %w", m.Version, err)
+        }
+        if err := DB.Create(&SchemaMigration{Version: m.Version, AppliedAt: time.Now()}).Error; err != nil {
+            return fmt.Errorf("record migration %s: %w", m.Version, err)
+        }
+    }
+
+    return nil
+}
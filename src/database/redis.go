@@ -87,6 +87,7 @@ func ClearProductCaches(ctx context.Context, productIDs ...uint) {
         // ================================================================
         patterns := []string{
             "products:p:*",      // All paginated caches (legacy)
+            "products:c:*",      // All cursor-paginated caches
             "products:v2:p:*",   // All v2 paginated caches
         }
         
@@ -140,7 +141,7 @@ func ClearProductCachesSync(ctx context.Context, productIDs ...uint) error {
     }
     
     // Delete paginated caches
-    patterns := []string{"products:p:*", "products:v2:p:*"}
+    patterns := []string{"products:p:*", "products:c:*", "products:v2:p:*"}
     for _, pattern := range patterns {
         keys, err := Redis.Keys(ctx, pattern).Result()
         if err != nil {
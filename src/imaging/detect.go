@@ -0,0 +1,48 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Format is an image type recognized by its magic bytes
+type Format string
+
+const (
+    JPEG Format = "jpeg"
+    PNG  Format = "png"
+    GIF  Format = "gif"
+)
+
+var (
+    ErrUnsupported = errors.New("unsupported image type; upload a JPEG, PNG or GIF")
+    ErrCorrupt     = errors.New("image data is corrupt or truncated")
+    ErrTooLarge    = errors.New("image dimensions are too large")
+)
+
+// Detect identifies the image type from the leading bytes; the file name and
+// the client's Content-Type are never trusted
+func Detect(data []byte) (Format, error) {
+    switch {
+    case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+        return JPEG, nil
+    case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+        return PNG, nil
+    case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+        return GIF, nil
+    }
+    return "", ErrUnsupported
+}
+
+// ContentType is the MIME type of the format
+func (f Format) ContentType() string {
+    return "image/" + string(f)
+}
+
+// Ext is the file extension used in storage keys
+func (f Format) Ext() string {
+    if f == JPEG {
+        return "jpg"
+    }
+    return string(f)
+}
@@ -0,0 +1,107 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// jpegOrientation reads the EXIF orientation (1-8) of a JPEG; 1 when absent.
+// Phones store photos sideways and rely on this tag, so it is applied before
+// the metadata is dropped by re-encoding.
+func jpegOrientation(data []byte) int {
+    pos := 2 // after SOI
+    for pos+4 <= len(data) {
+        if data[pos] != 0xFF {
+            return 1
+        }
+        marker := data[pos+1]
+        if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+            return 1
+        }
+        length := int(binary.BigEndian.Uint16(data[pos+2:]))
+        end := pos + 2 + length
+        if length < 2 || end > len(data) {
+            return 1
+        }
+        if marker == 0xE1 && length >= 8 && string(data[pos+4:pos+10]) == "Exif\x00\x00" {
+            return tiffOrientation(data[pos+10 : end])
+        }
+        pos = end
+    }
+    return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+    if len(tiff) < 8 {
+        return 1
+    }
+    var order binary.ByteOrder
+    switch string(tiff[:2]) {
+    case "II":
+        order = binary.LittleEndian
+    case "MM":
+        order = binary.BigEndian
+    default:
+        return 1
+    }
+
+    ifd := int(order.Uint32(tiff[4:]))
+    if ifd+2 > len(tiff) {
+        return 1
+    }
+    count := int(order.Uint16(tiff[ifd:]))
+    for i := 0; i < count; i++ {
+        entry := ifd + 2 + i*12
+        if entry+12 > len(tiff) {
+            return 1
+        }
+        if order.Uint16(tiff[entry:]) == 0x0112 {
+            if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+                return v
+            }
+            return 1
+        }
+    }
+    return 1
+}
+
+// orient turns src upright according to an EXIF orientation
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+    if orientation <= 1 || orientation > 8 {
+        return src
+    }
+
+    b := src.Bounds()
+    w, h := b.Dx(), b.Dy()
+    dw, dh := w, h
+    if orientation >= 5 {
+        dw, dh = h, w
+    }
+    dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+    for sy := 0; sy < h; sy++ {
+        for sx := 0; sx < w; sx++ {
+            var dx, dy int
+            switch orientation {
+            case 2: // mirrored
+                dx, dy = w-1-sx, sy
+            case 3: // upside down
+                dx, dy = w-1-sx, h-1-sy
+            case 4: // upside down, mirrored
+                dx, dy = sx, h-1-sy
+            case 5: // transposed
+                dx, dy = sy, sx
+            case 6: // rotated 90° clockwise to view
+                dx, dy = h-1-sy, sx
+            case 7: // transversed
+                dx, dy = h-1-sy, w-1-sx
+            case 8: // rotated 90° counter-clockwise to view
+                dx, dy = sy, w-1-sx
+            }
+            si := src.PixOffset(b.Min.X+sx, b.Min.Y+sy)
+            di := dst.PixOffset(dx, dy)
+            copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+        }
+    }
+    return dst
+}
@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Size is a rendition generated for every upload, bounded by MaxSide pixels
+type Size struct {
+    Name    string
+    MaxSide int
+}
+
+// Sizes are the renditions generated besides the full-size original
+var Sizes = []Size{
+    {Name: "thumb", MaxSide: 150},
+    {Name: "small", MaxSide: 320},
+    {Name: "medium", MaxSide: 640},
+    {Name: "large", MaxSide: 1280},
+}
+
+// Original is the name of the full-size rendition
+const Original = "original"
+
+const jpegQuality = 85
+
+// Rendition is an encoded version of an uploaded image
+type Rendition struct {
+    Name   string
+    Width  int
+    Height int
+    Format Format
+    Data   []byte
+}
+
+// Process validates an upload and encodes the original and every Size.
+// Everything is re-encoded: metadata such as GPS coordinates never reaches
+// storage, and the stored bytes are known to be a clean image. Opaque images
+// become JPEGs, images with transparency PNGs.
+func Process(data []byte, maxPixels int) ([]Rendition, error) {
+    format, err := Detect(data)
+    if err != nil {
+        return nil, err
+    }
+
+    // Dimensions are checked before decoding allocates the pixels
+    cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+    if err != nil {
+        return nil, ErrCorrupt
+    }
+    if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+        return nil, ErrTooLarge
+    }
+
+    var decoded image.Image
+    switch format {
+    case JPEG:
+        decoded, err = jpeg.Decode(bytes.NewReader(data))
+    case PNG:
+        decoded, err = png.Decode(bytes.NewReader(data))
+    case GIF:
+        decoded, err = gif.Decode(bytes.NewReader(data)) // first frame
+    }
+    if err != nil {
+        return nil, ErrCorrupt
+    }
+
+    img := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+    draw.Draw(img, img.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+    if format == JPEG {
+        img = orient(img, jpegOrientation(data))
+    }
+
+    out := JPEG
+    if !img.Opaque() {
+        out = PNG
+    }
+
+    renditions := make([]Rendition, 0, len(Sizes)+1)
+    original, err := encode(Original, img, out)
+    if err != nil {
+        return nil, err
+    }
+    renditions = append(renditions, original)
+
+    src := img
+    for i := len(Sizes) - 1; i >= 0; i-- {
+        // Each size is scaled from the next larger one, which is cheaper than from the original
+        src = Fit(src, Sizes[i].MaxSide)
+        rendition, err := encode(Sizes[i].Name, src, out)
+        if err != nil {
+            return nil, err
+        }
+        renditions = append(renditions, rendition)
+    }
+    return renditions, nil
+}
+
+func encode(name string, img *image.RGBA, format Format) (Rendition, error) {
+    var buf bytes.Buffer
+    var err error
+    if format == PNG {
+        err = png.Encode(&buf, img)
+    } else {
+        err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+    }
+    if err != nil {
+        return Rendition{}, err
+    }
+
+    b := img.Bounds()
+    return Rendition{Name: name, Width: b.Dx(), Height: b.Dy(), Format: format, Data: buf.Bytes()}, nil
+}
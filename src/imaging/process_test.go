@@ -0,0 +1,199 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int, alpha uint8) *image.NRGBA {
+    img := image.NewNRGBA(image.Rect(0, 0, w, h))
+    for y := 0; y < h; y++ {
+        for x := 0; x < w; x++ {
+            img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha})
+        }
+    }
+    return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, img); err != nil {
+        t.Fatalf("png.Encode: %v", err)
+    }
+    return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    if err := jpeg.Encode(&buf, img, nil); err != nil {
+        t.Fatalf("jpeg.Encode: %v", err)
+    }
+    return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    if err := gif.Encode(&buf, img, nil); err != nil {
+        t.Fatalf("gif.Encode: %v", err)
+    }
+    return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying orientation after the SOI marker
+func withOrientation(data []byte, orientation byte) []byte {
+    tiff := []byte{
+        'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, // little endian, IFD at 8
+        0x01, 0x00, // one entry
+        0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, orientation, 0x00, 0x00, 0x00, // Orientation, SHORT
+        0x00, 0x00, 0x00, 0x00, // no next IFD
+    }
+    payload := append([]byte("Exif\x00\x00"), tiff...)
+    length := len(payload) + 2
+    segment := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+
+    out := append([]byte{}, data[:2]...)
+    out = append(out, segment...)
+    return append(out, data[2:]...)
+}
+
+func TestDetect(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+        want Format
+        err  error
+    }{
+        {"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, JPEG, nil},
+        {"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), PNG, nil},
+        {"gif87a", []byte("GIF87a..."), GIF, nil},
+        {"gif89a", []byte("GIF89a..."), GIF, nil},
+        {"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "", ErrUnsupported},
+        {"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), "", ErrUnsupported},
+        {"png extension only", []byte("PNG\r\n"), "", ErrUnsupported},
+        {"truncated jpeg marker", []byte{0xFF, 0xD8}, "", ErrUnsupported},
+        {"empty", nil, "", ErrUnsupported},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := Detect(tt.data)
+            if got != tt.want || !errors.Is(err, tt.err) {
+                t.Errorf("Detect = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+            }
+        })
+    }
+}
+
+func TestProcess(t *testing.T) {
+    tests := []struct {
+        name      string
+        data      func(t *testing.T) []byte
+        maxPixels int
+        format    Format
+        width     int // of the original rendition
+        height    int
+        err       error
+    }{
+        {"opaque png becomes jpeg", func(t *testing.T) []byte { return encodePNG(t, testImage(400, 200, 255)) }, 1_000_000, JPEG, 400, 200, nil},
+        {"transparent png stays png", func(t *testing.T) []byte { return encodePNG(t, testImage(300, 300, 100)) }, 1_000_000, PNG, 300, 300, nil},
+        {"jpeg", func(t *testing.T) []byte { return encodeJPEG(t, testImage(2000, 1000, 255)) }, 4_000_000, JPEG, 2000, 1000, nil},
+        {"gif first frame", func(t *testing.T) []byte { return encodeGIF(t, testImage(64, 32, 255)) }, 1_000_000, JPEG, 64, 32, nil},
+        {"exif rotated jpeg is turned upright", func(t *testing.T) []byte { return withOrientation(encodeJPEG(t, testImage(120, 60, 255)), 6) }, 1_000_000, JPEG, 60, 120, nil},
+        {"at the pixel limit", func(t *testing.T) []byte { return encodePNG(t, testImage(100, 100, 255)) }, 10_000, JPEG, 100, 100, nil},
+        {"over the pixel limit", func(t *testing.T) []byte { return encodePNG(t, testImage(101, 100, 255)) }, 10_000, "", 0, 0, ErrTooLarge},
+        {"huge declared size", func(t *testing.T) []byte { return pngHeader(100000, 100000) }, 40_000_000, "", 0, 0, ErrTooLarge},
+        {"truncated png", func(t *testing.T) []byte { data := encodePNG(t, testImage(50, 50, 255)); return data[:len(data)/2] }, 1_000_000, "", 0, 0, ErrCorrupt},
+        {"garbage after magic bytes", func(t *testing.T) []byte { return append([]byte{0xFF, 0xD8, 0xFF}, bytes.Repeat([]byte{0x42}, 64)...) }, 1_000_000, "", 0, 0, ErrCorrupt},
+        {"not an image", func(t *testing.T) []byte { return []byte("%PDF-1.7") }, 1_000_000, "", 0, 0, ErrUnsupported},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            renditions, err := Process(tt.data(t), tt.maxPixels)
+            if tt.err != nil {
+                if !errors.Is(err, tt.err) {
+                    t.Fatalf("err = %v, want %v", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("Process: %v", err)
+            }
+
+            if len(renditions) != len(Sizes)+1 {
+                t.Fatalf("got %d renditions, want %d", len(renditions), len(Sizes)+1)
+            }
+            bounds := map[string]int{Original: max(tt.width, tt.height)}
+            for _, size := range Sizes {
+                bounds[size.Name] = size.MaxSide
+            }
+            for _, r := range renditions {
+                maxSide, ok := bounds[r.Name]
+                if !ok {
+                    t.Errorf("unexpected rendition %q", r.Name)
+                    continue
+                }
+                delete(bounds, r.Name)
+
+                if r.Format != tt.format {
+                    t.Errorf("%s: format %q, want %q", r.Name, r.Format, tt.format)
+                }
+                if r.Width > maxSide || r.Height > maxSide {
+                    t.Errorf("%s: %dx%d exceeds %d", r.Name, r.Width, r.Height, maxSide)
+                }
+                // Aspect ratio kept (within rounding)
+                if got, want := r.Width*tt.height, r.Height*tt.width; abs(got-want) > max(tt.width, tt.height) {
+                    t.Errorf("%s: %dx%d does not keep the %dx%d aspect ratio", r.Name, r.Width, r.Height, tt.width, tt.height)
+                }
+
+                detected, err := Detect(r.Data)
+                if err != nil || detected != tt.format {
+                    t.Errorf("%s: stored bytes detected as %q, %v", r.Name, detected, err)
+                }
+                cfg, _, err := image.DecodeConfig(bytes.NewReader(r.Data))
+                if err != nil {
+                    t.Fatalf("%s: stored bytes do not decode: %v", r.Name, err)
+                }
+                if cfg.Width != r.Width || cfg.Height != r.Height {
+                    t.Errorf("%s: encoded as %dx%d, reported %dx%d", r.Name, cfg.Width, cfg.Height, r.Width, r.Height)
+                }
+                if r.Name == Original && (r.Width != tt.width || r.Height != tt.height) {
+                    t.Errorf("original is %dx%d, want %dx%d", r.Width, r.Height, tt.width, tt.height)
+                }
+            }
+            if len(bounds) > 0 {
+                t.Errorf("missing renditions: %v", bounds)
+            }
+        })
+    }
+}
+
+// pngHeader is the start of a PNG declaring w×h pixels, without pixel data
+func pngHeader(w, h uint32) []byte {
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+        panic(err)
+    }
+    data := buf.Bytes()
+    // IHDR width and height follow the 8 byte signature and the chunk's length and type
+    data[16], data[17], data[18], data[19] = byte(w>>24), byte(w>>16), byte(w>>8), byte(w)
+    data[20], data[21], data[22], data[23] = byte(h>>24), byte(h>>16), byte(h>>8), byte(h)
+    binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+    return data
+}
+
+func abs(n int) int {
+    if n < 0 {
+        return -n
+    }
+    return n
+}
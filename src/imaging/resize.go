@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Fit scales src down so its longer side is at most maxSide, keeping the
+// aspect ratio. Images already small enough are returned as they are.
+func Fit(src *image.RGBA, maxSide int) *image.RGBA {
+    b := src.Bounds()
+    w, h := b.Dx(), b.Dy()
+    if w <= maxSide && h <= maxSide {
+        return src
+    }
+
+    dw, dh := maxSide, maxSide
+    if w >= h {
+        dh = max(1, int(math.Round(float64(h)*float64(maxSide)/float64(w))))
+    } else {
+        dw = max(1, int(math.Round(float64(w)*float64(maxSide)/float64(h))))
+    }
+    return Resize(src, dw, dh)
+}
+
+// Resize resamples src to w×h with a triangle filter widened by the scale
+// factor, so downscaling averages every source pixel instead of skipping
+// rows. Pixels are premultiplied (image.RGBA), which keeps transparent edges
+// from bleeding dark fringes.
+func Resize(src *image.RGBA, w, h int) *image.RGBA {
+    b := src.Bounds()
+    if b.Dx() == w && b.Dy() == h {
+        return src
+    }
+
+    // Horizontal pass into a float buffer, then vertical into the result
+    xw := filterWeights(b.Dx(), w)
+    tmp := make([]float64, w*b.Dy()*4)
+    for y := 0; y < b.Dy(); y++ {
+        row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+        for x, cw := range xw {
+            var r, g, bl, a float64
+            for i, weight := range cw.weights {
+                p := row[(cw.start+i)*4:]
+                r += float64(p[0]) * weight
+                g += float64(p[1]) * weight
+                bl += float64(p[2]) * weight
+                a += float64(p[3]) * weight
+            }
+            t := tmp[(y*w+x)*4:]
+            t[0], t[1], t[2], t[3] = r, g, bl, a
+        }
+    }
+
+    yw := filterWeights(b.Dy(), h)
+    dst := image.NewRGBA(image.Rect(0, 0, w, h))
+    for y, cw := range yw {
+        for x := 0; x < w; x++ {
+            var r, g, bl, a float64
+            for i, weight := range cw.weights {
+                t := tmp[((cw.start+i)*w+x)*4:]
+                r += t[0] * weight
+                g += t[1] * weight
+                bl += t[2] * weight
+                a += t[3] * weight
+            }
+            d := dst.Pix[dst.PixOffset(x, y):]
+            d[0], d[1], d[2], d[3] = clampByte(r), clampByte(g), clampByte(bl), clampByte(a)
+        }
+    }
+    return dst
+}
+
+type contribution struct {
+    start   int
+    weights []float64
+}
+
+// filterWeights computes, for each destination pixel, the source pixels it
+// covers and their normalized triangle-filter weights
+func filterWeights(srcLen, dstLen int) []contribution {
+    scale := float64(srcLen) / float64(dstLen)
+    radius := math.Max(scale, 1)
+
+    contributions := make([]contribution, dstLen)
+    for i := range contributions {
+        center := (float64(i) + 0.5) * scale
+        lo := max(0, int(math.Floor(center-radius)))
+        hi := min(srcLen-1, int(math.Ceil(center+radius)))
+
+        weights := make([]float64, 0, hi-lo+1)
+        var sum float64
+        for j := lo; j <= hi; j++ {
+            weight := 1 - math.Abs((float64(j)+0.5-center)/radius)
+            if weight < 0 {
+                weight = 0
+            }
+            weights = append(weights, weight)
+            sum += weight
+        }
+        if sum == 0 {
+            // Destination pixel between two samples of an upscale
+            weights = []float64{1}
+            lo = min(int(center), srcLen-1)
+        } else {
+            for k := range weights {
+                weights[k] /= sum
+            }
+        }
+        contributions[i] = contribution{start: lo, weights: weights}
+    }
+    return contributions
+}
+
+func clampByte(v float64) uint8 {
+    switch {
+    case v <= 0:
+        return 0
+    case v >= 255:
+        return 255
+    }
+    return uint8(v + 0.5)
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// LogMailer writes messages to a file (or the application log) for local development
+type LogMailer struct {
+    Path string // empty = application log
+    From string
+
+    mu sync.Mutex
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    raw := buildMessage(m.From, msg)
+
+    if m.Path == "" {
+        log.Printf("Mail to %s:\n%s", msg.To, raw)
+        return nil
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+    if err != nil {
+        return fmt.Errorf("open mail log: %w", err)
+    }
+    defer f.Close()
+
+    if _, err := fmt.Fprintf(f, "%s\r\n----------------------------------------\r\n", raw); err != nil {
+        return fmt.Errorf("write mail log: %w", err)
+    }
+
+    return nil
+}
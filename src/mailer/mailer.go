@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"ambassador/src/config"
+	"context"
+	"fmt"
+)
+
+// Message is a plain-text email
+type Message struct {
+    To      string
+    Subject string
+    Body    string
+}
+
+// Mailer delivers messages; implementations must be safe for concurrent use
+type Mailer interface {
+    Send(ctx context.Context, msg Message) error
+}
+
+var Default Mailer
+
+// Setup picks the mailer implementation from config (called from main)
+func Setup(cfg *config.Config) error {
+    m, err := New(cfg)
+    if err != nil {
+        return err
+    }
+
+    Default = m
+    return nil
+}
+
+// New builds the mailer selected by MAIL_DRIVER
+func New(cfg *config.Config) (Mailer, error) {
+    switch cfg.MailDriver {
+    case "smtp":
+        return &SMTPMailer{
+            Host:     cfg.SMTPHost,
+            Port:     cfg.SMTPPort,
+            Username: cfg.SMTPUsername,
+            Password: cfg.SMTPPassword,
+            From:     cfg.MailFrom,
+        }, nil
+    case "log", "":
+        return &LogMailer{Path: cfg.MailLogPath, From: cfg.MailFrom}, nil
+    default:
+        return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+    }
+}
+
+// Send delivers msg with the default mailer
+func Send(ctx context.Context, msg Message) error {
+    if Default == nil {
+        return fmt.Errorf("mailer not initialized")
+    }
+    return Default.Send(ctx, msg)
+}
@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"ambassador/src/utils"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyPrefix marks ambassador API keys: amb_<8 char id>_<secret>
+const APIKeyPrefix = "amb_"
+
+// Last-used timestamps are written at most this often per key
+const apiKeyTouchInterval = time.Minute
+
+// authenticateAPIKey authenticates a request carrying an X-API-Key header
+func authenticateAPIKey(c *fiber.Ctx, rawKey string) error {
+    if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+        return invalidAPIKey(c)
+    }
+
+    var key models.APIKey
+    if err := database.DB.
+        Preload("User").
+        Where("key_hash = ?", utils.HashToken(rawKey)).
+        First(&key).Error; err != nil {
+        return invalidAPIKey(c)
+    }
+
+    now := time.Now()
+    // Keys belong to ambassadors only; a promoted user's keys stop working
+    if !key.IsActive(now) || key.User.ID == 0 || !key.User.IsAmbassador {
+        return invalidAPIKey(c)
+    }
+    if key.User.IsSuspended() {
+        return accountSuspended(c)
+    }
+
+    touchAPIKey(&key, c.IP(), now)
+
+    c.Locals("user", &key.User)
+    c.Locals("user_id", strconv.Itoa(int(key.UserID)))
+    c.Locals("scope", "ambassador")
+    c.Locals("api_key", &key)
+
+    return c.Next()
+}
+
+// touchAPIKey records last use in the background, throttled per key
+func touchAPIKey(key *models.APIKey, ip string, now time.Time) {
+    if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval && key.LastUsedIP == ip {
+        return
+    }
+
+    go func(id uint) {
+        if err := database.DB.Model(&models.APIKey{}).
+            Where("id = ?", id).
+            Updates(map[string]interface{}{
+                "last_used_at": now,
+                "last_used_ip": ip,
+            }).Error; err != nil {
+            log.Printf("Failed to record API key %d usage: %v", id, err)
+        }
+    }(key.ID)
+}
+
+func invalidAPIKey(c *fiber.Ctx) error {
+    return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+        "success": false,
+        "error":   "UNAUTHORIZED",
+        "message": "Invalid, expired or revoked API key",
+        "code":    "INVALID_API_KEY",
+        "status":  401,
+    })
+}
+
+// GetAPIKey returns the API key used for this request, if any
+func GetAPIKey(c *fiber.Ctx) (*models.APIKey, bool) {
+    key, ok := c.Locals("api_key").(*models.APIKey)
+    return key, ok
+}
+
+// RequireAPIKeyScope lets browser sessions through and checks the scope of API keys
+func RequireAPIKeyScope(scope string) fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        key, ok := GetAPIKey(c)
+        if !ok || key.HasScope(scope) {
+            return c.Next()
+        }
+
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "success": false,
+            "error":   "FORBIDDEN",
+            "message": fmt.Sprintf("API key lacks the %s scope", scope),
+            "code":    "INSUFFICIENT_API_KEY_SCOPE",
+            "status":  403,
+        })
+    }
+}
+
+// RequireSession rejects API key and impersonation authentication
+// (account management needs the user's own login)
+func RequireSession(c *fiber.Ctx) error {
+    _, viaKey := GetAPIKey(c)
+    _, impersonated := GetImpersonatorID(c)
+    if viaKey || impersonated {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+            "success": false,
+            "error":   "FORBIDDEN",
+            "message": "This endpoint is not available to API keys or impersonation sessions",
+            "code":    "SESSION_REQUIRED",
+            "status":  403,
+        })
+    }
+    return c.Next()
+}
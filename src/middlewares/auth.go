@@ -4,7 +4,9 @@ import (
 	"ambassador/src/config"
 	"ambassador/src/database"
 	"ambassador/src/models"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -16,10 +18,16 @@ import (
 // Custom claims with scope
 type ClaimsWithScope struct {
     jwt.RegisteredClaims
-    Scope string `json:"scope"`
+    Scope          string `json:"scope"`
+    ImpersonatorID uint   `json:"imp,omitempty"` // admin acting as this user
 }
 
 func IsAuthenticated(c *fiber.Ctx) error {
+    // Personal API keys (integrations) take precedence over cookies
+    if apiKey := c.Get("X-API-Key"); apiKey != "" {
+        return authenticateAPIKey(c, apiKey)
+    }
+
     // Get token from cookie or Authorization header
     cookie := c.Cookies("jwt")
     if cookie == "" {
@@ -61,7 +69,45 @@ func IsAuthenticated(c *fiber.Ctx) error {
         })
     }
 
+    // Tokens issued before a password reset are no longer valid
+    var user models.User
+    if err := database.DB.Where("id = ?", claims.Subject).First(&user).Error; err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "unauthenticated",
+        })
+    }
+
+    if claims.IssuedAt == nil || user.SessionRevoked(claims.IssuedAt.Time) {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "success": false,
+            "error":   "UNAUTHORIZED",
+            "message": "Session has been revoked",
+            "code":    "SESSION_REVOKED",
+            "status":  401,
+        })
+    }
+
+    if user.IsSuspended() {
+        return accountSuspended(c)
+    }
+
+    // Impersonation tokens die with their audit record
+    if claims.ImpersonatorID != 0 {
+        if err := checkImpersonation(claims, &user); err != nil {
+            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+                "success": false,
+                "error":   "UNAUTHORIZED",
+                "message": "Impersonation session has ended",
+                "code":    "IMPERSONATION_ENDED",
+                "status":  401,
+            })
+        }
+        log.Printf("Impersonation: admin %d as user %d %s %s", claims.ImpersonatorID, user.ID, c.Method(), c.Path())
+        c.Locals("impersonator_id", claims.ImpersonatorID)
+    }
+
     // Store claims in context
+    c.Locals("user", &user)
     c.Locals("user_id", claims.Subject)
     c.Locals("scope", claims.Scope)
     c.Locals("claims", claims)
@@ -69,6 +115,36 @@ func IsAuthenticated(c *fiber.Ctx) error {
     return c.Next()
 }
 
+// accountSuspended rejects requests from suspended accounts
+func accountSuspended(c *fiber.Ctx) error {
+    return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+        "success": false,
+        "error":   "FORBIDDEN",
+        "message": "Account is suspended",
+        "code":    "ACCOUNT_SUSPENDED",
+        "status":  403,
+    })
+}
+
+// checkImpersonation verifies the impersonation record behind a token is still active
+// and that the admin who started it still is an active admin
+func checkImpersonation(claims *ClaimsWithScope, user *models.User) error {
+    var impersonation models.Impersonation
+    if err := database.DB.
+        Preload("Admin").
+        Where("token_id = ? AND admin_id = ? AND user_id = ?", claims.ID, claims.ImpersonatorID, user.ID).
+        First(&impersonation).Error; err != nil {
+        return err
+    }
+
+    admin := impersonation.Admin
+    if !impersonation.IsActive(time.Now()) || admin.ID == 0 || admin.IsAmbassador || admin.IsSuspended() {
+        return errors.New("impersonation is no longer active")
+    }
+
+    return nil
+}
+
 // Scope protection middleware
 func RequireScope(requiredScope string) fiber.Handler {
     return func(c *fiber.Ctx) error {
@@ -104,6 +180,11 @@ func GetUserID(c *fiber.Ctx) (uint, error) {
 
 // GetUser retrieves the full user from database using context
 func GetUser(c *fiber.Ctx) (*models.User, error) {
+    // Already loaded by IsAuthenticated
+    if user, ok := c.Locals("user").(*models.User); ok {
+        return user, nil
+    }
+
     userID, err := GetUserID(c)
     if err != nil {
         return nil, err
@@ -117,6 +198,12 @@ func GetUser(c *fiber.Ctx) (*models.User, error) {
     return &user, nil
 }
 
+// GetImpersonatorID returns the admin acting as the current user, if any
+func GetImpersonatorID(c *fiber.Ctx) (uint, bool) {
+    id, ok := c.Locals("impersonator_id").(uint)
+    return id, ok && id != 0
+}
+
 // GetScope retrieves scope from context
 func GetScope(c *fiber.Ctx) (string, error) {
     scope, ok := c.Locals("scope").(string)
@@ -156,4 +243,28 @@ func GenerateJWT(c *fiber.Ctx, id uint, scope string) error {
     
     c.Cookie(cookie)
     return nil
-}
\ No newline at end of file
+}
+// GenerateImpersonationToken signs a bearer token for adminID acting as userID.
+// tokenID must match the Impersonation record that audits it.
+func GenerateImpersonationToken(userID, adminID uint, tokenID string, expiresAt time.Time) (string, error) {
+    cfg := config.Get()
+
+    claims := ClaimsWithScope{
+        RegisteredClaims: jwt.RegisteredClaims{
+            ID:        tokenID,
+            Subject:   strconv.Itoa(int(userID)),
+            ExpiresAt: jwt.NewNumericDate(expiresAt),
+            IssuedAt:  jwt.NewNumericDate(time.Now()),
+        },
+        Scope:          "ambassador",
+        ImpersonatorID: adminID,
+    }
+
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    signedToken, err := token.SignedString([]byte(cfg.JWTSecret))
+    if err != nil {
+        return "", fmt.Errorf("JWT signing failed: %w", err)
+    }
+
+    return signedToken, nil
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultBodyLimit caps request bodies on routes without a larger limit
+// (fiber's own default)
+const DefaultBodyLimit = 4 << 20
+
+// BodyLimit caps request bodies at the limit limitFor returns for the request.
+// The server streams bodies larger than DefaultBodyLimit (StreamRequestBody),
+// so an oversized body is refused here before it is read.
+func BodyLimit(limitFor func(c *fiber.Ctx) int) fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        limit := limitFor(c)
+
+        length := c.Request().Header.ContentLength()
+        if length > limit {
+            return bodyTooLarge(c)
+        }
+
+        // Chunked bodies have no length up front: read them up to the limit
+        if stream := c.Context().RequestBodyStream(); length < 0 && stream != nil {
+            body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+            if err != nil {
+                return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                    "error": "could not read the request body",
+                })
+            }
+            if len(body) > limit {
+                return bodyTooLarge(c)
+            }
+            c.Request().SetBodyRaw(body)
+        }
+        return c.Next()
+    }
+}
+
+// bodyTooLarge responds 413 and closes the connection, as the rest of the
+// body is never read
+func bodyTooLarge(c *fiber.Ctx) error {
+    c.Context().SetConnectionClose()
+    return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+        "error": "request body too large",
+    })
+}
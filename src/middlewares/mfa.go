@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"ambassador/src/config"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Admin routes still reachable before MFA enrollment when it's mandatory
+var mfaEnrollmentPaths = []string{
+    "/api/admin/mfa",
+    "/api/admin/user",
+    "/api/admin/logout",
+}
+
+// RequireMFAEnrollment blocks admins without TOTP when MFA_REQUIRE_ADMIN is set
+func RequireMFAEnrollment(c *fiber.Ctx) error {
+    if !config.Get().MFARequireAdmin {
+        return c.Next()
+    }
+
+    user, err := GetUser(c)
+    if err != nil || user.IsAmbassador || user.MFAEnabled() {
+        return c.Next()
+    }
+
+    path := c.Path()
+    for _, allowed := range mfaEnrollmentPaths {
+        if path == allowed || strings.HasPrefix(path, allowed+"/") {
+            return c.Next()
+        }
+    }
+
+    return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+        "success": false,
+        "error":   "FORBIDDEN",
+        "message": "Two-factor authentication must be enabled for admin accounts",
+        "code":    "MFA_ENROLLMENT_REQUIRED",
+        "status":  403,
+    })
+}
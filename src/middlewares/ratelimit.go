@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"ambassador/src/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LoginRateLimit limits login-style requests per client IP (sliding window)
+func LoginRateLimit(c *fiber.Ctx) error {
+    if wait := ratelimit.HitIP(c.Context(), c.IP()); wait > 0 {
+        return TooManyRequests(c, wait)
+    }
+    return c.Next()
+}
+
+// CheckoutRateLimit limits checkout orders per client IP (sliding window)
+func CheckoutRateLimit(c *fiber.Ctx) error {
+    if wait := ratelimit.HitCheckoutIP(c.Context(), c.IP()); wait > 0 {
+        return TooManyRequests(c, wait)
+    }
+    return c.Next()
+}
+
+// TooManyRequests responds 429 with a Retry-After header
+func TooManyRequests(c *fiber.Ctx, wait time.Duration) error {
+    seconds := int(wait.Seconds())
+    c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+
+    return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+        "error":       "Too many requests. Please try again later.",
+        "retry_after": seconds,
+    })
+}
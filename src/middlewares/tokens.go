@@ -0,0 +1,176 @@
+package middlewares
+
+import (
+	"ambassador/src/config"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Purposes for single-purpose signed tokens (never accepted by IsAuthenticated)
+const (
+    PurposeEmailVerification = "email_verification"
+    PurposeMFAChallenge      = "mfa_challenge"
+    PurposeOIDCFlow          = "oidc_flow"
+)
+
+// PurposeClaims are signed with the JWT secret but carry no scope
+type PurposeClaims struct {
+    jwt.RegisteredClaims
+    Purpose string `json:"purpose"`
+    Email   string `json:"email,omitempty"`
+}
+
+// OIDCFlowClaims carry the per-login OIDC state in a signed cookie
+type OIDCFlowClaims struct {
+    jwt.RegisteredClaims
+    Purpose      string `json:"purpose"`
+    State        string `json:"state"`
+    Nonce        string `json:"nonce"`
+    CodeVerifier string `json:"cv"`
+}
+
+// GenerateEmailVerificationToken signs a token proving ownership of email for userID
+func GenerateEmailVerificationToken(userID uint, email string) (string, error) {
+    ttl := time.Duration(config.Get().EmailVerificationExpireHours) * time.Hour
+    return signPurposeToken(PurposeEmailVerification, userID, email, ttl)
+}
+
+// ParseEmailVerificationToken returns the user ID and email bound to a verification token
+func ParseEmailVerificationToken(tokenStr string) (uint, string, error) {
+    claims, err := parsePurposeToken(tokenStr, PurposeEmailVerification)
+    if err != nil {
+        return 0, "", err
+    }
+
+    userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+    if err != nil {
+        return 0, "", fmt.Errorf("invalid subject: %w", err)
+    }
+
+    return uint(userID), claims.Email, nil
+}
+
+// GenerateMFAChallengeToken signs the short-lived token returned by Login when a
+// second factor is needed; challengeID ties it to its models.MFAChallenge
+func GenerateMFAChallengeToken(userID uint, challengeID string, expiresAt time.Time) (string, error) {
+    claims := PurposeClaims{
+        RegisteredClaims: jwt.RegisteredClaims{
+            ID:        challengeID,
+            Subject:   strconv.Itoa(int(userID)),
+            ExpiresAt: jwt.NewNumericDate(expiresAt),
+            IssuedAt:  jwt.NewNumericDate(time.Now()),
+        },
+        Purpose: PurposeMFAChallenge,
+    }
+
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    signed, err := token.SignedString([]byte(config.Get().JWTSecret))
+    if err != nil {
+        return "", fmt.Errorf("token signing failed: %w", err)
+    }
+
+    return signed, nil
+}
+
+// ParseMFAChallengeToken returns the user ID that passed the password step
+// and the challenge ID, which the caller must consume
+func ParseMFAChallengeToken(tokenStr string) (uint, string, error) {
+    claims, err := parsePurposeToken(tokenStr, PurposeMFAChallenge)
+    if err != nil {
+        return 0, "", err
+    }
+    if claims.ID == "" {
+        return 0, "", fmt.Errorf("missing challenge ID")
+    }
+
+    userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+    if err != nil {
+        return 0, "", fmt.Errorf("invalid subject: %w", err)
+    }
+
+    return uint(userID), claims.ID, nil
+}
+
+// GenerateOIDCFlowToken signs the state, nonce and PKCE verifier of an OIDC login
+func GenerateOIDCFlowToken(state, nonce, verifier string, ttl time.Duration) (string, error) {
+    claims := OIDCFlowClaims{
+        RegisteredClaims: jwt.RegisteredClaims{
+            ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+            IssuedAt:  jwt.NewNumericDate(time.Now()),
+        },
+        Purpose:      PurposeOIDCFlow,
+        State:        state,
+        Nonce:        nonce,
+        CodeVerifier: verifier,
+    }
+
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    signed, err := token.SignedString([]byte(config.Get().JWTSecret))
+    if err != nil {
+        return "", fmt.Errorf("token signing failed: %w", err)
+    }
+
+    return signed, nil
+}
+
+// ParseOIDCFlowToken validates the OIDC flow cookie
+func ParseOIDCFlowToken(tokenStr string) (*OIDCFlowClaims, error) {
+    token, err := jwt.ParseWithClaims(tokenStr, &OIDCFlowClaims{}, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+        }
+        return []byte(config.Get().JWTSecret), nil
+    })
+    if err != nil || !token.Valid {
+        return nil, fmt.Errorf("invalid or expired token")
+    }
+
+    claims, ok := token.Claims.(*OIDCFlowClaims)
+    if !ok || claims.Purpose != PurposeOIDCFlow {
+        return nil, fmt.Errorf("invalid token purpose")
+    }
+
+    return claims, nil
+}
+
+func signPurposeToken(purpose string, userID uint, email string, ttl time.Duration) (string, error) {
+    claims := PurposeClaims{
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject:   strconv.Itoa(int(userID)),
+            ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+            IssuedAt:  jwt.NewNumericDate(time.Now()),
+        },
+        Purpose: purpose,
+        Email:   email,
+    }
+
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    signed, err := token.SignedString([]byte(config.Get().JWTSecret))
+    if err != nil {
+        return "", fmt.Errorf("token signing failed: %w", err)
+    }
+
+    return signed, nil
+}
+
+func parsePurposeToken(tokenStr, purpose string) (*PurposeClaims, error) {
+    token, err := jwt.ParseWithClaims(tokenStr, &PurposeClaims{}, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+        }
+        return []byte(config.Get().JWTSecret), nil
+    })
+    if err != nil || !token.Valid {
+        return nil, fmt.Errorf("invalid or expired token")
+    }
+
+    claims, ok := token.Claims.(*PurposeClaims)
+    if !ok || claims.Purpose != purpose {
+        return nil, fmt.Errorf("invalid token purpose")
+    }
+
+    return claims, nil
+}
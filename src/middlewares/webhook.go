@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebhookSignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>"
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// webhookTolerance is how old (or early) a signed webhook may be
+const webhookTolerance = 5 * time.Minute
+
+// RequireCheckoutWebhook accepts only requests signed with CHECKOUT_WEBHOOK_SECRET,
+// i.e. sent by the payment provider; see utils.VerifyWebhookSignature
+func RequireCheckoutWebhook(c *fiber.Ctx) error {
+    secret := config.Get().CheckoutWebhookSecret
+    if secret == "" {
+        return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+            "error": "payment confirmation is not configured",
+        })
+    }
+
+    if err := utils.VerifyWebhookSignature(secret, c.Get(WebhookSignatureHeader), c.Body(), time.Now(), webhookTolerance); err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+            "error": "invalid or missing webhook signature",
+        })
+    }
+    return c.Next()
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes an ambassador can grant to a personal API key
+const (
+    APIKeyScopeProfileRead  = "profile:read"
+    APIKeyScopeStatsRead    = "stats:read"
+    APIKeyScopeOrdersRead   = "orders:read"
+    APIKeyScopeRankingsRead = "rankings:read"
+    APIKeyScopeLinksWrite   = "links:write"
+)
+
+var APIKeyScopes = []string{
+    APIKeyScopeProfileRead,
+    APIKeyScopeStatsRead,
+    APIKeyScopeOrdersRead,
+    APIKeyScopeRankingsRead,
+    APIKeyScopeLinksWrite,
+}
+
+// APIKey is a personal access key for integrations; only its hash is stored
+type APIKey struct {
+    Model
+    UserID     uint       `gorm:"index;not null" json:"user_id"`
+    Name       string     `gorm:"size:100;not null" json:"name"`
+    Prefix     string     `gorm:"size:16;index;not null" json:"prefix"` // public part shown in listings
+    KeyHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+    Scopes     string     `gorm:"size:255;not null" json:"-"` // comma-separated
+    ExpiresAt  *time.Time `json:"expires_at"`
+    LastUsedAt *time.Time `json:"last_used_at"`
+    LastUsedIP string     `gorm:"size:45" json:"last_used_ip"`
+    RevokedAt  *time.Time `json:"revoked_at"`
+
+    User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ScopeList returns the granted scopes
+func (k *APIKey) ScopeList() []string {
+    if k.Scopes == "" {
+        return []string{}
+    }
+    return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+    for _, s := range k.ScopeList() {
+        if s == scope {
+            return true
+        }
+    }
+    return false
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive(now time.Time) bool {
+    if k.RevokedAt != nil {
+        return false
+    }
+    return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// IsValidAPIKeyScope reports whether scope is a known API key scope
+func IsValidAPIKeyScope(scope string) bool {
+    for _, s := range APIKeyScopes {
+        if s == scope {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog is an append-only record of an admin mutation. Rows are hash
+// chained: Hash covers the row's content and PrevHash, the previous row's Hash.
+type AuditLog struct {
+    ID             uint            `gorm:"primaryKey" json:"id"`
+    CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+    ActorID        *uint           `gorm:"index" json:"actor_id"`
+    ActorEmail     string          `gorm:"size:255" json:"actor_email"`
+    ImpersonatorID *uint           `json:"impersonator_id,omitempty"`
+    Action         string          `gorm:"size:100;index;not null" json:"action"` // e.g. product.update
+    TargetType     string          `gorm:"size:50;index:idx_audit_target" json:"target_type"`
+    TargetID       string          `gorm:"size:64;index:idx_audit_target" json:"target_id"`
+    Changes        json.RawMessage `gorm:"type:text" json:"changes"` // {"field": {"from": .., "to": ..}}
+    IP             string          `gorm:"size:45" json:"ip"`
+    RequestID      string          `gorm:"size:64;index" json:"request_id"`
+    Method         string          `gorm:"size:10" json:"method"`
+    Path           string          `gorm:"size:255" json:"path"`
+    Status         int             `json:"status"`
+    PrevHash       string          `gorm:"size:64" json:"prev_hash"`
+    Hash           string          `gorm:"size:64;index" json:"hash"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Category groups products in a tree; a product listed under a category
+// also shows up under every ancestor of that category
+type Category struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Name      string    `gorm:"size:100;not null" json:"name"`
+    Slug      string    `gorm:"size:120;uniqueIndex;not null" json:"slug"`
+    ParentID  *uint     `gorm:"index" json:"parent_id"`
+
+    Parent   *Category  `gorm:"foreignKey:ParentID" json:"-"`
+    Products []Product  `gorm:"many2many:product_categories;" json:"-"`
+}
+
+// Tag is a free-form product label
+type Tag struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Name      string    `gorm:"size:100;not null" json:"name"`
+    Slug      string    `gorm:"size:120;uniqueIndex;not null" json:"slug"`
+
+    Products []Product `gorm:"many2many:product_tags;" json:"-"`
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a name into a lowercase, dash-separated slug
+func Slugify(name string) string {
+    return strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// CategoryDescendantIDs returns id and the IDs of all categories below it.
+// The tree is small, so it is walked in memory from a single query.
+func CategoryDescendantIDs(db *gorm.DB, id uint) ([]uint, error) {
+    var categories []Category
+    if err := db.Select("id, parent_id").Find(&categories).Error; err != nil {
+        return nil, err
+    }
+
+    children := make(map[uint][]uint)
+    for _, category := range categories {
+        if category.ParentID != nil {
+            children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+        }
+    }
+
+    ids := []uint{id}
+    seen := map[uint]bool{id: true}
+    for i := 0; i < len(ids); i++ {
+        for _, child := range children[ids[i]] {
+            if !seen[child] {
+                seen[child] = true
+                ids = append(ids, child)
+            }
+        }
+    }
+    return ids, nil
+}
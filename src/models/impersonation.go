@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Impersonation records an admin acting as an ambassador for support.
+// The issued token is only valid while its row is active.
+type Impersonation struct {
+    Model
+    AdminID   uint       `gorm:"index;not null" json:"admin_id"`
+    UserID    uint       `gorm:"index;not null" json:"user_id"`
+    TokenID   string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // jti of the issued token
+    Reason    string     `gorm:"size:255;not null" json:"reason"`
+    IP        string     `gorm:"size:45" json:"ip"`
+    ExpiresAt time.Time  `json:"expires_at"`
+    EndedAt   *time.Time `json:"ended_at"`
+
+    Admin User `gorm:"foreignKey:AdminID" json:"-"`
+    User  User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// IsActive reports whether the impersonation has neither ended nor expired
+func (i *Impersonation) IsActive(now time.Time) bool {
+    return i.EndedAt == nil && now.Before(i.ExpiresAt)
+}
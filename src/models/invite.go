@@ -0,0 +1,43 @@
+package models
+
+import (
+	"ambassador/src/utils"
+	"time"
+)
+
+// AdminInvite is a single-use token that allows one email to register as admin
+type AdminInvite struct {
+    Model
+    Email       string     `gorm:"size:255;index;not null" json:"email"`
+    TokenHash   string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // SHA-256 of the token, never the token itself
+    InvitedByID *uint      `gorm:"index" json:"invited_by_id"`            // nil when created from the CLI
+    UsedByID    *uint      `gorm:"index" json:"used_by_id"`
+    ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+    UsedAt      *time.Time `json:"used_at"`
+
+    InvitedBy *User `gorm:"foreignKey:InvitedByID" json:"invited_by,omitempty"`
+    UsedBy    *User `gorm:"foreignKey:UsedByID" json:"used_by,omitempty"`
+}
+
+// IsUsable reports whether the invite can still be redeemed
+func (i *AdminInvite) IsUsable(now time.Time) bool {
+    return i.UsedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// NewAdminInvite builds an invite for email and returns it with the plain token.
+// The plain token is only available here; callers must hand it to the invitee.
+func NewAdminInvite(email string, invitedByID *uint, ttl time.Duration) (*AdminInvite, string, error) {
+    token, err := utils.GenerateToken(32)
+    if err != nil {
+        return nil, "", err
+    }
+
+    invite := &AdminInvite{
+        Email:       email,
+        TokenHash:   utils.HashToken(token),
+        InvitedByID: invitedByID,
+        ExpiresAt:   time.Now().Add(ttl),
+    }
+
+    return invite, token, nil
+}
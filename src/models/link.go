@@ -13,5 +13,6 @@ type Link struct {
     // Associations
     User     User      `json:"user" gorm:"foreignKey:UserID"`
     Products []Product `json:"products" gorm:"many2many:link_products;"` // ← Make sure this exists!
+    Variants []ProductVariant `json:"variants" gorm:"many2many:link_variants;"` // preselected variants of the linked products
     Orders   []Order   `json:"orders,omitempty" gorm:"-"`
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// MFAChallenge is the server-side record of a password-verified login that
+// still needs its second factor. The challenge token carries TokenID; a
+// challenge takes one code attempt, then a new login is needed.
+type MFAChallenge struct {
+    ID        uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time  `json:"created_at"`
+    UserID    uint       `gorm:"index;not null" json:"user_id"`
+    TokenID   string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+    ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+    UsedAt    *time.Time `json:"used_at"`
+}
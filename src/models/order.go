@@ -1,20 +1,36 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AmbassadorShare is the part of an order item's price credited to the ambassador
+const AmbassadorShare = 0.3
+
+// AnonymizedEmail replaces erased email addresses on orders and users
+const AnonymizedEmail = "erased@anonymized.invalid"
+
 type Order struct {
     Model
    TransactionID   string `gorm:"size:50;uniqueIndex" json:"transaction_id" validate:"required,min=10"`
     UserID          uint   `gorm:"index" json:"user_id" validate:"required,gt=0"`
-    Code            string `gorm:"size:10;uniqueIndex" json:"code" validate:"required,len=10"`
+    Code            string `gorm:"size:10;index" json:"code" validate:"required,len=10"` // link code; many orders per link
     AmbassadorEmail string `gorm:"size:100;index" json:"ambassador_email" validate:"omitempty,email"`
     FirstName       string `gorm:"size:50;not null" json:"-" validate:"required,min=2,max=50"`
     LastName        string `gorm:"size:50;not null" json:"-" validate:"required,min=2,max=50"`
     Name            string `gorm:"-" json:"name"`
-    Email           string `gorm:"size:100;not null" json:"email" validate:"required,email"`
+    Email           string `gorm:"size:100;not null;index" json:"email" validate:"required,email"`
     Address         string `gorm:"size:255;not null" json:"address" validate:"required,min=5"`
     City            string `gorm:"size:50;not null" json:"city" validate:"required,min=2"`
     Country         string `gorm:"size:50;not null" json:"country" validate:"required,min=2"`
     Zip             string `gorm:"size:20" json:"zip" validate:"omitempty"`
     Complete        bool   `gorm:"default:false" json:"-"`
+    ExpiresAt       *time.Time `gorm:"index" json:"expires_at,omitempty"` // unpaid checkout orders: stock held until then
+    CancelledAt     *time.Time `json:"cancelled_at,omitempty"` // checkout cancelled or expired
+    AnonymizedAt    *time.Time `json:"anonymized_at,omitempty"` // buyer PII erased, totals kept
+    RequestIP       string `gorm:"size:45;index" json:"-"` // checkout orders: client IP, for the pending-order cap
     Total float64 `json:"total" gorm:"-"`
 
 	// Relationships
@@ -30,10 +46,35 @@ type OrderItem struct {
     Quantity          uint    `gorm:"not null;default:1" json:"quantity" validate:"required,gte=1"`
     AdminRevenue      float64 `gorm:"type:decimal(10,2);not null;default:0" json:"admin_revenue" validate:"required,gte=0"`
     AmbassadorRevenue float64 `gorm:"type:decimal(10,2);not null;default:0" json:"ambassador_revenue" validate:"required,gte=0"`
+    ProductVersionID  *uint   `gorm:"index" json:"product_version_id"` // product as it was when ordered
+    VersionInferred   bool    `gorm:"-" json:"version_inferred,omitempty"` // matched by order date (items from before versioning)
+
+    // Variant as it was when ordered; the variant itself may change or be deleted
+    VariantID         *uint             `gorm:"index" json:"variant_id,omitempty"`
+    VariantSKU        string            `gorm:"size:64" json:"variant_sku,omitempty"`
+    VariantAttributes VariantAttributes `gorm:"type:text;serializer:json" json:"variant_attributes,omitempty"`
+
+    ProductVersion    *ProductVersion `gorm:"foreignKey:ProductVersionID" json:"product_version,omitempty"`
 
     Order             Order   `gorm:"foreignKey:OrderID" json:"-"`
 }
 
+// IsPending reports whether a checkout order still awaits payment
+func (order *Order) IsPending() bool {
+    return !order.Complete && order.CancelledAt == nil
+}
+
+// CountPendingCheckoutOrders counts unpaid, unexpired checkout orders placed
+// with email or from ip
+func CountPendingCheckoutOrders(db *gorm.DB, email, ip string, now time.Time) (int64, error) {
+    var count int64
+    err := db.Model(&Order{}).
+        Where("complete = ? AND cancelled_at IS NULL AND expires_at > ?", false, now).
+        Where("(email = ? OR request_ip = ?)", email, ip).
+        Count(&count).Error
+    return count, err
+}
+
 func (order *Order) FullName() string {
     return order.FirstName + " " + order.LastName
 }
@@ -48,3 +89,19 @@ func (order *Order) GetTotal() float64 {
     return total
 }
 
+// AnonymizeOrders erases buyer PII on the orders matched by db (a query scoped
+// with Where). Order items and the ambassador attribution are untouched so
+// revenue figures are preserved.
+func AnonymizeOrders(db *gorm.DB, now time.Time) (int64, error) {
+    result := db.Model(&Order{}).Updates(map[string]interface{}{
+        "first_name":    "Erased",
+        "last_name":     "Buyer",
+        "email":         AnonymizedEmail,
+        "address":       "",
+        "city":          "",
+        "zip":           "",
+        "request_ip":    "",
+        "anonymized_at": now,
+    })
+    return result.RowsAffected, result.Error
+}
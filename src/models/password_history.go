@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PasswordHistory keeps previous password hashes to prevent reuse
+type PasswordHistory struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    UserID    uint      `gorm:"index;not null" json:"user_id"`
+    Hash      []byte    `gorm:"not null" json:"-"`
+}
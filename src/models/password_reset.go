@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use, short-lived password reset token (stored hashed)
+type PasswordReset struct {
+    Model
+    UserID    uint       `gorm:"index;not null" json:"user_id"`
+    TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+    ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+    UsedAt    *time.Time `json:"used_at"`
+    RequestIP string     `gorm:"size:45" json:"request_ip"`
+
+    User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -1,12 +1,93 @@
 package models
 
+import "time"
+
 type Product struct {
     Model
     Title       string  `gorm:"size:255;not null;index" json:"title"` 
     Description string  `gorm:"type:text"`
     Image       string  `gorm:"size:500"`
     Price       float64 `gorm:"type:decimal(10,2);not null" json:"price"`
+    Version     uint    `gorm:"not null;default:0" json:"version"` // latest ProductVersion
+
+    // ExternalSKU identifies the product in an outside catalog; imports upsert by it
+    ExternalSKU *string `gorm:"size:64;uniqueIndex" json:"external_sku"`
+
+    // Inventory: Stock is the quantity on hand (nil = not tracked, always in
+    // stock); Reserved units are held by pending checkouts
+    Stock    *int `json:"stock"`
+    Reserved int  `gorm:"not null;default:0" json:"reserved"`
+
+    // Variants, when a product has any, are what checkout sells
+    Variants []ProductVariant `gorm:"foreignKey:ProductID" json:"variants,omitempty"`
+
+    // Scheduled sale: SalePrice applies between the optional start and end times
+    SalePrice    *float64   `gorm:"type:decimal(10,2)" json:"sale_price"`
+    SaleStartsAt *time.Time `gorm:"index" json:"sale_starts_at"`
+    SaleEndsAt   *time.Time `gorm:"index" json:"sale_ends_at"`
 
     // Optional: back references to links
     Links []Link `json:"links,omitempty" gorm:"many2many:link_products;"`
-}
\ No newline at end of file
+    Categories []Category `json:"categories,omitempty" gorm:"many2many:product_categories;"`
+    Tags       []Tag      `json:"tags,omitempty" gorm:"many2many:product_tags;"`
+}
+
+// ProductFulltextIndex is the FULLTEXT index on (title, description), see database migrations
+const ProductFulltextIndex = "ft_products_title_description"
+
+// EffectivePriceSQL computes the effective price in queries; bind the current time twice
+const EffectivePriceSQL = "CASE WHEN sale_price IS NOT NULL" +
+    " AND (sale_starts_at IS NULL OR sale_starts_at <= ?)" +
+    " AND (sale_ends_at IS NULL OR sale_ends_at > ?)" +
+    " THEN sale_price ELSE price END"
+
+// Available is the stock that can still be reserved (tracked products only)
+func (p *Product) Available() int {
+    if p.Stock == nil {
+        return 0
+    }
+    return *p.Stock - p.Reserved
+}
+
+// InStock reports whether the product can be ordered
+func (p *Product) InStock() bool {
+    return StockAvailable(p.Stock, p.Reserved)
+}
+
+// StockAvailable reports availability for rows loaded without a full Product
+func StockAvailable(stock *int, reserved int) bool {
+    return stock == nil || *stock-reserved > 0
+}
+
+// OnSale reports whether the sale price applies at now
+func (p *Product) OnSale(now time.Time) bool {
+    return SaleActive(p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, now)
+}
+
+// EffectivePrice is the price charged at now: the sale price during a sale, otherwise Price
+func (p *Product) EffectivePrice(now time.Time) float64 {
+    return EffectivePrice(p.Price, p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, now)
+}
+
+// SaleActive reports whether a sale is running at now. The start is inclusive,
+// the end exclusive; a missing start or end leaves that side open.
+func SaleActive(salePrice *float64, startsAt, endsAt *time.Time, now time.Time) bool {
+    if salePrice == nil {
+        return false
+    }
+    if startsAt != nil && now.Before(*startsAt) {
+        return false
+    }
+    if endsAt != nil && !now.Before(*endsAt) {
+        return false
+    }
+    return true
+}
+
+// EffectivePrice applies a sale to price, for rows loaded without a full Product
+func EffectivePrice(price float64, salePrice *float64, startsAt, endsAt *time.Time, now time.Time) float64 {
+    if SaleActive(salePrice, startsAt, endsAt, now) {
+        return *salePrice
+    }
+    return price
+}
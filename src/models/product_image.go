@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ImageRendition is one stored size of a product image
+type ImageRendition struct {
+    Key         string `json:"key"` // storage key
+    Width       int    `json:"width"`
+    Height      int    `json:"height"`
+    ContentType string `json:"content_type"`
+    Size        int    `json:"size"` // bytes
+}
+
+// ImageRenditions maps size names ("original", "thumb", ...) to renditions
+type ImageRenditions map[string]ImageRendition
+
+// ProductImage is an uploaded product image; Position orders a product's
+// images and the first one is the product's main image
+type ProductImage struct {
+    ID         uint            `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time       `json:"created_at"`
+    ProductID  uint            `gorm:"index;not null" json:"product_id"`
+    Position   int             `gorm:"not null;default:0" json:"position"`
+    Width      int             `json:"width"`
+    Height     int             `json:"height"`
+    Renditions ImageRenditions `gorm:"type:text;serializer:json" json:"renditions"`
+}
+
+// Keys lists the storage keys of every rendition
+func (img *ProductImage) Keys() []string {
+    keys := make([]string, 0, len(img.Renditions))
+    for _, rendition := range img.Renditions {
+        keys = append(keys, rendition.Key)
+    }
+    return keys
+}
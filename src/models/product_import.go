@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+    ImportQueued    = "queued"
+    ImportRunning   = "running"
+    ImportCompleted = "completed"
+    ImportFailed    = "failed" // the job itself failed; row errors are in the report
+
+    ImportActionCreate    = "create"
+    ImportActionUpdate    = "update"
+    ImportActionUnchanged = "unchanged" // matched by SKU, nothing to change
+    ImportActionError     = "error"
+)
+
+const (
+    // ImportHeartbeatInterval is how often an unfinished job's instance says it is alive
+    ImportHeartbeatInterval = 30 * time.Second
+
+    // ImportStaleAfter without a heartbeat means the job's instance is gone
+    ImportStaleAfter = 2 * time.Minute
+)
+
+// ImportRowResult is the outcome of one data row. In a dry run the action is
+// what would have happened and no product ID is assigned for creates.
+type ImportRowResult struct {
+    Row         int      `json:"row"` // 1-based, not counting the CSV header
+    ExternalSKU string   `json:"external_sku,omitempty"`
+    Action      string   `json:"action"`
+    ProductID   *uint    `json:"product_id,omitempty"`
+    Errors      []string `json:"errors,omitempty"`
+}
+
+// ProductImport is a bulk product import job, run in the background
+type ProductImport struct {
+    ID          uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+    CreatedByID *uint      `gorm:"index" json:"created_by_id"`
+    Filename    string     `gorm:"size:255" json:"filename"`
+    Format      string     `gorm:"size:10;not null" json:"format"` // csv or jsonl
+    DryRun      bool       `gorm:"not null;default:false" json:"dry_run"`
+    Status      string     `gorm:"size:20;not null;index" json:"status"`
+    Owner       string     `gorm:"size:100" json:"owner"` // instance holding the job's input in memory
+    HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+    TotalRows   int        `gorm:"not null;default:0" json:"total_rows"`
+    Processed   int        `gorm:"not null;default:0" json:"processed"`
+    Created     int        `gorm:"not null;default:0" json:"created"`
+    Updated     int        `gorm:"not null;default:0" json:"updated"`
+    Unchanged   int        `gorm:"not null;default:0" json:"unchanged"`
+    Failed      int        `gorm:"not null;default:0" json:"failed"`
+    Error       string     `gorm:"size:500" json:"error,omitempty"`
+    StartedAt   *time.Time `json:"started_at"`
+    FinishedAt  *time.Time `json:"finished_at"`
+
+    Report []ImportRowResult `gorm:"type:mediumtext;serializer:json" json:"report,omitempty"`
+}
+
+// TouchImport records a heartbeat for an unfinished job
+func TouchImport(db *gorm.DB, id uint, now time.Time) error {
+    return db.Model(&ProductImport{}).
+        Where("id = ? AND status IN ?", id, []string{ImportQueued, ImportRunning}).
+        Update("heartbeat_at", now).Error
+}
+
+// FailInterruptedImports marks queued or running jobs whose instance stopped
+// sending heartbeats as failed; their input only ever lived in that
+// instance's memory. Jobs of live instances are left alone.
+func FailInterruptedImports(db *gorm.DB, now time.Time) (int64, error) {
+    result := db.Model(&ProductImport{}).
+        Where("status IN ?", []string{ImportQueued, ImportRunning}).
+        Where("heartbeat_at IS NULL OR heartbeat_at < ?", now.Add(-ImportStaleAfter)).
+        Updates(map[string]interface{}{
+            "status":      ImportFailed,
+            "error":       "interrupted: the server running it stopped",
+            "finished_at": now,
+        })
+    return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProductStat is the denormalized sales summary of a product, derived from
+// the order items of completed orders. Rows are refreshed whenever orders
+// change and fully rebuilt by RefreshProductStats(db) without IDs.
+type ProductStat struct {
+    ProductID  uint      `gorm:"primaryKey;autoIncrement:false" json:"product_id"`
+    OrderCount int64     `gorm:"not null;default:0;index" json:"order_count"` // popularity
+    UnitsSold  int64     `gorm:"not null;default:0" json:"units_sold"`
+    Revenue    float64   `gorm:"type:decimal(12,2);not null;default:0;index" json:"revenue"`
+    UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// RefreshProductStats recomputes the stats of the given products, or of
+// every product when none are given
+func RefreshProductStats(db *gorm.DB, productIDs ...uint) error {
+    return db.Transaction(func(tx *gorm.DB) error {
+        stale := tx.Where("1 = 1")
+        if len(productIDs) > 0 {
+            stale = tx.Where("product_id IN ?", productIDs)
+        }
+        if err := stale.Delete(&ProductStat{}).Error; err != nil {
+            return err
+        }
+
+        var stats []ProductStat
+        aggregate := tx.Table("order_items").
+            Select("order_items.product_id, COUNT(DISTINCT order_items.order_id) AS order_count," +
+                " SUM(order_items.quantity) AS units_sold, SUM(order_items.price * order_items.quantity) AS revenue").
+            Joins("JOIN orders ON orders.id = order_items.order_id").
+            Where("orders.complete = ? AND orders.deleted_at IS NULL AND order_items.deleted_at IS NULL", true).
+            Group("order_items.product_id")
+        if len(productIDs) > 0 {
+            aggregate = aggregate.Where("order_items.product_id IN ?", productIDs)
+        }
+        if err := aggregate.Scan(&stats).Error; err != nil {
+            return err
+        }
+        if len(stats) == 0 {
+            return nil
+        }
+
+        return tx.CreateInBatches(stats, 500).Error
+    })
+}
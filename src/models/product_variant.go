@@ -0,0 +1,104 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VariantAttributes describe what sets a variant apart, e.g. {"size": "42", "color": "red"}
+type VariantAttributes map[string]string
+
+// Label renders the attributes in key order: "color: red, size: 42"
+func (a VariantAttributes) Label() string {
+    keys := make([]string, 0, len(a))
+    for key := range a {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    parts := make([]string, len(keys))
+    for i, key := range keys {
+        parts[i] = key + ": " + a[key]
+    }
+    return strings.Join(parts, ", ")
+}
+
+// ProductVariant is a purchasable version of a product (a size, a color).
+// A product with variants is sold per variant: checkout picks one, and stock
+// is kept on the variant (nil Stock = not tracked, like on Product).
+type ProductVariant struct {
+    ID         uint              `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time         `json:"created_at"`
+    UpdatedAt  time.Time         `json:"updated_at"`
+    ProductID  uint              `gorm:"index;not null" json:"product_id"`
+    SKU        string            `gorm:"size:64;uniqueIndex;not null" json:"sku"`
+    Attributes VariantAttributes `gorm:"type:text;serializer:json" json:"attributes"`
+    Price      *float64          `gorm:"type:decimal(10,2)" json:"price"` // nil = the product's price
+    Stock      *int              `json:"stock"`
+    Reserved   int               `gorm:"not null;default:0" json:"reserved"`
+
+    EffectivePrice float64 `gorm:"-" json:"effective_price"`
+    InStock        bool    `gorm:"-" json:"in_stock"`
+}
+
+// Available is the stock that can still be reserved (tracked variants only)
+func (v *ProductVariant) Available() int {
+    if v.Stock == nil {
+        return 0
+    }
+    return *v.Stock - v.Reserved
+}
+
+// PriceAt is what the variant costs at now. A price override replaces the
+// product price; a running product sale takes the same share off it.
+func (v *ProductVariant) PriceAt(product *Product, now time.Time) float64 {
+    if v.Price == nil {
+        return product.EffectivePrice(now)
+    }
+    if !product.OnSale(now) || product.Price <= 0 {
+        return *v.Price
+    }
+    return math.Round(*v.Price**product.SalePrice/product.Price*100) / 100
+}
+
+// Apply fills the computed price and availability fields for now
+func (v *ProductVariant) Apply(product *Product, now time.Time) {
+    v.EffectivePrice = v.PriceAt(product, now)
+    v.InStock = StockAvailable(v.Stock, v.Reserved)
+}
+
+// LockVariants loads variants FOR UPDATE in ID order. Callers lock products
+// first, then variants, so lock order is the same everywhere.
+func LockVariants(tx *gorm.DB, ids []uint) ([]ProductVariant, error) {
+    var variants []ProductVariant
+    if len(ids) == 0 {
+        return variants, nil
+    }
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("id IN ?", ids).
+        Order("id").
+        Find(&variants).Error
+    return variants, err
+}
+
+// ProductsWithVariants returns which of the given products have variants
+func ProductsWithVariants(db *gorm.DB, productIDs []uint) (map[uint]bool, error) {
+    var ids []uint
+    if err := db.Model(&ProductVariant{}).
+        Where("product_id IN ?", productIDs).
+        Distinct().
+        Pluck("product_id", &ids).Error; err != nil {
+        return nil, err
+    }
+
+    result := make(map[uint]bool, len(ids))
+    for _, id := range ids {
+        result[id] = true
+    }
+    return result, nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Product version changes
+const (
+    ProductChangeBaseline = "baseline" // state of a product created before versioning
+    ProductChangeCreate   = "create"
+    ProductChangeUpdate   = "update"
+    ProductChangeRevert   = "revert"
+    ProductChangeDelete   = "delete"
+    ProductChangeSale     = "sale" // sale price or window changed
+)
+
+// ProductVersion is an immutable snapshot of a product after each change
+type ProductVersion struct {
+    ID           uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time  `json:"created_at"`
+    ProductID    uint       `gorm:"not null;uniqueIndex:idx_product_version" json:"product_id"`
+    Version      uint       `gorm:"not null;uniqueIndex:idx_product_version" json:"version"`
+    Title        string     `gorm:"size:255;not null" json:"title"`
+    Description  string     `gorm:"type:text" json:"description"`
+    Image        string     `gorm:"size:500" json:"image"`
+    Price        float64    `gorm:"type:decimal(10,2);not null" json:"price"`
+    SalePrice    *float64   `gorm:"type:decimal(10,2)" json:"sale_price"`
+    SaleStartsAt *time.Time `json:"sale_starts_at"`
+    SaleEndsAt   *time.Time `json:"sale_ends_at"`
+    Change       string     `gorm:"size:20;not null" json:"change"`
+    RevertedFrom *uint      `json:"reverted_from,omitempty"` // version restored by a revert
+    ChangedByID  *uint      `json:"changed_by_id"`
+}
+
+// RecordProductVersion bumps the product's version and stores a snapshot of its
+// current fields. Call it inside the transaction that changed the product; the
+// row lock serializes concurrent changes to the same product.
+func RecordProductVersion(tx *gorm.DB, product *Product, change string, changedByID *uint, revertedFrom *uint) (*ProductVersion, error) {
+    var current Product
+    if err := tx.Unscoped().
+        Clauses(clause.Locking{Strength: "UPDATE"}).
+        Select("id", "version").
+        First(&current, product.ID).Error; err != nil {
+        return nil, err
+    }
+
+    next := current.Version + 1
+    if err := tx.Unscoped().Model(&Product{}).
+        Where("id = ?", product.ID).
+        UpdateColumn("version", next).Error; err != nil {
+        return nil, err
+    }
+    product.Version = next
+
+    version := &ProductVersion{
+        ProductID:    product.ID,
+        Version:      next,
+        Title:        product.Title,
+        Description:  product.Description,
+        Image:        product.Image,
+        Price:        product.Price,
+        SalePrice:    product.SalePrice,
+        SaleStartsAt: product.SaleStartsAt,
+        SaleEndsAt:   product.SaleEndsAt,
+        Change:       change,
+        RevertedFrom: revertedFrom,
+        ChangedByID:  changedByID,
+    }
+    if err := tx.Create(version).Error; err != nil {
+        return nil, err
+    }
+
+    return version, nil
+}
+
+// LatestProductVersions maps product IDs to their newest version
+func LatestProductVersions(db *gorm.DB, productIDs []uint) (map[uint]ProductVersion, error) {
+    versions := make(map[uint]ProductVersion)
+    if len(productIDs) == 0 {
+        return versions, nil
+    }
+
+    var rows []ProductVersion
+    if err := db.
+        Where("(product_id, version) IN (?)",
+            db.Model(&ProductVersion{}).
+                Select("product_id, MAX(version)").
+                Where("product_id IN ?", productIDs).
+                Group("product_id")).
+        Find(&rows).Error; err != nil {
+        return nil, err
+    }
+
+    for _, row := range rows {
+        versions[row.ProductID] = row
+    }
+    return versions, nil
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use MFA backup code (stored hashed)
+type RecoveryCode struct {
+    Model
+    UserID   uint       `gorm:"index;not null" json:"user_id"`
+    CodeHash string     `gorm:"size:64;index;not null" json:"-"`
+    UsedAt   *time.Time `json:"used_at"`
+}
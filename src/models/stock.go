@@ -0,0 +1,258 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientStock is returned by ReserveStock when a product can't cover a line
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// StockReservation holds units of a product (or one of its variants) for a
+// pending checkout order until it is paid (consumed), cancelled or expires
+// (released)
+type StockReservation struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time  `json:"created_at"`
+    OrderID    uint       `gorm:"index;not null" json:"order_id"`
+    ProductID  uint       `gorm:"index;not null" json:"product_id"`
+    VariantID  *uint      `gorm:"index" json:"variant_id"`
+    Quantity   int        `gorm:"not null" json:"quantity"`
+    ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+    ReleasedAt *time.Time `json:"released_at"`
+    ConsumedAt *time.Time `json:"consumed_at"`
+}
+
+// StockShortage describes a line ReserveStock couldn't cover
+type StockShortage struct {
+    ProductID uint  `json:"product_id"`
+    VariantID *uint `json:"variant_id,omitempty"`
+    Requested int   `json:"requested"`
+    Available int   `json:"available"`
+}
+
+// StockLine is a checkout line: units of a locked product, or of one of its
+// locked variants
+type StockLine struct {
+    Product  *Product
+    Variant  *ProductVariant // nil = the product itself
+    Quantity int
+}
+
+// tracked reports whether the line draws on counted stock
+func (l StockLine) tracked() bool {
+    if l.Variant != nil {
+        return l.Variant.Stock != nil
+    }
+    return l.Product.Stock != nil
+}
+
+func (l StockLine) available() int {
+    if l.Variant != nil {
+        return l.Variant.Available()
+    }
+    return l.Product.Available()
+}
+
+func (l StockLine) variantID() *uint {
+    if l.Variant == nil {
+        return nil
+    }
+    return &l.Variant.ID
+}
+
+// LockProducts loads products FOR UPDATE in ID order, so concurrent
+// checkouts touching the same products queue instead of deadlocking
+func LockProducts(tx *gorm.DB, ids []uint) ([]Product, error) {
+    sorted := append([]uint(nil), ids...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+    var products []Product
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("id IN ?", sorted).
+        Order("id").
+        Find(&products).Error
+    return products, err
+}
+
+// ReserveStock reserves the lines of an order. Untracked products and
+// variants (nil Stock) need no reservation. On ErrInsufficientStock nothing
+// is written and the shortages are returned. The IDs of products that ran
+// out (entirely or in a variant) are returned for cache invalidation.
+func ReserveStock(tx *gorm.DB, lines []StockLine, orderID uint, expiresAt time.Time) ([]uint, []StockShortage, error) {
+    var shortages []StockShortage
+    for _, line := range lines {
+        if line.tracked() && line.available() < line.Quantity {
+            shortages = append(shortages, StockShortage{
+                ProductID: line.Product.ID,
+                VariantID: line.variantID(),
+                Requested: line.Quantity,
+                Available: max(line.available(), 0),
+            })
+        }
+    }
+    if len(shortages) > 0 {
+        return nil, shortages, ErrInsufficientStock
+    }
+
+    var soldOut []uint
+    for _, line := range lines {
+        if !line.tracked() || line.Quantity <= 0 {
+            continue
+        }
+        var update *gorm.DB
+        if line.Variant != nil {
+            update = tx.Model(&ProductVariant{}).Where("id = ?", line.Variant.ID)
+        } else {
+            update = tx.Model(&Product{}).Where("id = ?", line.Product.ID)
+        }
+        if err := update.UpdateColumn("reserved", gorm.Expr("reserved + ?", line.Quantity)).Error; err != nil {
+            return nil, nil, err
+        }
+        if err := tx.Create(&StockReservation{
+            OrderID:   orderID,
+            ProductID: line.Product.ID,
+            VariantID: line.variantID(),
+            Quantity:  line.Quantity,
+            ExpiresAt: expiresAt,
+        }).Error; err != nil {
+            return nil, nil, err
+        }
+        if line.available() == line.Quantity {
+            soldOut = append(soldOut, line.Product.ID)
+        }
+    }
+    return soldOut, nil, nil
+}
+
+// ReleaseOrderStock returns an order's open reservations to stock (cancelled
+// or expired checkout). It returns the products that are back in stock.
+func ReleaseOrderStock(tx *gorm.DB, orderID uint, now time.Time) ([]uint, error) {
+    return settleOrderStock(tx, orderID, now, false)
+}
+
+// ConsumeOrderStock turns an order's open reservations into sold units (paid
+// checkout): stock and reserved both drop by the reserved quantity.
+func ConsumeOrderStock(tx *gorm.DB, orderID uint, now time.Time) ([]uint, error) {
+    return settleOrderStock(tx, orderID, now, true)
+}
+
+func settleOrderStock(tx *gorm.DB, orderID uint, now time.Time, consume bool) ([]uint, error) {
+    var reservations []StockReservation
+    if err := tx.Where("order_id = ? AND released_at IS NULL AND consumed_at IS NULL", orderID).
+        Find(&reservations).Error; err != nil {
+        return nil, err
+    }
+    if len(reservations) == 0 {
+        return nil, nil
+    }
+
+    var productIDs, variantIDs []uint
+    for _, reservation := range reservations {
+        if reservation.VariantID != nil {
+            variantIDs = append(variantIDs, *reservation.VariantID)
+        } else {
+            productIDs = append(productIDs, reservation.ProductID)
+        }
+    }
+    products, err := LockProducts(tx, productIDs)
+    if err != nil {
+        return nil, err
+    }
+    variants, err := LockVariants(tx, variantIDs)
+    if err != nil {
+        return nil, err
+    }
+    productWasAvailable := make(map[uint]bool, len(products))
+    for _, product := range products {
+        productWasAvailable[product.ID] = product.InStock()
+    }
+    variantWasAvailable := make(map[uint]bool, len(variants))
+    for _, variant := range variants {
+        variantWasAvailable[variant.ID] = StockAvailable(variant.Stock, variant.Reserved)
+    }
+
+    // Releasing can bring a sold-out product back; consuming never changes availability
+    var restocked []uint
+    for _, reservation := range reservations {
+        updates := map[string]interface{}{"reserved": gorm.Expr("GREATEST(reserved - ?, 0)", reservation.Quantity)}
+        column := "released_at"
+        if consume {
+            updates["stock"] = gorm.Expr("GREATEST(stock - ?, 0)", reservation.Quantity)
+            column = "consumed_at"
+        }
+
+        update := tx.Model(&Product{}).Where("id = ?", reservation.ProductID)
+        wasAvailable := productWasAvailable[reservation.ProductID]
+        if reservation.VariantID != nil {
+            update = tx.Model(&ProductVariant{}).Where("id = ?", *reservation.VariantID)
+            wasAvailable = variantWasAvailable[*reservation.VariantID]
+        }
+        if err := update.UpdateColumns(updates).Error; err != nil {
+            return nil, err
+        }
+        if err := tx.Model(&reservation).Update(column, now).Error; err != nil {
+            return nil, err
+        }
+        if !consume && !wasAvailable {
+            restocked = append(restocked, reservation.ProductID)
+        }
+    }
+    return restocked, nil
+}
+
+// CancelCheckoutOrder releases a pending order's stock and marks it cancelled.
+// The order row must be locked by the caller.
+func CancelCheckoutOrder(tx *gorm.DB, order *Order, now time.Time) ([]uint, error) {
+    restocked, err := ReleaseOrderStock(tx, order.ID, now)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Model(order).Update("cancelled_at", now).Error; err != nil {
+        return nil, err
+    }
+    return restocked, nil
+}
+
+// ExpireCheckoutOrders cancels up to limit pending orders whose reservation
+// ran out, one transaction each. It returns the products back in stock.
+func ExpireCheckoutOrders(db *gorm.DB, now time.Time, limit int) (int, []uint, error) {
+    var ids []uint
+    if err := db.Model(&Order{}).
+        Where("complete = ? AND cancelled_at IS NULL AND expires_at <= ?", false, now).
+        Order("expires_at").
+        Limit(limit).
+        Pluck("id", &ids).Error; err != nil {
+        return 0, nil, err
+    }
+
+    expired := 0
+    var restocked []uint
+    for _, id := range ids {
+        err := db.Transaction(func(tx *gorm.DB) error {
+            var order Order
+            if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+                return err
+            }
+            // Paid or cancelled since the scan
+            if !order.IsPending() {
+                return nil
+            }
+            ids, err := CancelCheckoutOrder(tx, &order, now)
+            if err != nil {
+                return err
+            }
+            expired++
+            restocked = append(restocked, ids...)
+            return nil
+        })
+        if err != nil {
+            return expired, restocked, err
+        }
+    }
+    return expired, restocked, nil
+}
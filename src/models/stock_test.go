@@ -0,0 +1,379 @@
+package models_test
+
+import (
+	"ambassador/src/models"
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestReserveStockShortages(t *testing.T) {
+    variantID := uint(30)
+    tests := []struct {
+        name  string
+        lines []models.StockLine
+        want  []models.StockShortage
+    }{
+        {
+            name: "product short",
+            lines: []models.StockLine{
+                {Product: &models.Product{Model: models.Model{ID: 1}, Stock: intPtr(5), Reserved: 3}, Quantity: 3},
+            },
+            want: []models.StockShortage{{ProductID: 1, Requested: 3, Available: 2}},
+        },
+        {
+            name: "variant short while its product has none counted",
+            lines: []models.StockLine{
+                {Product: &models.Product{Model: models.Model{ID: 2}}, Variant: &models.ProductVariant{ID: variantID, ProductID: 2, Stock: intPtr(1)}, Quantity: 2},
+            },
+            want: []models.StockShortage{{ProductID: 2, VariantID: &variantID, Requested: 2, Available: 1}},
+        },
+        {
+            name: "over-reserved stock reports zero available",
+            lines: []models.StockLine{
+                {Product: &models.Product{Model: models.Model{ID: 3}, Stock: intPtr(2), Reserved: 5}, Quantity: 1},
+            },
+            want: []models.StockShortage{{ProductID: 3, Requested: 1, Available: 0}},
+        },
+        {
+            name: "every short line is reported, covered and untracked lines are not",
+            lines: []models.StockLine{
+                {Product: &models.Product{Model: models.Model{ID: 4}, Stock: intPtr(10)}, Quantity: 10},
+                {Product: &models.Product{Model: models.Model{ID: 5}}, Quantity: 1000},
+                {Product: &models.Product{Model: models.Model{ID: 6}, Stock: intPtr(0)}, Quantity: 1},
+                {Product: &models.Product{Model: models.Model{ID: 7}, Stock: intPtr(4), Reserved: 4}, Quantity: 2},
+            },
+            want: []models.StockShortage{
+                {ProductID: 6, Requested: 1, Available: 0},
+                {ProductID: 7, Requested: 2, Available: 0},
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            // Nothing is written on a shortage, so no database is needed
+            soldOut, shortages, err := models.ReserveStock(nil, tt.lines, 1, time.Now())
+            if !errors.Is(err, models.ErrInsufficientStock) {
+                t.Fatalf("err = %v, want ErrInsufficientStock", err)
+            }
+            if soldOut != nil {
+                t.Errorf("sold out = %v on a failed reservation", soldOut)
+            }
+            if !reflect.DeepEqual(shortages, tt.want) {
+                t.Errorf("shortages:\n got %+v\nwant %+v", shortages, tt.want)
+            }
+        })
+    }
+}
+
+// The tests below need MySQL: set TEST_DATABASE_DSN to a scratch database,
+// e.g. user:pass@tcp(localhost:3306)/ambassador_test?parseTime=true.
+// Only the stock tables are migrated, and every test runs in a transaction
+// that is rolled back.
+
+func testDB(t *testing.T) *gorm.DB {
+    t.Helper()
+    dsn := os.Getenv("TEST_DATABASE_DSN")
+    if dsn == "" {
+        t.Skip("TEST_DATABASE_DSN not set")
+    }
+
+    db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+    if err != nil {
+        t.Fatalf("connect: %v", err)
+    }
+    if err := db.AutoMigrate(&models.Product{}, &models.ProductVariant{}, &models.Order{}, &models.OrderItem{}, &models.StockReservation{}); err != nil {
+        t.Fatalf("migrate: %v", err)
+    }
+    t.Cleanup(func() {
+        if sqlDB, err := db.DB(); err == nil {
+            sqlDB.Close()
+        }
+    })
+    return db
+}
+
+func inRollback(t *testing.T, db *gorm.DB, fn func(tx *gorm.DB)) {
+    tx := db.Begin()
+    if tx.Error != nil {
+        t.Fatalf("begin: %v", tx.Error)
+    }
+    defer tx.Rollback()
+    fn(tx)
+}
+
+var suffixSeq atomic.Int64
+
+// uniqueSuffix keeps SKUs and transaction IDs apart across runs and rows
+func uniqueSuffix() string {
+    return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(suffixSeq.Add(1), 10)
+}
+
+func createProduct(t *testing.T, tx *gorm.DB, stock *int) *models.Product {
+    t.Helper()
+    product := models.Product{Title: "Stock test product", Description: "Stock test", Image: "https://img.example.com/p.jpg", Price: 10, Stock: stock}
+    if err := tx.Create(&product).Error; err != nil {
+        t.Fatalf("create product: %v", err)
+    }
+    return &product
+}
+
+func createVariant(t *testing.T, tx *gorm.DB, productID uint, stock *int) *models.ProductVariant {
+    t.Helper()
+    variant := models.ProductVariant{
+        ProductID:  productID,
+        SKU:        "STOCK-TEST-" + uniqueSuffix(),
+        Attributes: models.VariantAttributes{"size": "M"},
+        Stock:      stock,
+    }
+    if err := tx.Create(&variant).Error; err != nil {
+        t.Fatalf("create variant: %v", err)
+    }
+    return &variant
+}
+
+func createOrder(t *testing.T, tx *gorm.DB, expiresAt time.Time) *models.Order {
+    t.Helper()
+    order := models.Order{
+        TransactionID: "chk_stocktest_" + uniqueSuffix(),
+        UserID:        1,
+        Code:          "STOCKTEST0",
+        FirstName:     "Stock",
+        LastName:      "Tester",
+        Email:         "stock@example.com",
+        Address:       "1 Test Street",
+        City:          "Testville",
+        Country:       "Testland",
+        ExpiresAt:     &expiresAt,
+    }
+    if err := tx.Create(&order).Error; err != nil {
+        t.Fatalf("create order: %v", err)
+    }
+    return &order
+}
+
+// stockLine is a line to reserve; VariantID 0 = the product itself
+type stockLine struct {
+    ProductID uint
+    VariantID uint
+    Quantity  int
+}
+
+// reserve locks the lines' rows and reserves them for order, as checkout does
+func reserve(t *testing.T, tx *gorm.DB, order *models.Order, lines ...stockLine) ([]uint, []models.StockShortage, error) {
+    t.Helper()
+    var productIDs, variantIDs []uint
+    for _, line := range lines {
+        productIDs = append(productIDs, line.ProductID)
+        if line.VariantID != 0 {
+            variantIDs = append(variantIDs, line.VariantID)
+        }
+    }
+    products, err := models.LockProducts(tx, productIDs)
+    if err != nil {
+        t.Fatalf("lock products: %v", err)
+    }
+    variants, err := models.LockVariants(tx, variantIDs)
+    if err != nil {
+        t.Fatalf("lock variants: %v", err)
+    }
+
+    var stockLines []models.StockLine
+    for _, line := range lines {
+        stockLine := models.StockLine{Quantity: line.Quantity}
+        for i := range products {
+            if products[i].ID == line.ProductID {
+                stockLine.Product = &products[i]
+            }
+        }
+        for i := range variants {
+            if variants[i].ID == line.VariantID {
+                stockLine.Variant = &variants[i]
+            }
+        }
+        stockLines = append(stockLines, stockLine)
+    }
+    return models.ReserveStock(tx, stockLines, order.ID, *order.ExpiresAt)
+}
+
+func assertProductStock(t *testing.T, tx *gorm.DB, id uint, stock, reserved int) {
+    t.Helper()
+    var product models.Product
+    if err := tx.First(&product, id).Error; err != nil {
+        t.Fatalf("load product %d: %v", id, err)
+    }
+    if product.Stock == nil || *product.Stock != stock || product.Reserved != reserved {
+        t.Errorf("product %d: stock %v reserved %d, want %d reserved %d", id, product.Stock, product.Reserved, stock, reserved)
+    }
+}
+
+func assertVariantStock(t *testing.T, tx *gorm.DB, id uint, stock, reserved int) {
+    t.Helper()
+    var variant models.ProductVariant
+    if err := tx.First(&variant, id).Error; err != nil {
+        t.Fatalf("load variant %d: %v", id, err)
+    }
+    if variant.Stock == nil || *variant.Stock != stock || variant.Reserved != reserved {
+        t.Errorf("variant %d: stock %v reserved %d, want %d reserved %d", id, variant.Stock, variant.Reserved, stock, reserved)
+    }
+}
+
+// openReservations counts an order's reservations neither consumed nor released
+func openReservations(t *testing.T, tx *gorm.DB, orderID uint) int64 {
+    t.Helper()
+    var count int64
+    if err := tx.Model(&models.StockReservation{}).
+        Where("order_id = ? AND released_at IS NULL AND consumed_at IS NULL", orderID).
+        Count(&count).Error; err != nil {
+        t.Fatalf("count reservations: %v", err)
+    }
+    return count
+}
+
+func TestReserveAndConsumeStock(t *testing.T) {
+    db := testDB(t)
+    inRollback(t, db, func(tx *gorm.DB) {
+        now := time.Now()
+        tracked := createProduct(t, tx, intPtr(10))
+        untracked := createProduct(t, tx, nil)
+        withVariants := createProduct(t, tx, nil)
+        variant := createVariant(t, tx, withVariants.ID, intPtr(3))
+
+        order := createOrder(t, tx, now.Add(15*time.Minute))
+        soldOut, _, err := reserve(t, tx, order,
+            stockLine{ProductID: tracked.ID, Quantity: 4},
+            stockLine{ProductID: untracked.ID, Quantity: 50},
+            stockLine{ProductID: withVariants.ID, VariantID: variant.ID, Quantity: 3},
+        )
+        if err != nil {
+            t.Fatalf("reserve: %v", err)
+        }
+        if !reflect.DeepEqual(soldOut, []uint{withVariants.ID}) {
+            t.Errorf("sold out = %v, want [%d]", soldOut, withVariants.ID)
+        }
+        assertProductStock(t, tx, tracked.ID, 10, 4)
+        assertVariantStock(t, tx, variant.ID, 3, 3)
+        if n := openReservations(t, tx, order.ID); n != 2 {
+            t.Errorf("%d open reservations, want 2 (untracked products need none)", n)
+        }
+
+        // Only 6 units are left to promise; a failed reservation writes nothing
+        other := createOrder(t, tx, now.Add(15*time.Minute))
+        _, shortages, err := reserve(t, tx, other, stockLine{ProductID: tracked.ID, Quantity: 7})
+        if !errors.Is(err, models.ErrInsufficientStock) {
+            t.Fatalf("err = %v, want ErrInsufficientStock", err)
+        }
+        if len(shortages) != 1 || shortages[0].Available != 6 || shortages[0].Requested != 7 {
+            t.Errorf("shortages = %+v, want 7 requested, 6 available", shortages)
+        }
+        assertProductStock(t, tx, tracked.ID, 10, 4)
+        if n := openReservations(t, tx, other.ID); n != 0 {
+            t.Errorf("failed reservation left %d reservations", n)
+        }
+
+        // Paying turns reserved units into sold ones
+        if _, err := models.ConsumeOrderStock(tx, order.ID, now); err != nil {
+            t.Fatalf("consume: %v", err)
+        }
+        assertProductStock(t, tx, tracked.ID, 6, 0)
+        assertVariantStock(t, tx, variant.ID, 0, 0)
+        if n := openReservations(t, tx, order.ID); n != 0 {
+            t.Errorf("%d reservations still open after consuming", n)
+        }
+
+        // A repeated confirmation changes nothing
+        if _, err := models.ConsumeOrderStock(tx, order.ID, now); err != nil {
+            t.Fatalf("consume again: %v", err)
+        }
+        assertProductStock(t, tx, tracked.ID, 6, 0)
+        assertVariantStock(t, tx, variant.ID, 0, 0)
+    })
+}
+
+func TestCancelAndExpireCheckoutOrders(t *testing.T) {
+    db := testDB(t)
+    inRollback(t, db, func(tx *gorm.DB) {
+        now := time.Now()
+        product := createProduct(t, tx, intPtr(6))
+
+        // Reserving the last units sells the product out; cancelling brings it back
+        order := createOrder(t, tx, now.Add(15*time.Minute))
+        soldOut, _, err := reserve(t, tx, order, stockLine{ProductID: product.ID, Quantity: 6})
+        if err != nil {
+            t.Fatalf("reserve: %v", err)
+        }
+        if !reflect.DeepEqual(soldOut, []uint{product.ID}) {
+            t.Errorf("sold out = %v, want [%d]", soldOut, product.ID)
+        }
+
+        restocked, err := models.CancelCheckoutOrder(tx, order, now)
+        if err != nil {
+            t.Fatalf("cancel: %v", err)
+        }
+        if !reflect.DeepEqual(restocked, []uint{product.ID}) {
+            t.Errorf("restocked = %v, want [%d]", restocked, product.ID)
+        }
+        assertProductStock(t, tx, product.ID, 6, 0)
+        var cancelled models.Order
+        if err := tx.First(&cancelled, order.ID).Error; err != nil {
+            t.Fatalf("load order: %v", err)
+        }
+        if cancelled.CancelledAt == nil || cancelled.IsPending() {
+            t.Errorf("order not cancelled: %+v", cancelled)
+        }
+
+        // A cancelled order's stock can't be consumed afterwards
+        if _, err := models.ConsumeOrderStock(tx, order.ID, now); err != nil {
+            t.Fatalf("consume cancelled: %v", err)
+        }
+        assertProductStock(t, tx, product.ID, 6, 0)
+
+        // Cancelling while other units remain does not report a restock
+        partial := createOrder(t, tx, now.Add(15*time.Minute))
+        if _, _, err := reserve(t, tx, partial, stockLine{ProductID: product.ID, Quantity: 2}); err != nil {
+            t.Fatalf("reserve: %v", err)
+        }
+        restocked, err = models.CancelCheckoutOrder(tx, partial, now)
+        if err != nil {
+            t.Fatalf("cancel: %v", err)
+        }
+        if len(restocked) != 0 {
+            t.Errorf("restocked = %v for a product that never sold out", restocked)
+        }
+        assertProductStock(t, tx, product.ID, 6, 0)
+
+        // Unpaid orders past their reservation are cancelled by the expiry job
+        late := createOrder(t, tx, now.Add(-time.Minute))
+        current := createOrder(t, tx, now.Add(time.Hour))
+        if _, _, err := reserve(t, tx, late, stockLine{ProductID: product.ID, Quantity: 2}); err != nil {
+            t.Fatalf("reserve: %v", err)
+        }
+        if _, _, err := reserve(t, tx, current, stockLine{ProductID: product.ID, Quantity: 1}); err != nil {
+            t.Fatalf("reserve: %v", err)
+        }
+        expired, _, err := models.ExpireCheckoutOrders(tx, now, 1000)
+        if err != nil {
+            t.Fatalf("expire: %v", err)
+        }
+        if expired < 1 {
+            t.Errorf("expired %d orders, want the late one", expired)
+        }
+        assertProductStock(t, tx, product.ID, 6, 1)
+        if n := openReservations(t, tx, late.ID); n != 0 {
+            t.Errorf("expired order kept %d open reservations", n)
+        }
+        if n := openReservations(t, tx, current.ID); n != 1 {
+            t.Errorf("current order has %d open reservations, want 1", n)
+        }
+    })
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -11,6 +13,13 @@ type User struct {
     Email string        `gorm:"uniqueIndex;size:255" json:"email"`
     Password     []byte `json:"-"` // hides password in JSON responses
     IsAmbassador bool   `json:"-"`
+    EmailVerifiedAt *time.Time `json:"email_verified_at"`
+    SessionsRevokedAt *time.Time `json:"-"` // JWTs issued before this are rejected
+    TOTPSecret    string     `gorm:"size:64" json:"-"` // pending until TOTPEnabledAt is set
+    TOTPEnabledAt *time.Time `json:"-"`
+    TOTPLastStep  int64      `json:"-"` // last accepted time step, prevents code replay
+    SuspendedAt     *time.Time `json:"suspended_at,omitempty"`
+    SuspendedReason string     `gorm:"size:255" json:"suspended_reason,omitempty"`
     Revenue      float64 `json:"revenue,omitempty" gorm:"-"`
 }
 
@@ -21,6 +30,31 @@ func (u *User) CalculateRevenue(db *gorm.DB) float64 {
     return u.calculateAdminRevenue(db)
 }
 
+// IsEmailVerified reports whether the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+    return u.EmailVerifiedAt != nil
+}
+
+// MFAEnabled reports whether TOTP two-factor authentication is active
+func (u *User) MFAEnabled() bool {
+    return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
+// IsSuspended reports whether an admin has suspended the account
+func (u *User) IsSuspended() bool {
+    return u.SuspendedAt != nil
+}
+
+// SessionRevoked reports whether a token issued at issuedAt has been revoked.
+// JWT iat has whole-second precision, so tokens from the revocation's own
+// second are revoked too; signing in again a second later works.
+func (u *User) SessionRevoked(issuedAt time.Time) bool {
+    if u.SessionsRevokedAt == nil {
+        return false
+    }
+    return !issuedAt.After(u.SessionsRevokedAt.Truncate(time.Second))
+}
+
 func (u *User) Name() string {
     return u.FirstName + " " + u.LastName
 }
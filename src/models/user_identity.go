@@ -0,0 +1,12 @@
+package models
+
+// UserIdentity links a User to an external OpenID Connect account
+type UserIdentity struct {
+    Model
+    UserID  uint   `gorm:"index;not null" json:"user_id"`
+    Issuer  string `gorm:"size:255;not null;uniqueIndex:idx_identity_issuer_subject" json:"issuer"`
+    Subject string `gorm:"size:255;not null;uniqueIndex:idx_identity_issuer_subject" json:"subject"`
+    Email   string `gorm:"size:255" json:"email"` // email reported by the provider at link time
+
+    User User `gorm:"foreignKey:UserID" json:"-"`
+}
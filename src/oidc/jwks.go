@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JSONWebKey is the subset of RFC 7517 needed to verify ID tokens
+type JSONWebKey struct {
+    Kid string `json:"kid"`
+    Kty string `json:"kty"`
+    Alg string `json:"alg"`
+    Use string `json:"use"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+    Y   string `json:"y"`
+}
+
+type JSONWebKeySet struct {
+    Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKey converts the JWK to an *rsa.PublicKey or *ecdsa.PublicKey
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+    switch k.Kty {
+    case "RSA":
+        n, err := decodeBigInt(k.N)
+        if err != nil {
+            return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+        }
+        e, err := decodeBigInt(k.E)
+        if err != nil {
+            return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+        }
+        return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+    case "EC":
+        var curve elliptic.Curve
+        switch k.Crv {
+        case "P-256":
+            curve = elliptic.P256()
+        case "P-384":
+            curve = elliptic.P384()
+        case "P-521":
+            curve = elliptic.P521()
+        default:
+            return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+        }
+        x, err := decodeBigInt(k.X)
+        if err != nil {
+            return nil, fmt.Errorf("invalid EC x: %w", err)
+        }
+        y, err := decodeBigInt(k.Y)
+        if err != nil {
+            return nil, fmt.Errorf("invalid EC y: %w", err)
+        }
+        return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+    default:
+        return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+    }
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+    b, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,18 @@
+package oidc
+
+import (
+	"ambassador/src/utils"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewCodeVerifier returns a high-entropy PKCE code verifier (RFC 7636, 43 chars)
+func NewCodeVerifier() (string, error) {
+    return utils.GenerateToken(32)
+}
+
+// CodeChallengeS256 derives the S256 code challenge for a verifier
+func CodeChallengeS256(verifier string) string {
+    sum := sha256.Sum256([]byte(verifier))
+    return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,324 @@
+package oidc
+
+import (
+	"ambassador/src/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var ErrNotConfigured = errors.New("OIDC provider not configured")
+
+// JWKS are cached this long, and refetched at most this often on unknown key IDs
+const (
+    jwksTTL          = time.Hour
+    jwksRefetchDelay = time.Minute
+)
+
+// Discovery is the subset of the provider metadata document we rely on
+type Discovery struct {
+    Issuer                        string   `json:"issuer"`
+    AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+    TokenEndpoint                 string   `json:"token_endpoint"`
+    JWKSURI                       string   `json:"jwks_uri"`
+    TokenEndpointAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+    CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// TokenResponse is the token endpoint response for the authorization-code grant
+type TokenResponse struct {
+    AccessToken string `json:"access_token"`
+    TokenType   string `json:"token_type"`
+    IDToken     string `json:"id_token"`
+    ExpiresIn   int    `json:"expires_in"`
+}
+
+// IDTokenClaims are the ID token claims used for sign-in and account linking
+type IDTokenClaims struct {
+    jwt.RegisteredClaims
+    Nonce         string `json:"nonce"`
+    AuthorizedParty string `json:"azp"`
+    Email         string `json:"email"`
+    EmailVerified bool   `json:"email_verified"`
+    Name          string `json:"name"`
+    GivenName     string `json:"given_name"`
+    FamilyName    string `json:"family_name"`
+}
+
+// Provider talks to one OpenID Connect provider
+type Provider struct {
+    ClientID     string
+    ClientSecret string
+    RedirectURL  string
+    Scopes       []string
+
+    issuer    string
+    discovery Discovery
+    client    *http.Client
+
+    mu            sync.Mutex
+    keys          map[string]interface{}
+    keysFetchedAt time.Time
+}
+
+var (
+    defaultProvider *Provider
+    defaultMu       sync.Mutex
+)
+
+// Enabled reports whether an OIDC issuer is configured
+func Enabled(cfg *config.Config) bool {
+    return cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != ""
+}
+
+// Default returns the configured provider, discovering it on first use so the
+// API can start before the identity provider is reachable
+func Default(ctx context.Context) (*Provider, error) {
+    cfg := config.Get()
+    if !Enabled(cfg) {
+        return nil, ErrNotConfigured
+    }
+
+    defaultMu.Lock()
+    defer defaultMu.Unlock()
+
+    if defaultProvider != nil {
+        return defaultProvider, nil
+    }
+
+    p, err := NewProvider(ctx, cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL, strings.Fields(cfg.OIDCScopes))
+    if err != nil {
+        return nil, err
+    }
+
+    defaultProvider = p
+    return p, nil
+}
+
+// NewProvider fetches the discovery document for issuer
+func NewProvider(ctx context.Context, issuer, clientID, clientSecret, redirectURL string, scopes []string) (*Provider, error) {
+    p := &Provider{
+        ClientID:     clientID,
+        ClientSecret: clientSecret,
+        RedirectURL:  redirectURL,
+        Scopes:       scopes,
+        issuer:       strings.TrimSuffix(issuer, "/"),
+        client:       &http.Client{Timeout: 10 * time.Second},
+    }
+
+    wellKnown := p.issuer + "/.well-known/openid-configuration"
+    if err := p.getJSON(ctx, wellKnown, &p.discovery); err != nil {
+        return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+    }
+
+    // The issuer in the document must match exactly (OIDC Discovery 4.3)
+    if strings.TrimSuffix(p.discovery.Issuer, "/") != p.issuer {
+        return nil, fmt.Errorf("OIDC issuer mismatch: expected %s, got %s", p.issuer, p.discovery.Issuer)
+    }
+    if p.discovery.AuthorizationEndpoint == "" || p.discovery.TokenEndpoint == "" || p.discovery.JWKSURI == "" {
+        return nil, errors.New("OIDC discovery document is missing endpoints")
+    }
+
+    return p, nil
+}
+
+// AuthCodeURL builds the authorization request URL (authorization code + PKCE S256)
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+    q := url.Values{}
+    q.Set("response_type", "code")
+    q.Set("client_id", p.ClientID)
+    q.Set("redirect_uri", p.RedirectURL)
+    q.Set("scope", strings.Join(p.Scopes, " "))
+    q.Set("state", state)
+    q.Set("nonce", nonce)
+    q.Set("code_challenge", codeChallenge)
+    q.Set("code_challenge_method", "S256")
+
+    sep := "?"
+    if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+        sep = "&"
+    }
+    return p.discovery.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Exchange trades an authorization code for tokens
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+    form := url.Values{}
+    form.Set("grant_type", "authorization_code")
+    form.Set("code", code)
+    form.Set("redirect_uri", p.RedirectURL)
+    form.Set("code_verifier", codeVerifier)
+
+    useBasic := p.supportsAuthMethod("client_secret_basic")
+    if !useBasic {
+        form.Set("client_id", p.ClientID)
+        form.Set("client_secret", p.ClientSecret)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.Header.Set("Accept", "application/json")
+    if useBasic {
+        req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+    }
+
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("token request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+    if err != nil {
+        return nil, fmt.Errorf("token response read failed: %w", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+    }
+
+    var token TokenResponse
+    if err := json.Unmarshal(body, &token); err != nil {
+        return nil, fmt.Errorf("invalid token response: %w", err)
+    }
+    if token.IDToken == "" {
+        return nil, errors.New("token response has no id_token")
+    }
+
+    return &token, nil
+}
+
+// VerifyIDToken checks signature, issuer, audience, expiry and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+    parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+
+    claims := &IDTokenClaims{}
+    _, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+        kid, _ := token.Header["kid"].(string)
+        return p.key(ctx, kid)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("invalid ID token: %w", err)
+    }
+
+    if strings.TrimSuffix(claims.Issuer, "/") != p.issuer {
+        return nil, fmt.Errorf("ID token issuer mismatch: %s", claims.Issuer)
+    }
+    if !claims.VerifyAudience(p.ClientID, true) {
+        return nil, errors.New("ID token audience mismatch")
+    }
+    if len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID {
+        return nil, errors.New("ID token authorized party mismatch")
+    }
+    if claims.ExpiresAt == nil {
+        return nil, errors.New("ID token has no expiry")
+    }
+    if claims.Subject == "" {
+        return nil, errors.New("ID token has no subject")
+    }
+    if nonce == "" || claims.Nonce != nonce {
+        return nil, errors.New("ID token nonce mismatch")
+    }
+
+    return claims, nil
+}
+
+// Issuer returns the normalized issuer URL (used to key linked identities)
+func (p *Provider) Issuer() string {
+    return p.issuer
+}
+
+// key returns the verification key for kid, refreshing the JWKS when needed
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    stale := time.Since(p.keysFetchedAt) > jwksTTL
+    if k, ok := p.lookupKey(kid); ok && !stale {
+        return k, nil
+    }
+
+    // Unknown kid usually means the provider rotated keys
+    if !stale && time.Since(p.keysFetchedAt) < jwksRefetchDelay {
+        return nil, fmt.Errorf("unknown signing key %q", kid)
+    }
+
+    var set JSONWebKeySet
+    if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+        return nil, fmt.Errorf("JWKS fetch failed: %w", err)
+    }
+
+    keys := make(map[string]interface{}, len(set.Keys))
+    for _, jwk := range set.Keys {
+        if jwk.Use != "" && jwk.Use != "sig" {
+            continue
+        }
+        pub, err := jwk.PublicKey()
+        if err != nil {
+            continue
+        }
+        keys[jwk.Kid] = pub
+    }
+    p.keys = keys
+    p.keysFetchedAt = time.Now()
+
+    if k, ok := p.lookupKey(kid); ok {
+        return k, nil
+    }
+    return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds kid; tokens without kid are accepted only with a single key
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+    if kid == "" && len(p.keys) == 1 {
+        for _, k := range p.keys {
+            return k, true
+        }
+    }
+    k, ok := p.keys[kid]
+    return k, ok
+}
+
+func (p *Provider) supportsAuthMethod(method string) bool {
+    // client_secret_basic is the default when the provider doesn't say (RFC 8414)
+    if len(p.discovery.TokenEndpointAuthMethods) == 0 {
+        return method == "client_secret_basic"
+    }
+    for _, m := range p.discovery.TokenEndpointAuthMethods {
+        if m == method {
+            return true
+        }
+    }
+    return false
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Accept", "application/json")
+
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("GET %s returned %d", endpoint, resp.StatusCode)
+    }
+
+    return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
@@ -0,0 +1,128 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedList answers "has this password appeared in a breach?" offline, using
+// the Have I Been Pwned k-anonymity layout: SHA-1 hashes grouped by 5-hex prefix.
+//
+// Path can be either
+//   - a file with one "SHA1HEX[:COUNT]" per line (loaded into memory), or
+//   - a directory of range files named by prefix ("5BAA6" or "5BAA6.txt")
+//     containing "SUFFIX[:COUNT]" lines, read on demand.
+type BreachedList struct {
+    dir    string
+    ranges map[string]map[string]struct{} // prefix -> suffixes
+    size   int
+}
+
+// LoadBreachedList opens path; an empty path disables the check
+func LoadBreachedList(path string) (*BreachedList, error) {
+    if path == "" {
+        return nil, nil
+    }
+
+    info, err := os.Stat(path)
+    if err != nil {
+        return nil, fmt.Errorf("breached password list: %w", err)
+    }
+
+    if info.IsDir() {
+        return &BreachedList{dir: path}, nil
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("breached password list: %w", err)
+    }
+    defer f.Close()
+
+    list := &BreachedList{ranges: make(map[string]map[string]struct{})}
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        hash := parseHashLine(scanner.Text())
+        if len(hash) != 40 {
+            continue
+        }
+        prefix, suffix := hash[:5], hash[5:]
+        if list.ranges[prefix] == nil {
+            list.ranges[prefix] = make(map[string]struct{})
+        }
+        list.ranges[prefix][suffix] = struct{}{}
+        list.size++
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("breached password list: %w", err)
+    }
+
+    return list, nil
+}
+
+// Size is the number of hashes loaded in memory (0 for directory mode)
+func (b *BreachedList) Size() int {
+    if b == nil {
+        return 0
+    }
+    return b.size
+}
+
+// Contains reports whether password is in the list
+func (b *BreachedList) Contains(password string) (bool, error) {
+    if b == nil {
+        return false, nil
+    }
+
+    sum := sha1.Sum([]byte(password))
+    hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+    prefix, suffix := hash[:5], hash[5:]
+
+    if b.dir == "" {
+        _, found := b.ranges[prefix][suffix]
+        return found, nil
+    }
+
+    return b.searchRangeFile(prefix, suffix)
+}
+
+func (b *BreachedList) searchRangeFile(prefix, suffix string) (bool, error) {
+    for _, name := range []string{prefix, prefix + ".txt"} {
+        f, err := os.Open(filepath.Join(b.dir, name))
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            return false, err
+        }
+
+        found := false
+        scanner := bufio.NewScanner(f)
+        for scanner.Scan() {
+            if parseHashLine(scanner.Text()) == suffix {
+                found = true
+                break
+            }
+        }
+        err = scanner.Err()
+        f.Close()
+        return found, err
+    }
+
+    // No range file means no breached hash with this prefix
+    return false, nil
+}
+
+// parseHashLine extracts the upper-case hex hash from "HASH" or "HASH:COUNT"
+func parseHashLine(line string) string {
+    line = strings.TrimSpace(line)
+    if i := strings.IndexByte(line, ':'); i >= 0 {
+        line = line[:i]
+    }
+    return strings.ToUpper(line)
+}
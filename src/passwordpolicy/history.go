@@ -0,0 +1,67 @@
+package passwordpolicy
+
+import (
+	"ambassador/src/models"
+	"ambassador/src/utils"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrPasswordReused = errors.New("password was used recently, please choose a different one")
+
+// CheckReuse rejects the current password and the last HistorySize-1 ones
+func CheckReuse(db *gorm.DB, user *models.User, password string) error {
+    if current.HistorySize <= 0 {
+        return nil
+    }
+
+    if len(user.Password) > 0 && utils.CheckPassword(user.Password, password) == nil {
+        return ErrPasswordReused
+    }
+
+    if current.HistorySize == 1 {
+        return nil
+    }
+
+    var history []models.PasswordHistory
+    if err := db.
+        Where("user_id = ?", user.ID).
+        Order("id DESC").
+        Limit(current.HistorySize - 1).
+        Find(&history).Error; err != nil {
+        return err
+    }
+
+    for _, h := range history {
+        if utils.CheckPassword(h.Hash, password) == nil {
+            return ErrPasswordReused
+        }
+    }
+
+    return nil
+}
+
+// Remember stores the outgoing password hash and prunes older entries
+func Remember(tx *gorm.DB, userID uint, oldHash []byte) error {
+    if current.HistorySize <= 1 || len(oldHash) == 0 {
+        return nil
+    }
+
+    if err := tx.Create(&models.PasswordHistory{UserID: userID, Hash: oldHash}).Error; err != nil {
+        return err
+    }
+
+    // Keep HistorySize-1 previous hashes (the current one lives on the user)
+    var keepIDs []uint
+    if err := tx.Model(&models.PasswordHistory{}).
+        Where("user_id = ?", userID).
+        Order("id DESC").
+        Limit(current.HistorySize - 1).
+        Pluck("id", &keepIDs).Error; err != nil {
+        return err
+    }
+
+    return tx.Where("user_id = ? AND id NOT IN ?", userID, keepIDs).
+        Delete(&models.PasswordHistory{}).Error
+}
@@ -0,0 +1,132 @@
+package passwordpolicy
+
+import (
+	"ambassador/src/config"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// bcrypt ignores everything after 72 bytes
+const maxLength = 72
+
+// Policy is the password policy applied on register, update and reset
+type Policy struct {
+    MinLength   int
+    MinScore    int // 0..4, see EstimateStrength
+    HistorySize int // number of recent passwords that can't be reused (including the current one)
+    Breached    *BreachedList
+}
+
+// UserInfo is what a password must not contain
+type UserInfo struct {
+    Email     string
+    FirstName string
+    LastName  string
+}
+
+// ValidationError carries a message safe to show to users
+type ValidationError struct {
+    Message     string
+    Suggestions []string
+}
+
+func (e *ValidationError) Error() string {
+    return e.Message
+}
+
+var current = Policy{MinLength: 8, MinScore: 2, HistorySize: 5}
+
+// Setup builds the policy from config and loads the breached list (called from main)
+func Setup(cfg *config.Config) error {
+    breached, err := LoadBreachedList(cfg.BreachedPasswordsPath)
+    if err != nil {
+        return err
+    }
+
+    current = Policy{
+        MinLength:   cfg.PasswordMinLength,
+        MinScore:    cfg.PasswordMinScore,
+        HistorySize: cfg.PasswordHistorySize,
+        Breached:    breached,
+    }
+
+    if breached != nil {
+        log.Printf("Breached password list loaded from %s (%d hashes in memory)", cfg.BreachedPasswordsPath, breached.Size())
+    }
+    return nil
+}
+
+// Current returns the active policy
+func Current() Policy {
+    return current
+}
+
+// Validate checks password against the active policy
+func Validate(password string, user UserInfo) error {
+    return current.Validate(password, user)
+}
+
+// Validate checks length, personal info, strength and breach status
+func (p Policy) Validate(password string, user UserInfo) error {
+    if len([]rune(password)) < p.MinLength {
+        return &ValidationError{Message: fmt.Sprintf("Password must be at least %d characters", p.MinLength)}
+    }
+    if len(password) > maxLength {
+        return &ValidationError{Message: "Password too long"}
+    }
+
+    words := personalWords(user)
+    lower := strings.ToLower(password)
+    for _, w := range words {
+        // Very short names would reject too many unrelated passwords
+        if len([]rune(w)) >= 4 && strings.Contains(lower, w) {
+            return &ValidationError{Message: "Password must not contain your name or email"}
+        }
+    }
+
+    strength := EstimateStrength(password, words...)
+    if strength.Score < p.MinScore {
+        return &ValidationError{
+            Message:     "Password is too weak",
+            Suggestions: append(strength.Warnings, "add more words or characters that don't form a common pattern"),
+        }
+    }
+
+    breached, err := p.Breached.Contains(password)
+    if err != nil {
+        // The list is a safety net; don't block sign-ups when it can't be read
+        log.Printf("Breached password lookup failed: %v", err)
+    }
+    if breached {
+        return &ValidationError{Message: "This password has appeared in a data breach, please choose a different one"}
+    }
+
+    return nil
+}
+
+// personalWords returns lower-cased name and email parts of at least 3 characters
+func personalWords(user UserInfo) []string {
+    var words []string
+    add := func(w string) {
+        w = strings.ToLower(strings.TrimSpace(w))
+        if len([]rune(w)) >= 3 {
+            words = append(words, w)
+        }
+    }
+
+    add(user.FirstName)
+    add(user.LastName)
+
+    email := strings.ToLower(user.Email)
+    if at := strings.IndexByte(email, '@'); at > 0 {
+        add(email[:at])
+        for _, part := range strings.FieldsFunc(email[:at], func(r rune) bool {
+            return r == '.' || r == '_' || r == '-' || r == '+'
+        }) {
+            add(part)
+        }
+    }
+
+    return words
+}
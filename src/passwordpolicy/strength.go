@@ -0,0 +1,288 @@
+package passwordpolicy
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength is a zxcvbn-style estimate: the password is split into the cheapest
+// sequence of patterns (dictionary words, keyboard/alphabet sequences, repeats,
+// years, brute force) and the guesses needed for each are multiplied.
+type Strength struct {
+    Guesses  float64  // log10 of estimated guesses
+    Score    int      // 0 (too guessable) .. 4 (very unguessable)
+    Warnings []string // human readable hints about weak patterns
+}
+
+type match struct {
+    start, end int // [start, end) in runes
+    log10      float64
+    warning    string
+}
+
+// Frequently used passwords and words, ranked roughly by popularity
+var commonWords = []string{
+    "password", "123456", "12345678", "qwerty", "123456789", "12345", "1234", "111111",
+    "1234567", "dragon", "123123", "baseball", "abc123", "football", "monkey", "letmein",
+    "696969", "shadow", "master", "666666", "qwertyuiop", "123321", "mustang", "1234567890",
+    "michael", "654321", "superman", "1qaz2wsx", "7777777", "121212", "000000", "qazwsx",
+    "123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+    "buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou",
+    "hello123", "charlie", "robert", "thomas", "hockey", "ranger", "daniel", "starwars",
+    "whatever", "112233", "george", "computer", "michelle", "jessica", "pepper", "1111",
+    "zxcvbn", "555555", "11111111", "131313", "freedom", "777777", "pass", "maggie",
+    "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+    "love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees",
+    "987654321", "dallas", "austin", "thunder", "taylor", "matrix", "welcome", "admin",
+    "administrator", "login", "secret", "passw0rd", "changeme", "default", "guest",
+    "ambassador", "shopping", "money", "orange", "flower", "spring", "winter", "autumn",
+    "football1", "baseball1", "qwerty123", "password1", "letmein1", "hello", "world",
+}
+
+var commonRank = func() map[string]int {
+    ranks := make(map[string]int, len(commonWords))
+    for i, w := range commonWords {
+        if _, ok := ranks[w]; !ok {
+            ranks[w] = i + 1
+        }
+    }
+    return ranks
+}()
+
+var sequences = []string{
+    "abcdefghijklmnopqrstuvwxyz",
+    "0123456789",
+    "qwertyuiop",
+    "asdfghjkl",
+    "zxcvbnm",
+    "1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+var leet = strings.NewReplacer("@", "a", "4", "a", "0", "o", "1", "i", "!", "i", "3", "e", "5", "s", "$", "s", "7", "t", "+", "t", "8", "b")
+
+// EstimateStrength scores password; extra words (name, email parts) count as dictionary hits
+func EstimateStrength(password string, userWords ...string) Strength {
+    runes := []rune(password)
+    n := len(runes)
+    if n == 0 {
+        return Strength{Score: 0, Warnings: []string{"password is empty"}}
+    }
+
+    matches := findMatches(runes, userWords)
+    bruteforce := math.Log10(float64(cardinality(runes)))
+
+    // best[i] = cheapest log10(guesses) covering runes[:i]
+    best := make([]float64, n+1)
+    via := make([]*match, n+1)
+    for i := 1; i <= n; i++ {
+        best[i] = best[i-1] + bruteforce
+        via[i] = nil
+        for j := range matches {
+            m := &matches[j]
+            if m.end == i && best[m.start]+m.log10 < best[i] {
+                best[i] = best[m.start] + m.log10
+                via[i] = m
+            }
+        }
+    }
+
+    // Collect warnings from the patterns actually used
+    var warnings []string
+    seen := map[string]bool{}
+    for i := n; i > 0; {
+        if m := via[i]; m != nil {
+            if m.warning != "" && !seen[m.warning] {
+                seen[m.warning] = true
+                warnings = append(warnings, m.warning)
+            }
+            i = m.start
+        } else {
+            i--
+        }
+    }
+
+    guesses := best[n]
+    return Strength{Guesses: guesses, Score: scoreFor(guesses), Warnings: warnings}
+}
+
+// Thresholds from zxcvbn (online throttled .. offline slow hash)
+func scoreFor(log10Guesses float64) int {
+    switch {
+    case log10Guesses < 3:
+        return 0
+    case log10Guesses < 6:
+        return 1
+    case log10Guesses < 8:
+        return 2
+    case log10Guesses < 10:
+        return 3
+    default:
+        return 4
+    }
+}
+
+func findMatches(runes []rune, userWords []string) []match {
+    var matches []match
+    lower := []rune(strings.ToLower(string(runes)))
+    n := len(runes)
+
+    personal := map[string]bool{}
+    for _, w := range userWords {
+        w = strings.ToLower(strings.TrimSpace(w))
+        if len([]rune(w)) >= 3 {
+            personal[w] = true
+        }
+    }
+
+    for i := 0; i < n; i++ {
+        for j := i + 3; j <= n; j++ {
+            token := string(lower[i:j])
+            original := string(runes[i:j])
+
+            // Dictionary and personal info, also through l33t substitutions
+            for _, candidate := range []string{token, leet.Replace(token)} {
+                if personal[candidate] {
+                    matches = append(matches, match{i, j, math.Log10(2 * caseVariations(original)), "avoid using your name or email in the password"})
+                }
+                if rank, ok := commonRank[candidate]; ok {
+                    guesses := float64(rank) * caseVariations(original)
+                    if candidate != token {
+                        guesses *= 2
+                    }
+                    matches = append(matches, match{i, j, math.Log10(guesses), "this is similar to a commonly used password"})
+                }
+            }
+
+            // Alphabet, digit and keyboard runs (forwards or backwards)
+            if isSequence(token) {
+                base := 26.0
+                if token[0] == 'a' || token[0] == '1' || token[0] == 'z' || token[0] == '9' || token[0] == 'q' {
+                    base = 4
+                }
+                matches = append(matches, match{i, j, math.Log10(base * float64(j-i)), "sequences like abc or qwerty are easy to guess"})
+            }
+
+            // Same character or chunk repeated
+            if unit := repeatUnit(lower[i:j]); unit > 0 {
+                unitGuesses := math.Pow(float64(cardinality(runes[i:i+unit])), float64(unit))
+                matches = append(matches, match{i, j, math.Log10(unitGuesses * float64((j-i)/unit)), "repeats like aaa or abcabc are easy to guess"})
+            }
+        }
+
+        // Years 1900-2039
+        if i+4 <= n {
+            y := string(lower[i : i+4])
+            if (strings.HasPrefix(y, "19") || strings.HasPrefix(y, "20")) && isDigits(y) && y < "2040" {
+                matches = append(matches, match{i, i + 4, math.Log10(140), "years are easy to guess"})
+            }
+        }
+    }
+
+    return matches
+}
+
+// caseVariations estimates how many capitalizations an attacker has to try
+func caseVariations(word string) float64 {
+    upper, lower := 0, 0
+    for _, r := range word {
+        if unicode.IsUpper(r) {
+            upper++
+        } else if unicode.IsLower(r) {
+            lower++
+        }
+    }
+    switch {
+    case upper == 0:
+        return 1
+    case lower == 0, upper == 1 && unicode.IsUpper([]rune(word)[0]):
+        return 2
+    default:
+        return math.Pow(2, float64(min(upper, lower)+1))
+    }
+}
+
+func isSequence(token string) bool {
+    if len(token) < 3 {
+        return false
+    }
+    reversed := reverse(token)
+    for _, seq := range sequences {
+        if strings.Contains(seq, token) || strings.Contains(seq, reversed) {
+            return true
+        }
+    }
+    return false
+}
+
+// repeatUnit returns the length of the repeating chunk if s is a repetition, else 0
+func repeatUnit(s []rune) int {
+    n := len(s)
+    for unit := 1; unit <= n/2; unit++ {
+        if n%unit != 0 {
+            continue
+        }
+        ok := true
+        for k := unit; k < n && ok; k++ {
+            ok = s[k] == s[k%unit]
+        }
+        if ok {
+            return unit
+        }
+    }
+    return 0
+}
+
+// cardinality is the size of the character space used by runes
+func cardinality(runes []rune) int {
+    var lower, upper, digit, symbol, other bool
+    for _, r := range runes {
+        switch {
+        case r >= 'a' && r <= 'z':
+            lower = true
+        case r >= 'A' && r <= 'Z':
+            upper = true
+        case r >= '0' && r <= '9':
+            digit = true
+        case r < 128:
+            symbol = true
+        default:
+            other = true
+        }
+    }
+
+    c := 0
+    if lower {
+        c += 26
+    }
+    if upper {
+        c += 26
+    }
+    if digit {
+        c += 10
+    }
+    if symbol {
+        c += 33
+    }
+    if other {
+        c += 100
+    }
+    return max(c, 10)
+}
+
+func isDigits(s string) bool {
+    for _, r := range s {
+        if r < '0' || r > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+func reverse(s string) string {
+    r := []rune(s)
+    for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+        r[i], r[j] = r[j], r[i]
+    }
+    return string(r)
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// FallbackStore uses the primary store and switches to the secondary on errors,
+// so an unavailable Redis never disables brute-force protection.
+type FallbackStore struct {
+    primary   Store
+    secondary Store
+
+    mu        sync.Mutex
+    lastWarnAt time.Time
+}
+
+func NewFallbackStore(primary, secondary Store) *FallbackStore {
+    return &FallbackStore{primary: primary, secondary: secondary}
+}
+
+func (s *FallbackStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int64, time.Time, error) {
+    n, oldest, err := s.primary.Hit(ctx, key, now, window)
+    if err == nil {
+        return n, oldest, nil
+    }
+    s.warn(err)
+    return s.secondary.Hit(ctx, key, now, window)
+}
+
+func (s *FallbackStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+    n, err := s.primary.Incr(ctx, key, ttl)
+    if err == nil {
+        return n, nil
+    }
+    s.warn(err)
+    return s.secondary.Incr(ctx, key, ttl)
+}
+
+func (s *FallbackStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+    // Locks go to both so they survive the primary flapping
+    secondaryErr := s.secondary.Lock(ctx, key, ttl)
+    if err := s.primary.Lock(ctx, key, ttl); err != nil {
+        s.warn(err)
+        return secondaryErr
+    }
+    return nil
+}
+
+func (s *FallbackStore) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+    local, _ := s.secondary.LockTTL(ctx, key)
+
+    remote, err := s.primary.LockTTL(ctx, key)
+    if err != nil {
+        s.warn(err)
+        return local, nil
+    }
+
+    if local > remote {
+        return local, nil
+    }
+    return remote, nil
+}
+
+func (s *FallbackStore) Delete(ctx context.Context, keys ...string) error {
+    secondaryErr := s.secondary.Delete(ctx, keys...)
+    if err := s.primary.Delete(ctx, keys...); err != nil {
+        s.warn(err)
+        return secondaryErr
+    }
+    return nil
+}
+
+// warn logs primary failures at most once a minute
+func (s *FallbackStore) warn(err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if time.Since(s.lastWarnAt) < time.Minute {
+        return
+    }
+    s.lastWarnAt = time.Now()
+    log.Printf("Warning: rate limit store unavailable, using in-memory fallback: %v", err)
+}
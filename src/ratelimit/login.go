@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"ambassador/src/config"
+	"ambassador/src/database"
+	"ambassador/src/utils"
+	"context"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits for login brute-force protection and checkout throttling
+type Limits struct {
+    IPMaxAttempts    int           // login attempts per IP per IPWindow
+    IPWindow         time.Duration
+    EmailMaxFailures int           // failed logins per email per EmailWindow before lockout
+    EmailWindow      time.Duration
+    LockoutBase      time.Duration // first lockout; doubles on each subsequent one
+    LockoutMax       time.Duration
+
+    CheckoutIPMax    int // checkout orders per IP per CheckoutIPWindow
+    CheckoutIPWindow time.Duration
+}
+
+var (
+    store  Store // set by Setup; see activeStore
+    limits = Limits{
+        IPMaxAttempts:    20,
+        IPWindow:         15 * time.Minute,
+        EmailMaxFailures: 5,
+        EmailWindow:      15 * time.Minute,
+        LockoutBase:      time.Minute,
+        LockoutMax:       time.Hour,
+        CheckoutIPMax:    10,
+        CheckoutIPWindow: 15 * time.Minute,
+    }
+)
+
+// lockoutMemory is how long past lockouts count towards the backoff
+const lockoutMemory = 24 * time.Hour
+
+var defaultStoreOnce sync.Once
+
+// Setup configures limits and the store (called from main after database.Connect).
+// Redis is always the primary; the in-memory store only takes over while
+// Redis is unreachable, including when it was down at startup.
+func Setup(cfg *config.Config) {
+    limits = Limits{
+        IPMaxAttempts:    cfg.LoginIPMaxAttempts,
+        IPWindow:         time.Duration(cfg.LoginIPWindowMinutes) * time.Minute,
+        EmailMaxFailures: cfg.LoginEmailMaxFailures,
+        EmailWindow:      time.Duration(cfg.LoginEmailWindowMinutes) * time.Minute,
+        LockoutBase:      time.Duration(cfg.LoginLockoutBaseSeconds) * time.Second,
+        LockoutMax:       time.Duration(cfg.LoginLockoutMaxMinutes) * time.Minute,
+        CheckoutIPMax:    cfg.CheckoutIPMaxOrders,
+        CheckoutIPWindow: time.Duration(cfg.CheckoutIPWindowMinutes) * time.Minute,
+    }
+
+    store = NewFallbackStore(NewRedisStore(database.Redis), NewMemoryStore())
+}
+
+// activeStore returns the store chosen by Setup. Code running without Setup
+// (tools, tests) gets a process-local store on first use.
+func activeStore() Store {
+    defaultStoreOnce.Do(func() {
+        if store == nil {
+            store = NewMemoryStore()
+        }
+    })
+    return store
+}
+
+// HitIP records a login attempt from ip and returns how long to wait when over the limit
+func HitIP(ctx context.Context, ip string) time.Duration {
+    return hitWindow(ctx, "login:ip:"+ip, limits.IPMaxAttempts, limits.IPWindow)
+}
+
+// HitCheckoutIP records a checkout from ip and returns how long to wait when over the limit
+func HitCheckoutIP(ctx context.Context, ip string) time.Duration {
+    return hitWindow(ctx, "checkout:ip:"+ip, limits.CheckoutIPMax, limits.CheckoutIPWindow)
+}
+
+// hitWindow counts a hit on key in a sliding window; errors let the request through
+func hitWindow(ctx context.Context, key string, max int, window time.Duration) time.Duration {
+    now := time.Now()
+    count, oldest, err := activeStore().Hit(ctx, key, now, window)
+    if err != nil {
+        log.Printf("Rate limit check failed for %s: %v", key, err)
+        return 0
+    }
+
+    if count <= int64(max) {
+        return 0
+    }
+
+    return retryAfter(oldest.Add(window).Sub(now))
+}
+
+// AccountLockedFor returns the remaining lockout for an email (0 when not locked)
+func AccountLockedFor(ctx context.Context, email string) time.Duration {
+    ttl, err := activeStore().LockTTL(ctx, lockKey(email))
+    if err != nil {
+        log.Printf("Lockout check failed: %v", err)
+        return 0
+    }
+    return ttl
+}
+
+// RecordFailure counts a failed login for email and locks the account once the
+// window limit is exceeded. Returns the lockout duration, 0 if not locked.
+func RecordFailure(ctx context.Context, email string) time.Duration {
+    count, _, err := activeStore().Hit(ctx, failuresKey(email), time.Now(), limits.EmailWindow)
+    if err != nil {
+        log.Printf("Failed to record login failure: %v", err)
+        return 0
+    }
+
+    if count < int64(limits.EmailMaxFailures) {
+        return 0
+    }
+
+    // Exponential backoff: base, 2*base, 4*base ... capped at max
+    lockouts, err := activeStore().Incr(ctx, lockoutsKey(email), lockoutMemory)
+    if err != nil {
+        lockouts = 1
+    }
+
+    duration := lockoutDuration(lockouts)
+    if err := activeStore().Lock(ctx, lockKey(email), duration); err != nil {
+        log.Printf("Failed to lock account: %v", err)
+        return 0
+    }
+
+    // Start counting afresh once the lock expires
+    _ = activeStore().Delete(ctx, failuresKey(email))
+
+    log.Printf("Account locked for %v after %d failed logins (lockout #%d)", duration, count, lockouts)
+    return retryAfter(duration)
+}
+
+// RecordSuccess clears failure history after a successful login
+func RecordSuccess(ctx context.Context, email string) {
+    if err := activeStore().Delete(ctx, failuresKey(email), lockoutsKey(email)); err != nil {
+        log.Printf("Failed to clear login failures: %v", err)
+    }
+}
+
+// Unlock removes any lockout and failure history for email (admin action)
+func Unlock(ctx context.Context, email string) error {
+    return activeStore().Delete(ctx, lockKey(email), failuresKey(email), lockoutsKey(email))
+}
+
+func lockoutDuration(lockouts int64) time.Duration {
+    if lockouts < 1 {
+        lockouts = 1
+    }
+
+    multiplier := math.Pow(2, float64(lockouts-1))
+    duration := time.Duration(float64(limits.LockoutBase) * multiplier)
+    if duration <= 0 || duration > limits.LockoutMax {
+        return limits.LockoutMax
+    }
+    return duration
+}
+
+// retryAfter rounds up to whole seconds for the Retry-After header
+func retryAfter(d time.Duration) time.Duration {
+    if d < time.Second {
+        return time.Second
+    }
+    return d.Round(time.Second)
+}
+
+// Email keys are hashed so addresses are not stored in Redis
+func emailKey(email string) string {
+    return utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func failuresKey(email string) string { return "login:fail:" + emailKey(email) }
+func lockoutsKey(email string) string { return "login:lockouts:" + emailKey(email) }
+func lockKey(email string) string     { return "login:lock:" + emailKey(email) }
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryCounter struct {
+    value     int64
+    expiresAt time.Time
+}
+
+// MemoryStore is a process-local Store used when Redis is unavailable.
+// Limits are per instance, so they are looser with several replicas.
+type MemoryStore struct {
+    mu       sync.Mutex
+    windows  map[string][]time.Time
+    counters map[string]memoryCounter
+    locks    map[string]time.Time
+
+    janitorOnce sync.Once
+}
+
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{
+        windows:  make(map[string][]time.Time),
+        counters: make(map[string]memoryCounter),
+        locks:    make(map[string]time.Time),
+    }
+}
+
+// startJanitor begins expiring entries once the store first holds any, so an
+// idle fallback behind Redis costs nothing
+func (s *MemoryStore) startJanitor() {
+    s.janitorOnce.Do(func() {
+        go s.janitor(time.Minute)
+    })
+}
+
+func (s *MemoryStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int64, time.Time, error) {
+    s.startJanitor()
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    events := prune(s.windows[key], now.Add(-window))
+    events = append(events, now)
+    s.windows[key] = events
+
+    return int64(len(events)), events[0], nil
+}
+
+func (s *MemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+    s.startJanitor()
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    counter, ok := s.counters[key]
+    if !ok || now.After(counter.expiresAt) {
+        counter = memoryCounter{expiresAt: now.Add(ttl)}
+    }
+    counter.value++
+    s.counters[key] = counter
+
+    return counter.value, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+    s.startJanitor()
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.locks[key] = time.Now().Add(ttl)
+    return nil
+}
+
+func (s *MemoryStore) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    until, ok := s.locks[key]
+    if !ok {
+        return 0, nil
+    }
+
+    remaining := time.Until(until)
+    if remaining <= 0 {
+        delete(s.locks, key)
+        return 0, nil
+    }
+    return remaining, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, key := range keys {
+        delete(s.windows, key)
+        delete(s.counters, key)
+        delete(s.locks, key)
+    }
+    return nil
+}
+
+// janitor drops expired entries so the maps don't grow unbounded
+func (s *MemoryStore) janitor(interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for now := range ticker.C {
+        s.mu.Lock()
+        for key, events := range s.windows {
+            // Windows are at most a day long
+            if len(events) == 0 || now.Sub(events[len(events)-1]) > 24*time.Hour {
+                delete(s.windows, key)
+            }
+        }
+        for key, counter := range s.counters {
+            if now.After(counter.expiresAt) {
+                delete(s.counters, key)
+            }
+        }
+        for key, until := range s.locks {
+            if now.After(until) {
+                delete(s.locks, key)
+            }
+        }
+        s.mu.Unlock()
+    }
+}
+
+// prune drops events older than cutoff (events are in ascending order)
+func prune(events []time.Time, cutoff time.Time) []time.Time {
+    i := 0
+    for i < len(events) && !events[i].After(cutoff) {
+        i++
+    }
+    return events[i:]
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStore keeps sliding windows in sorted sets scored by unix nanoseconds
+type RedisStore struct {
+    client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+    return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int64, time.Time, error) {
+    nowNano := now.UnixNano()
+    member := strconv.FormatInt(nowNano, 10) + "-" + strconv.Itoa(rand.Intn(1_000_000))
+
+    var card *redis.IntCmd
+    var oldest *redis.ZSliceCmd
+    _, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(nowNano-window.Nanoseconds(), 10))
+        pipe.ZAdd(ctx, key, &redis.Z{Score: float64(nowNano), Member: member})
+        card = pipe.ZCard(ctx, key)
+        oldest = pipe.ZRangeWithScores(ctx, key, 0, 0)
+        pipe.Expire(ctx, key, window)
+        return nil
+    })
+    if err != nil {
+        return 0, time.Time{}, fmt.Errorf("redis sliding window: %w", err)
+    }
+
+    oldestAt := now
+    if z := oldest.Val(); len(z) > 0 {
+        oldestAt = time.Unix(0, int64(z[0].Score))
+    }
+
+    return card.Val(), oldestAt, nil
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+    n, err := s.client.Incr(ctx, key).Result()
+    if err != nil {
+        return 0, err
+    }
+    if n == 1 {
+        if err := s.client.Expire(ctx, key, ttl).Err(); err != nil {
+            return 0, err
+        }
+    }
+    return n, nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+    return s.client.Set(ctx, key, "1", ttl).Err()
+}
+
+func (s *RedisStore) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+    ttl, err := s.client.PTTL(ctx, key).Result()
+    if err != nil {
+        return 0, err
+    }
+    // -2 = missing key, -1 = no expiry (never set by us)
+    if ttl < 0 {
+        return 0, nil
+    }
+    return ttl, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+    if len(keys) == 0 {
+        return nil
+    }
+    return s.client.Del(ctx, keys...).Err()
+}
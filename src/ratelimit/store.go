@@ -0,0 +1,20 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists sliding windows, counters and locks.
+// Redis is the primary implementation; memory is the fallback.
+type Store interface {
+    // Hit records an event at now and returns the events inside the window and the oldest one
+    Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int64, time.Time, error)
+    // Incr increments a counter that expires ttl after its first increment
+    Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+    // Lock sets a key that expires after ttl
+    Lock(ctx context.Context, key string, ttl time.Duration) error
+    // LockTTL returns the remaining lock time (0 when not locked)
+    LockTTL(ctx context.Context, key string) (time.Duration, error)
+    Delete(ctx context.Context, keys ...string) error
+}
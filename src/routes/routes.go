@@ -1,14 +1,18 @@
 package routes
 
 import (
+	"ambassador/src/audit"
 	"ambassador/src/config"
 	"ambassador/src/controllers"
 	"ambassador/src/middlewares"
+	"ambassador/src/models"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
 func Setup(app *fiber.App, cfg *config.Config) {
@@ -18,74 +22,154 @@ func Setup(app *fiber.App, cfg *config.Config) {
     // Health check
     app.Get("/health", controllers.HealthCheck)
 
+    // Uploaded files; keys are random and never rewritten, so they cache forever
+    if cfg.StorageDriver == "local" {
+        app.Static("/uploads", cfg.StorageLocalPath, fiber.Static{
+            MaxAge: 365 * 24 * 60 * 60,
+        })
+    }
+
      // API routes group
     api := app.Group("/api")
 
-    // Rate limiting 
-    // authLimiter := limiter.New(limiter.Config{
-    //     Max:        5,
-    //     Expiration: 15 * time.Minute,
-    //     KeyGenerator: func(c *fiber.Ctx) string {
-    //         return c.IP()
-    //     },
-    //     LimitReached: func(c *fiber.Ctx) error {
-    //         return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-    //             "error": "Too many requests. Please try again later.",
-    //         })
-    //     },
-    //     Storage: nil, // Use in-memory storage (for production, use Redis)
-    // })
+    // Email verification (link from the verification email)
+    api.Get("/verify-email", controllers.VerifyEmail)
 
     // PUBLIC AUTH ROUTES
     adminPublic := api.Group("/admin")
     adminPublic.Post("/register" , controllers.Register)
-    adminPublic.Post("/login",  controllers.Login)
+    adminPublic.Post("/login", middlewares.LoginRateLimit, controllers.Login)
+    adminPublic.Post("/login/mfa", middlewares.LoginRateLimit, controllers.LoginMFA)
+    adminPublic.Post("/password/forgot", middlewares.LoginRateLimit, controllers.ForgotPassword)
+    adminPublic.Post("/password/reset", middlewares.LoginRateLimit, controllers.ResetPassword)
 
     // PROTECTED ADMIN ROUTES 
     adminProtected := api.Group("/admin")
-    adminProtected.Use(middlewares.IsAuthenticated, middlewares.RequireScope("admin"))
+    adminProtected.Use(middlewares.IsAuthenticated, middlewares.RequireScope("admin"), middlewares.RequireMFAEnrollment, audit.Middleware())
     adminProtected.Get("/user", controllers.User)
     adminProtected.Post("/logout", controllers.Logout)
     adminProtected.Put("/users/info", controllers.UpdateInfo)
     adminProtected.Put("/users/password", controllers.UpdatePassword)
+    adminProtected.Post("/verify-email/resend", controllers.ResendVerification)
+    // Personal data export and account deletion
+    adminProtected.Get("/me/export", controllers.ExportMe)
+    adminProtected.Delete("/me", controllers.DeleteMe)
+    // Two-factor authentication
+    adminProtected.Get("/mfa", controllers.MFAStatus)
+    adminProtected.Post("/mfa/enroll", controllers.EnrollMFA)
+    adminProtected.Post("/mfa/verify", controllers.VerifyMFA)
+    adminProtected.Post("/mfa/recovery-codes", controllers.RegenerateRecoveryCodes)
+    adminProtected.Delete("/mfa", controllers.DisableMFA)
     // AMBASSADORS
     adminProtected.Get("/ambassadors", controllers.Ambassadors)
+    // User management
+    adminProtected.Get("/users", controllers.Users)
+    adminProtected.Post("/users/:id/unlock", controllers.UnlockUser)
+    adminProtected.Post("/users/:id/suspend", controllers.SuspendUser)
+    adminProtected.Post("/users/:id/unsuspend", controllers.UnsuspendUser)
+    adminProtected.Put("/users/:id/role", controllers.UpdateUserRole)
+    adminProtected.Delete("/users/:id", controllers.DeleteUser)
+    // Support impersonation (time-limited, recorded)
+    adminProtected.Post("/users/:id/impersonate", controllers.Impersonate)
+    adminProtected.Get("/impersonations", controllers.Impersonations)
+    adminProtected.Post("/impersonations/:id/end", controllers.EndImpersonation)
+    // Buyer data access and erasure requests
+    adminProtected.Get("/buyers/export", controllers.ExportBuyer)
+    adminProtected.Post("/buyers/erase", controllers.EraseBuyer)
+    // Audit log
+    adminProtected.Get("/audit", controllers.AuditLogs)
+    // Invites (admin registration is invite-only)
+    adminProtected.Get("/invites", controllers.Invites)
+    adminProtected.Post("/invites", controllers.CreateInvite)
     // Products
     adminProtected.Get("/products", controllers.Products)
     adminProtected.Post("/products", controllers.CreateProducts)
+    adminProtected.Post("/products/import", controllers.ImportProducts)
+    adminProtected.Get("/products/imports", controllers.ProductImports)
+    adminProtected.Get("/products/imports/:importId", controllers.GetProductImport)
     adminProtected.Get("/products/:id", controllers.GetProduct)
     adminProtected.Put("/products/:id", controllers.UpdateProduct)
     adminProtected.Delete("/products/:id", controllers.DeleteProduct)
+    adminProtected.Get("/products/:id/history", controllers.ProductHistory)
+    adminProtected.Post("/products/:id/revert", controllers.RevertProduct)
+    adminProtected.Put("/products/:id/sale", controllers.SetProductSale)
+    adminProtected.Delete("/products/:id/sale", controllers.RemoveProductSale)
+    adminProtected.Put("/products/:id/taxonomy", controllers.SetProductTaxonomy)
+    adminProtected.Put("/products/:id/stock", controllers.SetProductStock)
+    adminProtected.Get("/products/:id/images", controllers.ProductImages)
+    adminProtected.Post("/products/:id/images", controllers.UploadProductImages)
+    adminProtected.Put("/products/:id/images/order", controllers.ReorderProductImages)
+    adminProtected.Delete("/products/:id/images/:imageId", controllers.DeleteProductImage)
+    adminProtected.Get("/products/:id/variants", controllers.ProductVariants)
+    adminProtected.Post("/products/:id/variants", controllers.CreateProductVariant)
+    adminProtected.Put("/products/:id/variants/:variantId", controllers.UpdateProductVariant)
+    adminProtected.Delete("/products/:id/variants/:variantId", controllers.DeleteProductVariant)
+    // Categories (hierarchical) and tags
+    adminProtected.Get("/categories", controllers.Categories)
+    adminProtected.Post("/categories", controllers.CreateCategory)
+    adminProtected.Put("/categories/:id", controllers.UpdateCategory)
+    adminProtected.Delete("/categories/:id", controllers.DeleteCategory)
+    adminProtected.Get("/tags", controllers.Tags)
+    adminProtected.Post("/tags", controllers.CreateTag)
+    adminProtected.Put("/tags/:id", controllers.UpdateTag)
+    adminProtected.Delete("/tags/:id", controllers.DeleteTag)
     // Links
     adminProtected.Get("users/:id/links", controllers.Link)
     // Orders
     adminProtected.Get("/orders", controllers.Orders)
+    adminProtected.Get("/orders/:id", controllers.GetOrder)
+
+    /** ==================================================================== */
+
+    // CHECKOUT (buyers arriving through an ambassador link; no account)
+    checkout := api.Group("/checkout")
+    checkout.Post("/orders", middlewares.CheckoutRateLimit, controllers.CreateCheckoutOrder)
+    // Payment provider webhook, signed with CHECKOUT_WEBHOOK_SECRET
+    checkout.Post("/orders/confirm", middlewares.RequireCheckoutWebhook, controllers.ConfirmCheckoutOrder)
+    checkout.Post("/orders/cancel", controllers.CancelCheckoutOrder)
 
     /** ==================================================================== */
 
     // PUBLIC AMBASSADOR ROUTES
     ambassador := api.Group("/ambassador")
     ambassador.Post("/register", controllers.Register)
-    ambassador.Post("/login", controllers.Login)
+    ambassador.Post("/login", middlewares.LoginRateLimit, controllers.Login)
+    ambassador.Post("/password/forgot", middlewares.LoginRateLimit, controllers.ForgotPassword)
+    ambassador.Post("/password/reset", middlewares.LoginRateLimit, controllers.ResetPassword)
+    // OpenID Connect login
+    ambassador.Get("/oauth/login", middlewares.LoginRateLimit, controllers.OAuthLogin)
+    ambassador.Get("/oauth/callback", controllers.OAuthCallback)
 
     ambassador.Get("/products/frontend", controllers.ProductFrontEnd)
     ambassador.Get("/products/backend", controllers.ProductBackend)
+    ambassador.Get("/products/suggest", controllers.SuggestProducts)
+    ambassador.Get("/products/:id/variants", controllers.ProductVariants)
+    ambassador.Get("/products/:id/images", controllers.ProductImages)
+    ambassador.Get("/categories", controllers.Categories)
+    ambassador.Get("/tags", controllers.Tags)
 
     // PROTECTED AMBASSADOR ROUTES
     ambassadorAuthenticated := ambassador.Use(middlewares.IsAuthenticated,  middlewares.RequireScope("ambassador"))
-    ambassadorAuthenticated.Get("/user", controllers.User)
-    ambassadorAuthenticated.Post("/logout", controllers.Logout)
-    ambassadorAuthenticated.Put("/users/info", controllers.UpdateInfo)
-    ambassadorAuthenticated.Put("/users/password", controllers.UpdatePassword)
+    ambassadorAuthenticated.Get("/user", middlewares.RequireAPIKeyScope(models.APIKeyScopeProfileRead), controllers.User)
+    ambassadorAuthenticated.Post("/logout", middlewares.RequireSession, controllers.Logout)
+    ambassadorAuthenticated.Put("/users/info", middlewares.RequireSession, controllers.UpdateInfo)
+    ambassadorAuthenticated.Put("/users/password", middlewares.RequireSession, controllers.UpdatePassword)
+    ambassadorAuthenticated.Post("/verify-email/resend", middlewares.RequireSession, controllers.ResendVerification)
+    ambassadorAuthenticated.Get("/me/export", middlewares.RequireSession, controllers.ExportMe)
+    ambassadorAuthenticated.Delete("/me", middlewares.RequireSession, controllers.DeleteMe)
+    // Personal API keys (managed from a browser session only)
+    ambassadorAuthenticated.Get("/api-keys", middlewares.RequireSession, controllers.APIKeys)
+    ambassadorAuthenticated.Post("/api-keys", middlewares.RequireSession, controllers.CreateAPIKey)
+    ambassadorAuthenticated.Delete("/api-keys/:id", middlewares.RequireSession, controllers.RevokeAPIKey)
     // Links
-    ambassadorAuthenticated.Post("/links", controllers.CreateLink)
-    ambassadorAuthenticated.Get("/stats", controllers.Stats)
+    ambassadorAuthenticated.Post("/links", middlewares.RequireAPIKeyScope(models.APIKeyScopeLinksWrite), controllers.CreateLink)
+    ambassadorAuthenticated.Get("/stats", middlewares.RequireAPIKeyScope(models.APIKeyScopeStatsRead), controllers.Stats)
 
     // Orders
-    ambassadorAuthenticated.Get("/orders", controllers.Orders)
+    ambassadorAuthenticated.Get("/orders", middlewares.RequireAPIKeyScope(models.APIKeyScopeOrdersRead), controllers.Orders)
 
     // Rankings
-    ambassadorAuthenticated.Get("/rankings", controllers.Rankings)
+    ambassadorAuthenticated.Get("/rankings", middlewares.RequireAPIKeyScope(models.APIKeyScopeRankingsRead), controllers.Rankings)
 }
 
 // setupGlobalMiddleware configures middleware for all routes
@@ -95,27 +179,58 @@ func setupGlobalMiddleware(app *fiber.App, cfg *config.Config) {
         EnableStackTrace: !cfg.IsProduction(),
     }))
 
+    // Request IDs (X-Request-ID, echoed back and stored in the audit log)
+    app.Use(requestid.New())
+
     // Request logging
     if !cfg.IsProduction() {
         app.Use(logger.New(logger.Config{
-            Format:     "${time} | ${status} | ${latency} | ${method} ${path}\n",
+            Format:     "${time} | ${status} | ${latency} | ${method} ${path} | ${locals:requestid}\n",
             TimeFormat: "15:04:05",
             TimeZone:   "Local",
         }))
     } else {
         // Production logging (JSON format)
         app.Use(logger.New(logger.Config{
-            Format: `{"time":"${time}","status":${status},"latency":"${latency}","method":"${method}","path":"${path}","ip":"${ip}"}` + "\n",
+            Format: `{"time":"${time}","status":${status},"latency":"${latency}","method":"${method}","path":"${path}","ip":"${ip}","request_id":"${locals:requestid}"}` + "\n",
         }))
     }
 
+    // Request body size: image uploads and product imports take one full-size
+    // file plus form overhead, everything else the default
+    uploadLimit := (cfg.ImageMaxUploadMB + 1) << 20
+    app.Use(middlewares.BodyLimit(func(c *fiber.Ctx) int {
+        if isUploadRoute(c) {
+            return uploadLimit
+        }
+        return middlewares.DefaultBodyLimit
+    }))
+
     // CORS
     app.Use(cors.New(cors.Config{
         AllowOrigins:     cfg.CORSOrigins,
         AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
-        AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+        AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-Key,X-Request-ID",
+        ExposeHeaders:    "X-Request-ID",
         AllowCredentials: true,
         MaxAge:           86400, // 24 hours
     }))
 }
 
+
+// isUploadRoute matches the routes that accept files:
+// POST /api/admin/products/import and POST /api/admin/products/:id/images
+func isUploadRoute(c *fiber.Ctx) bool {
+    if c.Method() != fiber.MethodPost {
+        return false
+    }
+    rest, ok := strings.CutPrefix(c.Path(), "/api/admin/products/")
+    if !ok {
+        return false
+    }
+    if rest == "import" {
+        return true
+    }
+    id, ok := strings.CutSuffix(rest, "/images")
+    return ok && id != "" && !strings.Contains(id, "/")
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"context"
+	"log"
+	"time"
+)
+
+// importSweepInterval is how often imports of stopped instances are failed
+const importSweepInterval = time.Minute
+
+// runImportSweep fails product imports whose instance stopped (crash, restart
+// or scale-down); their rows only lived in that instance's memory
+func runImportSweep(ctx context.Context) {
+    ticker := time.NewTicker(importSweepInterval)
+    defer ticker.Stop()
+
+    for {
+        n, err := models.FailInterruptedImports(database.DB.WithContext(ctx), time.Now())
+        if err != nil {
+            log.Printf("Product import sweep failed: %v", err)
+        } else if n > 0 {
+            log.Printf("Marked %d interrupted product imports as failed", n)
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"context"
+	"log"
+	"time"
+)
+
+// productStatsInterval is how often product_stats is rebuilt from scratch.
+// Order changes refresh their products right away; the rebuild repairs
+// anything missed (orders edited directly in the database, failed refreshes).
+const productStatsInterval = time.Hour
+
+func runProductStats(ctx context.Context) {
+    ticker := time.NewTicker(productStatsInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            start := time.Now()
+            if err := models.RefreshProductStats(database.DB.WithContext(ctx)); err != nil {
+                log.Printf("Product stats rebuild failed: %v", err)
+                continue
+            }
+            log.Printf("Product stats rebuilt in %v", time.Since(start))
+        }
+    }
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+    // reservationExpiryInterval bounds how long expired checkouts keep their stock
+    reservationExpiryInterval = 30 * time.Second
+    reservationExpiryBatch    = 200
+)
+
+// runReservationExpiry cancels unpaid checkout orders past their reservation
+// and returns the stock they held
+func runReservationExpiry(ctx context.Context) {
+    ticker := time.NewTicker(reservationExpiryInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            expired, restocked, err := models.ExpireCheckoutOrders(database.DB.WithContext(ctx), time.Now(), reservationExpiryBatch)
+            if err != nil {
+                log.Printf("Checkout reservation expiry failed: %v", err)
+            }
+            if expired > 0 {
+                log.Printf("Released stock of %d expired checkout orders", expired)
+            }
+            if len(restocked) > 0 {
+                database.ClearProductCaches(ctx, restocked...)
+            }
+        }
+    }
+}
@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"ambassador/src/database"
+	"ambassador/src/models"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+    // Upper bound between checks, so sales scheduled by other instances are picked up
+    maxSaleWait = 10 * time.Minute
+
+    // A response computed just before a boundary may be cached just after it;
+    // caches are cleared a second time after this delay
+    saleRecheckDelay = 5 * time.Second
+)
+
+// saleChanged wakes the watcher when a sale is created, changed or removed
+var saleChanged = make(chan struct{}, 1)
+
+// NotifySaleChanged makes the watcher recompute the next sale boundary
+func NotifySaleChanged() {
+    select {
+    case saleChanged <- struct{}{}:
+    default:
+    }
+}
+
+// runSaleBoundaries sleeps until the next sale starts or ends, then invalidates
+// the product caches so listings switch between original and sale price on time
+func runSaleBoundaries(ctx context.Context) {
+    last := time.Now()
+
+    // Boundaries may have passed while the app was down
+    if err := database.ClearProductCachesSync(ctx); err != nil {
+        log.Printf("Scheduler: initial product cache clear failed: %v", err)
+    }
+
+    for {
+        wait := maxSaleWait
+        next, err := nextSaleBoundary(last)
+        if err != nil {
+            log.Printf("Scheduler: failed to find next sale boundary: %v", err)
+        } else if next != nil {
+            if d := time.Until(*next); d < wait {
+                wait = d
+            }
+        }
+        if wait < 0 {
+            wait = 0
+        }
+
+        timer := time.NewTimer(wait)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-saleChanged:
+            timer.Stop()
+        case <-timer.C:
+        }
+
+        now := time.Now()
+        ids, err := productsCrossingBoundary(last, now)
+        if err != nil {
+            log.Printf("Scheduler: failed to load sale boundaries: %v", err)
+            continue // retry the same window
+        }
+        last = now
+
+        if len(ids) > 0 {
+            log.Printf("Scheduler: sale started or ended for products %v", ids)
+            if err := database.ClearProductCachesSync(ctx, ids...); err != nil {
+                log.Printf("Scheduler: product cache clear failed: %v", err)
+            }
+            time.AfterFunc(saleRecheckDelay, func() {
+                if err := database.ClearProductCachesSync(context.Background(), ids...); err != nil {
+                    log.Printf("Scheduler: product cache clear failed: %v", err)
+                }
+            })
+        }
+    }
+}
+
+// nextSaleBoundary returns the earliest sale start or end after since
+func nextSaleBoundary(since time.Time) (*time.Time, error) {
+    var next struct {
+        StartsAt *time.Time
+        EndsAt   *time.Time
+    }
+
+    err := database.DB.Model(&models.Product{}).
+        Select("MIN(CASE WHEN sale_starts_at > ? THEN sale_starts_at END) AS starts_at, "+
+            "MIN(CASE WHEN sale_ends_at > ? THEN sale_ends_at END) AS ends_at", since, since).
+        Where("sale_price IS NOT NULL").
+        Scan(&next).Error
+    if err != nil {
+        return nil, err
+    }
+
+    switch {
+    case next.StartsAt == nil:
+        return next.EndsAt, nil
+    case next.EndsAt == nil || next.StartsAt.Before(*next.EndsAt):
+        return next.StartsAt, nil
+    default:
+        return next.EndsAt, nil
+    }
+}
+
+// productsCrossingBoundary lists products whose sale started or ended in (from, to]
+func productsCrossingBoundary(from, to time.Time) ([]uint, error) {
+    var ids []uint
+    err := database.DB.Model(&models.Product{}).
+        Where("sale_price IS NOT NULL").
+        Where("((sale_starts_at > ? AND sale_starts_at <= ?) OR (sale_ends_at > ? AND sale_ends_at <= ?))", from, to, from, to).
+        Pluck("id", &ids).Error
+    return ids, err
+}
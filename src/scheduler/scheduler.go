@@ -0,0 +1,17 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+)
+
+// Start launches the background jobs; they stop when ctx is cancelled
+func Start(ctx context.Context) {
+    go runSaleBoundaries(ctx)
+    go runSuggestRefresh(ctx)
+    go runProductStats(ctx)
+    go runReservationExpiry(ctx)
+    go runImportSweep(ctx)
+
+    log.Println("Scheduler started")
+}
@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"ambassador/src/search"
+	"context"
+	"time"
+)
+
+// suggestRefreshInterval picks up new orders in the suggestion ranking
+const suggestRefreshInterval = 10 * time.Minute
+
+// runSuggestRefresh periodically rebuilds the typeahead trie
+func runSuggestRefresh(ctx context.Context) {
+    ticker := time.NewTicker(suggestRefreshInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            search.Suggestions.ScheduleRefresh()
+        }
+    }
+}
@@ -0,0 +1,59 @@
+package search
+
+import "unicode/utf8"
+
+// maxEdits is the typo budget for a query word: short words must match
+// exactly, longer ones tolerate one or two edits
+func maxEdits(word string) int {
+    switch n := utf8.RuneCountInString(word); {
+    case n < 4:
+        return 0
+    case n < 8:
+        return 1
+    default:
+        return 2
+    }
+}
+
+// withinDistance reports whether the Damerau-Levenshtein (optimal string
+// alignment) distance between a and b is at most max, and the distance
+func withinDistance(a, b string, max int) (int, bool) {
+    ra, rb := []rune(a), []rune(b)
+    if d := len(ra) - len(rb); d > max || -d > max {
+        return 0, false
+    }
+
+    // Three rows are enough for adjacent transpositions
+    prev2 := make([]int, len(rb)+1)
+    prev := make([]int, len(rb)+1)
+    cur := make([]int, len(rb)+1)
+    for j := range prev {
+        prev[j] = j
+    }
+
+    for i := 1; i <= len(ra); i++ {
+        cur[0] = i
+        rowMin := cur[0]
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+            if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && prev2[j-2]+1 < cur[j] {
+                cur[j] = prev2[j-2] + 1
+            }
+            if cur[j] < rowMin {
+                rowMin = cur[j]
+            }
+        }
+        // Every later row is at least this far off
+        if rowMin > max {
+            return 0, false
+        }
+        prev2, prev, cur = prev, cur, prev2
+    }
+
+    d := prev[len(rb)]
+    return d, d <= max
+}
@@ -0,0 +1,51 @@
+package search
+
+import "testing"
+
+func TestMaxEdits(t *testing.T) {
+    tests := []struct {
+        word string
+        want int
+    }{
+        {"", 0},
+        {"hat", 0},
+        {"shoe", 1},
+        {"leather", 1},
+        {"blankets", 2},
+        {"sweatshirts", 2},
+        {"über", 1}, // counted in letters, not bytes
+    }
+    for _, tt := range tests {
+        if got := maxEdits(tt.word); got != tt.want {
+            t.Errorf("maxEdits(%q) = %d, want %d", tt.word, got, tt.want)
+        }
+    }
+}
+
+func TestWithinDistance(t *testing.T) {
+    tests := []struct {
+        a, b   string
+        max    int
+        want   int
+        within bool
+    }{
+        {"shoe", "shoe", 0, 0, true},
+        {"leather", "lether", 1, 1, true},  // deletion
+        {"shoe", "shoes", 1, 1, true},      // insertion
+        {"jacket", "jackit", 1, 1, true},   // substitution
+        {"wallet", "walelt", 1, 1, true},   // adjacent transposition counts once
+        {"kitten", "sitting", 3, 3, true},
+        {"kitten", "sitting", 2, 0, false},
+        {"hat", "sweater", 2, 0, false},    // length difference alone is too far
+        {"abcdef", "badcfe", 2, 0, false},  // rows exceed max early
+        {"héllo", "hello", 1, 1, true},     // runes, not bytes
+        {"", "", 0, 0, true},
+        {"abc", "", 3, 3, true},
+    }
+    for _, tt := range tests {
+        d, ok := withinDistance(tt.a, tt.b, tt.max)
+        if ok != tt.within || (ok && d != tt.want) {
+            t.Errorf("withinDistance(%q, %q, %d) = %d, %v; want %d, %v", tt.a, tt.b, tt.max, d, ok, tt.want, tt.within)
+        }
+    }
+}
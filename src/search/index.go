@@ -0,0 +1,280 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25F parameters: title matches weigh more than description matches
+const (
+    bm25K1      = 1.2
+    bm25B       = 0.75
+    titleWeight = 2.0
+    bodyWeight  = 1.0
+)
+
+// Document is the indexed text of a product
+type Document struct {
+    ID    uint
+    Title string
+    Body  string
+}
+
+// Result is a matching document and its BM25 score
+type Result struct {
+    ID    uint
+    Score float64
+}
+
+// Hits are the results of a query, best first, with the words that matched
+// (original spellings, for highlighting). Total counts every match, also
+// those cut by the limit.
+type Hits struct {
+    Results []Result
+    Words   []string
+    Total   int
+}
+
+type posting struct {
+    titleTF int
+    bodyTF  int
+}
+
+type docStats struct {
+    titleLen int
+    bodyLen  int
+    terms    []string            // distinct terms, for removal
+    words    map[string][]string // term → surface words in this document
+}
+
+// Index is an in-memory inverted index with BM25F ranking. It is safe for
+// concurrent use; writers replace a document atomically.
+type Index struct {
+    mu       sync.RWMutex
+    postings map[string]map[uint]posting
+    words    map[string]map[string]int // term → surface word → documents using it
+    docs     map[uint]*docStats
+    titleLen int // totals for average field lengths
+    bodyLen  int
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+    idx := &Index{}
+    idx.reset()
+    return idx
+}
+
+func (idx *Index) reset() {
+    idx.postings = make(map[string]map[uint]posting)
+    idx.words = make(map[string]map[string]int)
+    idx.docs = make(map[uint]*docStats)
+    idx.titleLen = 0
+    idx.bodyLen = 0
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+    return len(idx.docs)
+}
+
+// Put adds a document, replacing any previous version with the same ID
+func (idx *Index) Put(doc Document) {
+    idx.mu.Lock()
+    defer idx.mu.Unlock()
+    idx.remove(doc.ID)
+    idx.add(doc)
+}
+
+// Remove drops a document from the index
+func (idx *Index) Remove(id uint) {
+    idx.mu.Lock()
+    defer idx.mu.Unlock()
+    idx.remove(id)
+}
+
+// Replace swaps the whole contents of the index for docs
+func (idx *Index) Replace(docs []Document) {
+    fresh := NewIndex()
+    for _, doc := range docs {
+        fresh.add(doc)
+    }
+
+    idx.mu.Lock()
+    defer idx.mu.Unlock()
+    idx.postings, idx.words, idx.docs = fresh.postings, fresh.words, fresh.docs
+    idx.titleLen, idx.bodyLen = fresh.titleLen, fresh.bodyLen
+}
+
+func (idx *Index) add(doc Document) {
+    title, body := Tokenize(doc.Title), Tokenize(doc.Body)
+    stats := &docStats{
+        titleLen: len(title),
+        bodyLen:  len(body),
+        words:    make(map[string][]string),
+    }
+
+    tf := make(map[string]posting)
+    seenWord := make(map[string]bool)
+    noteWord := func(token Token) {
+        if !seenWord[token.Word] {
+            seenWord[token.Word] = true
+            stats.words[token.Term] = append(stats.words[token.Term], token.Word)
+        }
+    }
+    for _, token := range title {
+        entry := tf[token.Term]
+        entry.titleTF++
+        tf[token.Term] = entry
+        noteWord(token)
+    }
+    for _, token := range body {
+        entry := tf[token.Term]
+        entry.bodyTF++
+        tf[token.Term] = entry
+        noteWord(token)
+    }
+    idx.titleLen += stats.titleLen
+    idx.bodyLen += stats.bodyLen
+
+    for term, entry := range tf {
+        if idx.postings[term] == nil {
+            idx.postings[term] = make(map[uint]posting)
+        }
+        idx.postings[term][doc.ID] = entry
+        stats.terms = append(stats.terms, term)
+
+        if idx.words[term] == nil {
+            idx.words[term] = make(map[string]int)
+        }
+        for _, word := range stats.words[term] {
+            idx.words[term][word]++
+        }
+    }
+
+    idx.docs[doc.ID] = stats
+}
+
+func (idx *Index) remove(id uint) {
+    stats, ok := idx.docs[id]
+    if !ok {
+        return
+    }
+
+    for _, term := range stats.terms {
+        delete(idx.postings[term], id)
+        if len(idx.postings[term]) == 0 {
+            delete(idx.postings, term)
+        }
+        for _, word := range stats.words[term] {
+            if idx.words[term][word]--; idx.words[term][word] <= 0 {
+                delete(idx.words[term], word)
+            }
+        }
+        if len(idx.words[term]) == 0 {
+            delete(idx.words, term)
+        }
+    }
+
+    idx.titleLen -= stats.titleLen
+    idx.bodyLen -= stats.bodyLen
+    delete(idx.docs, id)
+}
+
+// Search ranks documents matching any query word with BM25F. A word that
+// isn't in the index is matched against similar indexed terms instead
+// (one typo for words of 4-7 letters, two from 8), at a reduced weight.
+// At most limit results are returned; limit <= 0 means all.
+func (idx *Index) Search(query string, limit int) Hits {
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    hits := Hits{Results: []Result{}, Words: []string{}}
+    if len(idx.docs) == 0 {
+        return hits
+    }
+
+    avgTitle := math.Max(float64(idx.titleLen)/float64(len(idx.docs)), 1)
+    avgBody := math.Max(float64(idx.bodyLen)/float64(len(idx.docs)), 1)
+    n := float64(len(idx.docs))
+
+    scores := make(map[uint]float64)
+    matched := make(map[string]bool)
+    seen := make(map[string]bool)
+
+    for _, token := range Tokenize(query) {
+        if seen[token.Term] {
+            continue
+        }
+        seen[token.Term] = true
+
+        for term, weight := range idx.expand(token) {
+            matched[term] = true
+
+            docs := idx.postings[term]
+            df := float64(len(docs))
+            idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+            for id, p := range docs {
+                stats := idx.docs[id]
+                tf := titleWeight*float64(p.titleTF)/(1-bm25B+bm25B*float64(stats.titleLen)/avgTitle) +
+                    bodyWeight*float64(p.bodyTF)/(1-bm25B+bm25B*float64(stats.bodyLen)/avgBody)
+                scores[id] += weight * idf * tf * (bm25K1 + 1) / (tf + bm25K1)
+            }
+        }
+    }
+
+    for id, score := range scores {
+        hits.Results = append(hits.Results, Result{ID: id, Score: score})
+    }
+    sort.Slice(hits.Results, func(i, j int) bool {
+        if hits.Results[i].Score != hits.Results[j].Score {
+            return hits.Results[i].Score > hits.Results[j].Score
+        }
+        return hits.Results[i].ID < hits.Results[j].ID
+    })
+    hits.Total = len(hits.Results)
+    if limit > 0 && len(hits.Results) > limit {
+        hits.Results = hits.Results[:limit]
+    }
+
+    for term := range matched {
+        for word := range idx.words[term] {
+            hits.Words = append(hits.Words, word)
+        }
+    }
+    sort.Strings(hits.Words)
+
+    return hits
+}
+
+// expand maps a query token to the indexed terms it matches and their weights
+func (idx *Index) expand(token Token) map[string]float64 {
+    if _, ok := idx.postings[token.Term]; ok {
+        return map[string]float64{token.Term: 1}
+    }
+
+    edits := maxEdits(token.Word)
+    if edits == 0 {
+        return nil
+    }
+
+    // Compare both the stem and the raw word: typos often break stemming
+    expansions := make(map[string]float64)
+    for term := range idx.postings {
+        best := -1
+        if d, ok := withinDistance(token.Term, term, edits); ok {
+            best = d
+        }
+        if d, ok := withinDistance(token.Word, term, edits); ok && (best < 0 || d < best) {
+            best = d
+        }
+        if best > 0 {
+            expansions[term] = 1 / float64(1+best)
+        }
+    }
+    return expansions
+}
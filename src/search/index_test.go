@@ -0,0 +1,183 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testIndex(docs ...Document) *Index {
+    idx := NewIndex()
+    idx.Replace(docs)
+    return idx
+}
+
+func resultIDs(hits Hits) []uint {
+    ids := []uint{}
+    for _, result := range hits.Results {
+        ids = append(ids, result.ID)
+    }
+    return ids
+}
+
+func TestIndexSearchRanking(t *testing.T) {
+    catalog := []Document{
+        {ID: 1, Title: "Leather wallet", Body: "Slim wallet in brown leather"},
+        {ID: 2, Title: "Canvas tote bag", Body: "Carry your leather goods in style"},
+        {ID: 3, Title: "Running shoes", Body: "Light shoes for running on roads and trails"},
+        {ID: 4, Title: "Trail running shoes with extra grip for wet and muddy mountain paths", Body: "Waterproof"},
+        {ID: 5, Title: "Wool socks", Body: "Warm socks"},
+        {ID: 6, Title: "Cotton socks", Body: "Soft socks"},
+    }
+    tests := []struct {
+        name  string
+        query string
+        want  []uint
+        words []string
+    }{
+        {
+            name:  "title match ranks above body match",
+            query: "leather",
+            want:  []uint{1, 2},
+            words: []string{"leather"},
+        },
+        {
+            name:  "shorter title ranks above a long one",
+            query: "running shoes",
+            want:  []uint{3, 4},
+            words: []string{"running", "shoes"},
+        },
+        {
+            name:  "rare word outweighs a common one",
+            query: "wool socks",
+            want:  []uint{5, 6},
+            words: []string{"socks", "wool"},
+        },
+        {
+            name:  "equal scores fall back to ID",
+            query: "socks",
+            want:  []uint{5, 6},
+            words: []string{"socks"},
+        },
+        {
+            name:  "query words are stemmed like documents",
+            query: "RUN",
+            want:  []uint{3, 4},
+            words: []string{"running"},
+        },
+        {
+            name:  "typo matches a similar term",
+            query: "lether",
+            want:  []uint{1, 2},
+            words: []string{"leather"},
+        },
+        {
+            name:  "short words must match exactly",
+            query: "bga",
+            want:  []uint{},
+            words: []string{},
+        },
+        {
+            name:  "stopwords alone match nothing",
+            query: "the and with",
+            want:  []uint{},
+            words: []string{},
+        },
+    }
+    idx := testIndex(catalog...)
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            hits := idx.Search(tt.query, 0)
+            if got := resultIDs(hits); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("results = %v, want %v (%+v)", got, tt.want, hits.Results)
+            }
+            if !reflect.DeepEqual(hits.Words, tt.words) {
+                t.Errorf("words = %v, want %v", hits.Words, tt.words)
+            }
+            if hits.Total != len(tt.want) {
+                t.Errorf("total = %d, want %d", hits.Total, len(tt.want))
+            }
+        })
+    }
+}
+
+func TestIndexSearchTypoScoresLower(t *testing.T) {
+    idx := testIndex(
+        Document{ID: 1, Title: "Leather wallet"},
+        Document{ID: 2, Title: "Canvas wallet"},
+    )
+    exact := idx.Search("leather", 0)
+    typo := idx.Search("lether", 0)
+    if len(exact.Results) != 1 || len(typo.Results) != 1 {
+        t.Fatalf("exact %+v, typo %+v: want one result each", exact.Results, typo.Results)
+    }
+    if typo.Results[0].Score >= exact.Results[0].Score {
+        t.Errorf("typo score %f not below exact score %f", typo.Results[0].Score, exact.Results[0].Score)
+    }
+}
+
+func TestIndexSearchLimit(t *testing.T) {
+    idx := testIndex(
+        Document{ID: 1, Title: "Red hat"},
+        Document{ID: 2, Title: "Red scarf"},
+        Document{ID: 3, Title: "Red gloves"},
+    )
+    hits := idx.Search("red", 2)
+    if got := resultIDs(hits); !reflect.DeepEqual(got, []uint{1, 2}) {
+        t.Errorf("results = %v, want [1 2]", got)
+    }
+    if hits.Total != 3 {
+        t.Errorf("total = %d, want 3 (matches cut by the limit still count)", hits.Total)
+    }
+}
+
+func TestIndexUpdates(t *testing.T) {
+    idx := NewIndex()
+    if hits := idx.Search("anything", 0); hits.Results == nil || hits.Words == nil || hits.Total != 0 {
+        t.Errorf("empty index: %+v, want empty non-nil slices", hits)
+    }
+
+    idx.Put(Document{ID: 1, Title: "Blue mug"})
+    idx.Put(Document{ID: 2, Title: "Blue plate"})
+    if idx.Len() != 2 {
+        t.Fatalf("len = %d, want 2", idx.Len())
+    }
+
+    // Put replaces the previous version of a document
+    idx.Put(Document{ID: 1, Title: "Green mug"})
+    if got := resultIDs(idx.Search("blue", 0)); !reflect.DeepEqual(got, []uint{2}) {
+        t.Errorf("blue after re-put = %v, want [2]", got)
+    }
+    if got := resultIDs(idx.Search("green", 0)); !reflect.DeepEqual(got, []uint{1}) {
+        t.Errorf("green after re-put = %v, want [1]", got)
+    }
+
+    // Removing the last document using a word forgets the word
+    idx.Remove(2)
+    hits := idx.Search("blue", 0)
+    if len(hits.Results) != 0 || len(hits.Words) != 0 {
+        t.Errorf("blue after remove = %+v, want no results or words", hits)
+    }
+    idx.Remove(42) // unknown IDs are ignored
+    if idx.Len() != 1 {
+        t.Errorf("len = %d, want 1", idx.Len())
+    }
+
+    idx.Replace([]Document{{ID: 7, Title: "Yellow lamp"}})
+    if got := resultIDs(idx.Search("green yellow", 0)); !reflect.DeepEqual(got, []uint{7}) {
+        t.Errorf("after replace = %v, want [7]", got)
+    }
+}
+
+func TestIndexSearchWordsKeepSpellings(t *testing.T) {
+    idx := testIndex(
+        Document{ID: 1, Title: "Connected speaker"},
+        Document{ID: 2, Title: "Speaker connections"},
+    )
+    hits := idx.Search("connecting", 0)
+    if got := resultIDs(hits); len(got) != 2 {
+        t.Errorf("results = %v, want both documents", got)
+    }
+    if want := []string{"connected", "connections"}; !reflect.DeepEqual(hits.Words, want) {
+        t.Errorf("words = %v, want %v", hits.Words, want)
+    }
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"ambassador/src/models"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxProductResults caps the ranked IDs a product query returns; listings
+// flag results past it as capped
+const MaxProductResults = 1000
+
+// Products is the product index used when SEARCH_BACKEND=memory. Each
+// process keeps its own copy: built by RebuildProducts at startup and kept
+// current by the product controllers through IndexProduct/RemoveProduct.
+// Changes made by other processes are not seen until a restart, so this
+// backend supports a single API instance only.
+var Products = NewIndex()
+
+var productsEnabled atomic.Bool
+
+// ProductsEnabled reports whether the in-memory index backs product search
+func ProductsEnabled() bool {
+    return productsEnabled.Load()
+}
+
+// RebuildProducts loads every product into the index and enables it
+func RebuildProducts(db *gorm.DB) error {
+    start := time.Now()
+
+    var docs []Document
+    var batch []models.Product
+    err := db.Model(&models.Product{}).
+        Select("id, title, description").
+        FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+            for _, product := range batch {
+                docs = append(docs, productDocument(&product))
+            }
+            return nil
+        }).Error
+    if err != nil {
+        return err
+    }
+
+    Products.Replace(docs)
+    productsEnabled.Store(true)
+
+    log.Printf("Search index built: %d products in %v", len(docs), time.Since(start))
+    return nil
+}
+
+// IndexProduct adds or refreshes a product after it was saved
+func IndexProduct(product *models.Product) {
+    Suggestions.ScheduleRefresh()
+    if ProductsEnabled() {
+        Products.Put(productDocument(product))
+    }
+}
+
+// RemoveProduct drops a deleted product from the indexes
+func RemoveProduct(id uint) {
+    Suggestions.ScheduleRefresh()
+    if ProductsEnabled() {
+        Products.Remove(id)
+    }
+}
+
+func productDocument(product *models.Product) Document {
+    return Document{ID: product.ID, Title: product.Title, Body: product.Description}
+}
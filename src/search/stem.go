@@ -0,0 +1,216 @@
+package search
+
+// Stem reduces an English word to its Porter stem ("running" → "run",
+// "connections" → "connect"). Words that aren't lowercase ASCII letters,
+// or are shorter than three letters, are returned unchanged.
+func Stem(word string) string {
+    if len(word) < 3 {
+        return word
+    }
+    for i := 0; i < len(word); i++ {
+        if word[i] < 'a' || word[i] > 'z' {
+            return word
+        }
+    }
+
+    s := &stemmer{b: []byte(word)}
+    s.step1a()
+    s.step1b()
+    s.step1c()
+    s.step2()
+    s.step3()
+    s.step4()
+    s.step5()
+    return string(s.b)
+}
+
+// stemmer follows M.F. Porter, "An algorithm for suffix stripping" (1980)
+type stemmer struct {
+    b []byte
+}
+
+func (s *stemmer) cons(i int) bool {
+    switch s.b[i] {
+    case 'a', 'e', 'i', 'o', 'u':
+        return false
+    case 'y':
+        return i == 0 || !s.cons(i-1)
+    }
+    return true
+}
+
+// measure counts the VC sequences in b[:end]
+func (s *stemmer) measure(end int) int {
+    n, i := 0, 0
+    for i < end && s.cons(i) {
+        i++
+    }
+    for i < end {
+        for i < end && !s.cons(i) {
+            i++
+        }
+        if i >= end {
+            break
+        }
+        n++
+        for i < end && s.cons(i) {
+            i++
+        }
+    }
+    return n
+}
+
+func (s *stemmer) hasVowel(end int) bool {
+    for i := 0; i < end; i++ {
+        if !s.cons(i) {
+            return true
+        }
+    }
+    return false
+}
+
+func (s *stemmer) doubleCons(end int) bool {
+    return end >= 2 && s.b[end-1] == s.b[end-2] && s.cons(end-1)
+}
+
+// cvc: b[:end] ends consonant-vowel-consonant, the last not w, x or y
+func (s *stemmer) cvc(end int) bool {
+    if end < 3 || !s.cons(end-1) || s.cons(end-2) || !s.cons(end-3) {
+        return false
+    }
+    c := s.b[end-1]
+    return c != 'w' && c != 'x' && c != 'y'
+}
+
+func (s *stemmer) ends(suffix string) bool {
+    n := len(s.b) - len(suffix)
+    return n >= 0 && string(s.b[n:]) == suffix
+}
+
+// replace swaps suffix for repl when the remaining stem has measure > m
+func (s *stemmer) replace(suffix, repl string, m int) bool {
+    if !s.ends(suffix) {
+        return false
+    }
+    stem := len(s.b) - len(suffix)
+    if s.measure(stem) > m {
+        s.b = append(s.b[:stem], repl...)
+    }
+    return true
+}
+
+func (s *stemmer) step1a() {
+    switch {
+    case s.ends("sses"):
+        s.b = s.b[:len(s.b)-2]
+    case s.ends("ies"):
+        s.b = s.b[:len(s.b)-2]
+    case s.ends("ss"):
+    case s.ends("s"):
+        s.b = s.b[:len(s.b)-1]
+    }
+}
+
+func (s *stemmer) step1b() {
+    if s.ends("eed") {
+        if s.measure(len(s.b)-3) > 0 {
+            s.b = s.b[:len(s.b)-1]
+        }
+        return
+    }
+
+    var stem int
+    switch {
+    case s.ends("ed") && s.hasVowel(len(s.b)-2):
+        stem = len(s.b) - 2
+    case s.ends("ing") && s.hasVowel(len(s.b)-3):
+        stem = len(s.b) - 3
+    default:
+        return
+    }
+    s.b = s.b[:stem]
+
+    switch {
+    case s.ends("at"), s.ends("bl"), s.ends("iz"):
+        s.b = append(s.b, 'e')
+    case s.doubleCons(len(s.b)):
+        if c := s.b[len(s.b)-1]; c != 'l' && c != 's' && c != 'z' {
+            s.b = s.b[:len(s.b)-1]
+        }
+    case s.measure(len(s.b)) == 1 && s.cvc(len(s.b)):
+        s.b = append(s.b, 'e')
+    }
+}
+
+func (s *stemmer) step1c() {
+    if s.ends("y") && s.hasVowel(len(s.b)-1) {
+        s.b[len(s.b)-1] = 'i'
+    }
+}
+
+var step2Suffixes = [][2]string{
+    {"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+    {"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+    {"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+    {"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+    {"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func (s *stemmer) step2() {
+    for _, r := range step2Suffixes {
+        if s.replace(r[0], r[1], 0) {
+            return
+        }
+    }
+}
+
+var step3Suffixes = [][2]string{
+    {"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+    {"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func (s *stemmer) step3() {
+    for _, r := range step3Suffixes {
+        if s.replace(r[0], r[1], 0) {
+            return
+        }
+    }
+}
+
+var step4Suffixes = []string{
+    "al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+    "ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func (s *stemmer) step4() {
+    // Longest matching suffix wins ("ement" over "ment" over "ent")
+    match := ""
+    for _, suffix := range step4Suffixes {
+        if len(suffix) > len(match) && s.ends(suffix) {
+            match = suffix
+        }
+    }
+    if match == "" {
+        return
+    }
+
+    stem := len(s.b) - len(match)
+    if match == "ion" && (stem == 0 || (s.b[stem-1] != 's' && s.b[stem-1] != 't')) {
+        return
+    }
+    if s.measure(stem) > 1 {
+        s.b = s.b[:stem]
+    }
+}
+
+func (s *stemmer) step5() {
+    if s.ends("e") {
+        stem := len(s.b) - 1
+        if m := s.measure(stem); m > 1 || (m == 1 && !s.cvc(stem)) {
+            s.b = s.b[:stem]
+        }
+    }
+    if s.ends("ll") && s.measure(len(s.b)) > 1 {
+        s.b = s.b[:len(s.b)-1]
+    }
+}
@@ -0,0 +1,68 @@
+package search
+
+import "testing"
+
+func TestStem(t *testing.T) {
+    // Examples from Porter's paper, step by step, and product vocabulary
+    tests := []struct {
+        word string
+        want string
+    }{
+        // Step 1a: plurals
+        {"caresses", "caress"},
+        {"ponies", "poni"},
+        {"ties", "ti"},
+        {"caress", "caress"},
+        {"cats", "cat"},
+        // Step 1b: -ed and -ing
+        {"feed", "feed"},
+        {"agreed", "agre"},
+        {"plastered", "plaster"},
+        {"bled", "bled"},
+        {"motoring", "motor"},
+        {"sing", "sing"},
+        {"conflated", "conflat"},
+        {"troubled", "troubl"},
+        {"sized", "size"},
+        {"hopping", "hop"},
+        {"falling", "fall"},
+        {"hissing", "hiss"},
+        {"filing", "file"},
+        // Step 1c: y → i
+        {"happy", "happi"},
+        {"sky", "sky"},
+        // Steps 2-4: derivational suffixes
+        {"relational", "relat"},
+        {"conditional", "condit"},
+        {"vietnamization", "vietnam"},
+        {"hopefulness", "hope"},
+        {"sensitiviti", "sensit"},
+        {"electrical", "electr"},
+        {"goodness", "good"},
+        {"adjustable", "adjust"},
+        {"replacement", "replac"},
+        {"adoption", "adopt"},
+        // Step 5: final e and double l
+        {"probate", "probat"},
+        {"rate", "rate"},
+        {"controll", "control"},
+        {"roll", "roll"},
+        // Catalog words
+        {"running", "run"},
+        {"connections", "connect"},
+        {"generalizations", "gener"},
+        {"shoes", "shoe"},
+        {"wallets", "wallet"},
+        {"leather", "leather"},
+        // Left alone
+        {"go", "go"},
+        {"x1", "x1"},
+        {"über", "über"},
+        {"Shoes", "Shoes"},
+    }
+    for _, tt := range tests {
+        if got := Stem(tt.word); got != tt.want {
+            t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"ambassador/src/models"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// MaxSuggestions is how many suggestions each trie node keeps ready
+const MaxSuggestions = 10
+
+// maxSuggestPrefix bounds trie depth; longer prefixes are matched on their start
+const maxSuggestPrefix = 32
+
+// suggestRefreshDelay batches the refreshes of bursts of product changes
+const suggestRefreshDelay = 2 * time.Second
+
+// Suggestion is a product title offered for a typed prefix
+type Suggestion struct {
+    ID     uint   `json:"id"`
+    Title  string `json:"title"`
+    Orders int64  `json:"orders"` // completed orders containing the product
+}
+
+// trieNode keeps the best suggestions for its prefix, so a lookup only walks
+// the prefix and never visits the subtree
+type trieNode struct {
+    children map[rune]*trieNode
+    top      []*Suggestion
+}
+
+// Suggester answers typeahead lookups from an immutable trie that is rebuilt
+// and swapped in whole
+type Suggester struct {
+    root atomic.Pointer[trieNode]
+
+    mu    sync.Mutex
+    timer *time.Timer
+    db    *gorm.DB
+}
+
+// Suggestions serves GET /api/ambassador/products/suggest
+var Suggestions = &Suggester{}
+
+// Lookup returns up to limit titles matching prefix at the start of the
+// title or of any word in it, most ordered first
+func (s *Suggester) Lookup(prefix string, limit int) []Suggestion {
+    results := []Suggestion{}
+    node := s.root.Load()
+    key := normalizeSuggest(prefix)
+    if node == nil || key == "" {
+        return results
+    }
+
+    for i, r := range []rune(key) {
+        if i == maxSuggestPrefix {
+            break
+        }
+        if node = node.children[r]; node == nil {
+            return results
+        }
+    }
+
+    for _, suggestion := range node.top {
+        if len(results) == limit {
+            break
+        }
+        results = append(results, *suggestion)
+    }
+    return results
+}
+
+// Refresh rebuilds the trie from products and their order counts (product_stats)
+func (s *Suggester) Refresh(db *gorm.DB) error {
+    start := time.Now()
+
+    var products []models.Product
+    if err := db.Select("id, title").Find(&products).Error; err != nil {
+        return err
+    }
+
+    var counts []models.ProductStat
+    if err := db.Select("product_id, order_count").Find(&counts).Error; err != nil {
+        return err
+    }
+    orders := make(map[uint]int64, len(counts))
+    for _, count := range counts {
+        orders[count.ProductID] = count.OrderCount
+    }
+
+    suggestions := make([]*Suggestion, 0, len(products))
+    for _, product := range products {
+        suggestions = append(suggestions, &Suggestion{ID: product.ID, Title: product.Title, Orders: orders[product.ID]})
+    }
+
+    s.root.Store(buildTrie(suggestions))
+    s.mu.Lock()
+    s.db = db
+    s.mu.Unlock()
+
+    log.Printf("Suggestions built: %d products in %v", len(products), time.Since(start))
+    return nil
+}
+
+// ScheduleRefresh rebuilds the trie shortly after product changes; calls in
+// quick succession share one rebuild. No-op until the first Refresh.
+func (s *Suggester) ScheduleRefresh() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.db == nil || s.timer != nil {
+        return
+    }
+    db := s.db
+    s.timer = time.AfterFunc(suggestRefreshDelay, func() {
+        s.mu.Lock()
+        s.timer = nil
+        s.mu.Unlock()
+
+        if err := s.Refresh(db); err != nil {
+            log.Printf("Suggestion refresh failed: %v", err)
+        }
+    })
+}
+
+// buildTrie indexes every suggestion under each word of its title
+func buildTrie(suggestions []*Suggestion) *trieNode {
+    // Inserting best first lets every node simply keep its first entries
+    sort.Slice(suggestions, func(i, j int) bool {
+        a, b := suggestions[i], suggestions[j]
+        if a.Orders != b.Orders {
+            return a.Orders > b.Orders
+        }
+        if len(a.Title) != len(b.Title) {
+            return len(a.Title) < len(b.Title)
+        }
+        return a.ID < b.ID
+    })
+
+    root := &trieNode{}
+    for _, suggestion := range suggestions {
+        key := normalizeSuggest(suggestion.Title)
+        for _, start := range wordStarts(key) {
+            root.insert(key[start:], suggestion)
+        }
+    }
+    return root
+}
+
+func (n *trieNode) insert(key string, suggestion *Suggestion) {
+    node := n
+    for i, r := range []rune(key) {
+        if i == maxSuggestPrefix {
+            break
+        }
+        if node.children == nil {
+            node.children = make(map[rune]*trieNode)
+        }
+        child := node.children[r]
+        if child == nil {
+            child = &trieNode{}
+            node.children[r] = child
+        }
+        node = child
+        node.add(suggestion)
+    }
+}
+
+func (n *trieNode) add(suggestion *Suggestion) {
+    if len(n.top) == MaxSuggestions {
+        return
+    }
+    // A title reaches a node once per word starting with the same letters
+    for _, existing := range n.top {
+        if existing == suggestion {
+            return
+        }
+    }
+    n.top = append(n.top, suggestion)
+}
+
+// normalizeSuggest lowercases and collapses whitespace
+func normalizeSuggest(text string) string {
+    return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// wordStarts returns the byte offsets where words of key begin
+func wordStarts(key string) []int {
+    starts := []int{}
+    prevLetter := false
+    for i, r := range key {
+        letter := unicode.IsLetter(r) || unicode.IsDigit(r)
+        if letter && !prevLetter {
+            starts = append(starts, i)
+        }
+        prevLetter = letter
+    }
+    return starts
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSuggester(suggestions ...Suggestion) *Suggester {
+    ptrs := make([]*Suggestion, 0, len(suggestions))
+    for i := range suggestions {
+        ptrs = append(ptrs, &suggestions[i])
+    }
+    s := &Suggester{}
+    s.root.Store(buildTrie(ptrs))
+    return s
+}
+
+func suggestionIDs(suggestions []Suggestion) []uint {
+    ids := []uint{}
+    for _, suggestion := range suggestions {
+        ids = append(ids, suggestion.ID)
+    }
+    return ids
+}
+
+func TestSuggesterLookup(t *testing.T) {
+    s := testSuggester(
+        Suggestion{ID: 1, Title: "Running Shoes", Orders: 5},
+        Suggestion{ID: 2, Title: "Trail Running Shoes", Orders: 20},
+        Suggestion{ID: 3, Title: "Rain jacket", Orders: 5},
+        Suggestion{ID: 4, Title: "Red  Rain Hat", Orders: 0},
+        Suggestion{ID: 5, Title: "Ring", Orders: 5},
+        Suggestion{ID: 6, Title: "Crème brûlée torch", Orders: 1},
+    )
+    tests := []struct {
+        name   string
+        prefix string
+        limit  int
+        want   []uint
+    }{
+        {name: "most ordered first", prefix: "run", limit: 10, want: []uint{2, 1}},
+        {name: "matches the start of any word", prefix: "shoes", limit: 10, want: []uint{2, 1}},
+        {name: "equal orders prefer shorter titles", prefix: "r", limit: 10, want: []uint{2, 5, 3, 1, 4}},
+        {name: "limit", prefix: "r", limit: 2, want: []uint{2, 5}},
+        {name: "case and spacing are ignored", prefix: "  RED   rain ", limit: 10, want: []uint{4}},
+        {name: "across a word boundary", prefix: "running sh", limit: 10, want: []uint{2, 1}},
+        {name: "no match in the middle of a word", prefix: "unning", limit: 10, want: []uint{}},
+        {name: "non-ASCII", prefix: "brû", limit: 10, want: []uint{6}},
+        {name: "unknown prefix", prefix: "zebra", limit: 10, want: []uint{}},
+        {name: "blank prefix", prefix: "   ", limit: 10, want: []uint{}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := suggestionIDs(s.Lookup(tt.prefix, tt.limit)); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Lookup(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSuggesterLookupBeforeRefresh(t *testing.T) {
+    s := &Suggester{}
+    if got := s.Lookup("run", 10); got == nil || len(got) != 0 {
+        t.Errorf("Lookup before any Refresh = %v, want empty", got)
+    }
+}
+
+func TestSuggesterTitleListedOnce(t *testing.T) {
+    // "Sun" and "Sunglasses" both start with "sun"
+    s := testSuggester(Suggestion{ID: 1, Title: "Sun Sunglasses"})
+    if got := suggestionIDs(s.Lookup("sun", 10)); !reflect.DeepEqual(got, []uint{1}) {
+        t.Errorf("Lookup = %v, want [1]", got)
+    }
+}
+
+func TestSuggesterKeepsTopSuggestions(t *testing.T) {
+    var suggestions []Suggestion
+    for i := 1; i <= MaxSuggestions+5; i++ {
+        suggestions = append(suggestions, Suggestion{ID: uint(i), Title: fmt.Sprintf("Mug %d", i), Orders: int64(i)})
+    }
+    s := testSuggester(suggestions...)
+
+    got := suggestionIDs(s.Lookup("mug", 100))
+    if len(got) != MaxSuggestions {
+        t.Fatalf("%d suggestions, want %d", len(got), MaxSuggestions)
+    }
+    if got[0] != MaxSuggestions+5 || got[len(got)-1] != 6 {
+        t.Errorf("suggestions = %v, want the %d most ordered, best first", got, MaxSuggestions)
+    }
+}
+
+func TestSuggesterLongPrefix(t *testing.T) {
+    title := strings.Repeat("a", maxSuggestPrefix) + "bcdef"
+    s := testSuggester(Suggestion{ID: 1, Title: title})
+
+    // Prefixes past the trie depth match on their first maxSuggestPrefix letters
+    for _, prefix := range []string{title, strings.Repeat("a", maxSuggestPrefix) + "zzz"} {
+        if got := suggestionIDs(s.Lookup(prefix, 10)); !reflect.DeepEqual(got, []uint{1}) {
+            t.Errorf("Lookup(%q) = %v, want [1]", prefix, got)
+        }
+    }
+}
+
+func TestWordStarts(t *testing.T) {
+    tests := []struct {
+        key  string
+        want []int
+    }{
+        {"running shoes", []int{0, 8}},
+        {"2-pack socks", []int{0, 2, 7}},
+        {"crème brûlée", []int{0, 7}},
+        {"  ", []int{}},
+    }
+    for _, tt := range tests {
+        if got := wordStarts(tt.key); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("wordStarts(%q) = %v, want %v", tt.key, got, tt.want)
+        }
+    }
+}
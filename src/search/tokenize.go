@@ -0,0 +1,37 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords are too common to help ranking and are not indexed
+var stopwords = map[string]bool{
+    "a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+    "be": true, "but": true, "by": true, "for": true, "from": true, "has": true,
+    "have": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+    "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+    "was": true, "were": true, "will": true, "with": true,
+}
+
+// Token is a normalized word of a text
+type Token struct {
+    Term string // stem, used as the index key
+    Word string // lowercased original word
+}
+
+// Tokenize splits text into lowercase words, drops stopwords and stems the rest
+func Tokenize(text string) []Token {
+    words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+
+    tokens := make([]Token, 0, len(words))
+    for _, word := range words {
+        if stopwords[word] {
+            continue
+        }
+        tokens = append(tokens, Token{Term: Stem(word), Word: word})
+    }
+    return tokens
+}
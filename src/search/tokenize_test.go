@@ -0,0 +1,44 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+    tests := []struct {
+        name string
+        text string
+        want []Token
+    }{
+        {
+            name: "lowercases and stems",
+            text: "Running Shoes",
+            want: []Token{{Term: "run", Word: "running"}, {Term: "shoe", Word: "shoes"}},
+        },
+        {
+            name: "drops stopwords",
+            text: "The best of the west and the rest",
+            want: []Token{{Term: "best", Word: "best"}, {Term: "west", Word: "west"}, {Term: "rest", Word: "rest"}},
+        },
+        {
+            name: "splits on punctuation, keeps digits and non-ASCII letters",
+            text: "2-pack—Über, socks!",
+            want: []Token{{Term: "2", Word: "2"}, {Term: "pack", Word: "pack"}, {Term: "über", Word: "über"}, {Term: "sock", Word: "socks"}},
+        },
+        {
+            name: "keeps duplicates for term frequency",
+            text: "red red",
+            want: []Token{{Term: "red", Word: "red"}, {Term: "red", Word: "red"}},
+        },
+        {name: "only stopwords", text: "the and of", want: []Token{}},
+        {name: "empty", text: "  ", want: []Token{}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores files under a directory, served by the API at PublicURL
+type Local struct {
+    Root      string
+    PublicURL string
+}
+
+// NewLocal creates the root directory if needed
+func NewLocal(root, publicURL string) (*Local, error) {
+    if root == "" {
+        return nil, errors.New("STORAGE_LOCAL_PATH is required for local storage")
+    }
+    if err := os.MkdirAll(root, 0o755); err != nil {
+        return nil, fmt.Errorf("create storage directory: %w", err)
+    }
+    return &Local{Root: root, PublicURL: strings.TrimRight(publicURL, "/")}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+    if err := validKey(key); err != nil {
+        return "", err
+    }
+    return filepath.Join(l.Root, filepath.FromSlash(key)), nil
+}
+
+// Put writes through a temporary file so readers never see a partial file
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+    path, err := l.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) Get(ctx context.Context, key string) ([]byte, error) {
+    path, err := l.path(key)
+    if err != nil {
+        return nil, err
+    }
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, ErrNotFound
+    }
+    return data, err
+}
+
+// Delete removes a file; deleting a missing file is not an error
+func (l *Local) Delete(ctx context.Context, key string) error {
+    path, err := l.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+func (l *Local) URL(key string) string {
+    return l.PublicURL + "/" + key
+}
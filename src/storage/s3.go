@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var s3Client = &http.Client{Timeout: 30 * time.Second}
+
+// S3 stores objects in an S3-compatible bucket (AWS, MinIO, the s3stub
+// command) using path-style URLs and SigV4 signed requests
+type S3 struct {
+    Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9100
+    Region    string
+    Bucket    string
+    AccessKey string
+    SecretKey string
+    PublicURL string // CDN or bucket URL for clients; empty = Endpoint/Bucket
+}
+
+func (s *S3) credentials() Credentials {
+    return Credentials{AccessKey: s.AccessKey, SecretKey: s.SecretKey, Region: s.Region}
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+    if err := validKey(key); err != nil {
+        return nil, err
+    }
+    u, err := url.Parse(s.Endpoint)
+    if err != nil {
+        return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+    }
+    u.Path = strings.TrimRight(u.Path, "/") + "/" + s.Bucket + "/" + key
+    u.RawPath = canonicalURI(u.Path) // sent exactly as signed
+    return u, nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+    u, err := s.objectURL(key)
+    if err != nil {
+        return nil, err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    if contentType != "" {
+        req.Header.Set("Content-Type", contentType)
+    }
+    SignV4(req, PayloadHash(body), s.credentials(), time.Now())
+
+    return s3Client.Do(req)
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+    resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+    if err != nil {
+        return fmt.Errorf("s3 put %s: %w", key, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return s3Error("put", key, resp)
+    }
+    return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+    resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+    if err != nil {
+        return nil, fmt.Errorf("s3 get %s: %w", key, err)
+    }
+    defer resp.Body.Close()
+
+    switch resp.StatusCode {
+    case http.StatusOK:
+        return io.ReadAll(resp.Body)
+    case http.StatusNotFound:
+        return nil, ErrNotFound
+    }
+    return nil, s3Error("get", key, resp)
+}
+
+// Delete removes an object; S3 treats missing objects as deleted
+func (s *S3) Delete(ctx context.Context, key string) error {
+    resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+    if err != nil {
+        return fmt.Errorf("s3 delete %s: %w", key, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+        return s3Error("delete", key, resp)
+    }
+    return nil
+}
+
+func (s *S3) URL(key string) string {
+    if s.PublicURL != "" {
+        return s.PublicURL + "/" + key
+    }
+    return s.Endpoint + "/" + s.Bucket + "/" + key
+}
+
+func s3Error(op, key string, resp *http.Response) error {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    return fmt.Errorf("s3 %s %s: %s: %s", op, key, resp.Status, strings.TrimSpace(string(body)))
+}
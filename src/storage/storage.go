@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"ambassador/src/config"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a missing object
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files under slash-separated keys
+// ("products/12/ab3f/thumb.jpg"); implementations must be safe for concurrent use
+type Storage interface {
+    Put(ctx context.Context, key string, data []byte, contentType string) error
+    Get(ctx context.Context, key string) ([]byte, error)
+    Delete(ctx context.Context, key string) error
+    // URL is where clients fetch the object
+    URL(key string) string
+}
+
+var Default Storage
+
+// Setup picks the storage implementation from config (called from main)
+func Setup(cfg *config.Config) error {
+    s, err := New(cfg)
+    if err != nil {
+        return err
+    }
+
+    Default = s
+    return nil
+}
+
+// New builds the storage selected by STORAGE_DRIVER
+func New(cfg *config.Config) (Storage, error) {
+    switch cfg.StorageDriver {
+    case "local", "":
+        return NewLocal(cfg.StorageLocalPath, cfg.StoragePublicURL)
+    case "s3":
+        return &S3{
+            Endpoint:  strings.TrimRight(cfg.S3Endpoint, "/"),
+            Region:    cfg.S3Region,
+            Bucket:    cfg.S3Bucket,
+            AccessKey: cfg.S3AccessKey,
+            SecretKey: cfg.S3SecretKey,
+            PublicURL: strings.TrimRight(cfg.StoragePublicURL, "/"),
+        }, nil
+    default:
+        return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+    }
+}
+
+// validKey rejects keys that could escape a directory or bucket prefix
+func validKey(key string) error {
+    if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+        return fmt.Errorf("invalid storage key %q", key)
+    }
+    for _, part := range strings.Split(key, "/") {
+        if part == "" || part == "." || part == ".." {
+            return fmt.Errorf("invalid storage key %q", key)
+        }
+    }
+    return nil
+}
@@ -1,21 +1,215 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns []byte for consistency with model
+// Supported password hashing algorithms
+const (
+    HashBcrypt   = "bcrypt"
+    HashArgon2id = "argon2id"
+)
+
+// ErrPasswordMismatch is returned when a password doesn't match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// ErrUnknownHashFormat is returned for hashes no hasher recognises
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHashing configures how new password hashes are produced.
+// Stored hashes carry their own algorithm tag and parameters, so changing
+// these settings never breaks existing logins; weaker hashes are upgraded
+// on the next successful login (see NeedsRehash).
+type PasswordHashing struct {
+    Algorithm     string // HashBcrypt or HashArgon2id
+    BcryptCost    int
+    Argon2Memory  uint32 // KiB
+    Argon2Time    uint32 // iterations
+    Argon2Threads uint8
+}
+
+// DefaultPasswordHashing is used until SetPasswordHashing is called (CLI commands)
+var DefaultPasswordHashing = PasswordHashing{
+    Algorithm:     HashBcrypt,
+    BcryptCost:    12,
+    Argon2Memory:  64 * 1024,
+    Argon2Time:    3,
+    Argon2Threads: 2,
+}
+
+const (
+    argon2SaltLength = 16
+    argon2KeyLength  = 32
+)
+
+var (
+    hashingMu sync.RWMutex
+    hashing   = DefaultPasswordHashing
+)
+
+// SetPasswordHashing replaces the parameters used for new hashes
+func SetPasswordHashing(h PasswordHashing) error {
+    switch h.Algorithm {
+    case HashBcrypt:
+        if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+            return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+        }
+    case HashArgon2id:
+        if h.Argon2Memory < 8*uint32(h.Argon2Threads) || h.Argon2Time < 1 || h.Argon2Threads < 1 {
+            return errors.New("invalid argon2id parameters")
+        }
+    default:
+        return fmt.Errorf("unsupported password hash algorithm %q", h.Algorithm)
+    }
+
+    hashingMu.Lock()
+    hashing = h
+    hashingMu.Unlock()
+    return nil
+}
+
+func currentHashing() PasswordHashing {
+    hashingMu.RLock()
+    defer hashingMu.RUnlock()
+    return hashing
+}
+
+// HashPassword returns []byte for consistency with model.
+// bcrypt hashes use the standard $2a$ format, argon2id hashes the PHC
+// string format ($argon2id$v=19$m=...,t=...,p=...$salt$hash).
 func HashPassword(password string) ([]byte, error) {
-	// Use cost 12 for better security (default is 10)
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, err
-	}
+    h := currentHashing()
+
+    if h.Algorithm == HashArgon2id {
+        return hashArgon2id(password, h)
+    }
 
-	return hashedPassword, nil
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+    if err != nil {
+        return nil, err
+    }
+
+    return hashedPassword, nil
 }
 
-// CheckPassword compares hash and password
+// CheckPassword compares hash and password (timing-safe) for any supported algorithm
 func CheckPassword(hashedPassword []byte, password string) error {
-    return bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
-}
\ No newline at end of file
+    switch {
+    case isBcryptHash(hashedPassword):
+        if err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password)); err != nil {
+            if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+                return ErrPasswordMismatch
+            }
+            return err
+        }
+        return nil
+    case bytes.HasPrefix(hashedPassword, []byte("$argon2id$")):
+        params, salt, key, err := decodeArgon2id(hashedPassword)
+        if err != nil {
+            return err
+        }
+        candidate := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+        if subtle.ConstantTimeCompare(candidate, key) != 1 {
+            return ErrPasswordMismatch
+        }
+        return nil
+    default:
+        return ErrUnknownHashFormat
+    }
+}
+
+// NeedsRehash reports whether a stored hash uses a different algorithm or
+// weaker parameters than the current configuration
+func NeedsRehash(hashedPassword []byte) bool {
+    h := currentHashing()
+
+    switch {
+    case isBcryptHash(hashedPassword):
+        if h.Algorithm != HashBcrypt {
+            return true
+        }
+        cost, err := bcrypt.Cost(hashedPassword)
+        return err != nil || cost < h.BcryptCost
+    case bytes.HasPrefix(hashedPassword, []byte("$argon2id$")):
+        if h.Algorithm != HashArgon2id {
+            return true
+        }
+        params, _, key, err := decodeArgon2id(hashedPassword)
+        if err != nil {
+            return true
+        }
+        return params.Argon2Memory < h.Argon2Memory ||
+            params.Argon2Time < h.Argon2Time ||
+            params.Argon2Threads < h.Argon2Threads ||
+            len(key) < argon2KeyLength
+    default:
+        return true
+    }
+}
+
+func isBcryptHash(hashedPassword []byte) bool {
+    return bytes.HasPrefix(hashedPassword, []byte("$2a$")) ||
+        bytes.HasPrefix(hashedPassword, []byte("$2b$")) ||
+        bytes.HasPrefix(hashedPassword, []byte("$2y$"))
+}
+
+func hashArgon2id(password string, h PasswordHashing) ([]byte, error) {
+    salt := make([]byte, argon2SaltLength)
+    if _, err := rand.Read(salt); err != nil {
+        return nil, err
+    }
+
+    key := argon2.IDKey([]byte(password), salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, argon2KeyLength)
+
+    encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+        argon2.Version, h.Argon2Memory, h.Argon2Time, h.Argon2Threads,
+        base64.RawStdEncoding.EncodeToString(salt),
+        base64.RawStdEncoding.EncodeToString(key))
+
+    return []byte(encoded), nil
+}
+
+// decodeArgon2id parses a PHC-formatted argon2id hash
+func decodeArgon2id(encoded []byte) (PasswordHashing, []byte, []byte, error) {
+    var params PasswordHashing
+
+    // "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+    parts := strings.Split(string(encoded), "$")
+    if len(parts) != 6 {
+        return params, nil, nil, ErrUnknownHashFormat
+    }
+
+    var version int
+    if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+        return params, nil, nil, ErrUnknownHashFormat
+    }
+
+    if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+        return params, nil, nil, ErrUnknownHashFormat
+    }
+    if params.Argon2Time < 1 || params.Argon2Threads < 1 {
+        return params, nil, nil, ErrUnknownHashFormat
+    }
+
+    salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+    if err != nil {
+        return params, nil, nil, ErrUnknownHashFormat
+    }
+    key, err := base64.RawStdEncoding.DecodeString(parts[5])
+    if err != nil || len(key) == 0 {
+        return params, nil, nil, ErrUnknownHashFormat
+    }
+
+    params.Algorithm = HashArgon2id
+    return params, salt, key, nil
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// WebhookSignature signs a webhook body sent at timestamp (Unix seconds):
+// hex HMAC-SHA256 of "<timestamp>.<body>"
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+    mac.Write([]byte("."))
+    mac.Write(body)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a "t=<timestamp>,v1=<signature>" header against
+// body. Timestamps further than tolerance from now are rejected so a captured
+// request can't be replayed later.
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+    var timestamp int64
+    var signatures []string
+    for _, part := range strings.Split(header, ",") {
+        key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+        if !ok {
+            continue
+        }
+        switch key {
+        case "t":
+            t, err := strconv.ParseInt(value, 10, 64)
+            if err != nil {
+                return ErrInvalidWebhookSignature
+            }
+            timestamp = t
+        case "v1":
+            signatures = append(signatures, value)
+        }
+    }
+    if timestamp == 0 || len(signatures) == 0 {
+        return ErrInvalidWebhookSignature
+    }
+
+    age := now.Sub(time.Unix(timestamp, 0))
+    if age > tolerance || age < -tolerance {
+        return ErrInvalidWebhookSignature
+    }
+
+    expected := []byte(WebhookSignature(secret, timestamp, body))
+    for _, signature := range signatures {
+        if hmac.Equal(expected, []byte(strings.ToLower(signature))) {
+            return nil
+        }
+    }
+    return ErrInvalidWebhookSignature
+}